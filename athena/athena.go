@@ -21,6 +21,10 @@
 package athena
 
 import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
@@ -39,6 +43,9 @@ const nilQueryExecutionResultConfigurationOutputLocation = constError("OutputLoc
 type Client struct {
 	// api is either an athena.Athena or a mock implementation for testing.
 	api athenaiface.AthenaAPI
+
+	// opts holds optional behaviour configured with the With* methods.
+	opts *options
 }
 
 // NewClient creates and returns a new Athena client.
@@ -52,7 +59,14 @@ func NewClient(session *session.Session) (Client, error) {
 		return Client{}, nilSession
 	}
 
-	return Client{athena.New(session)}, nil
+	return Client{api: athena.New(session)}, nil
+}
+
+// NewClientWithAPI creates a client using api, such as an athena.Athena
+// configured differently to NewClient, or the fake of the athenatest
+// package in tests.
+func NewClientWithAPI(api athenaiface.AthenaAPI) Client {
+	return Client{api: api}
 }
 
 // Query allows checking for query status and completion
@@ -70,6 +84,10 @@ type Row []string
 type Result struct {
 	Columns []Column `json:"columns"`
 	Rows    []Row    `json:"rows"`
+
+	// header is set if the first of Rows is the row of column names
+	// Athena pads onto the results of statements other than DDL.
+	header bool
 }
 
 // Column specifies the various properties of the column.
@@ -77,6 +95,9 @@ type Column struct {
 	// The name of the column.
 	Name string `json:"name"`
 
+	// The data type of the column, e.g. varchar or bigint.
+	Type string `json:"type"`
+
 	// Indicates whether values in the column are case-sensitive.
 	CaseSensitive bool `json:"case_sensitive"`
 
@@ -150,6 +171,11 @@ func makeQuery(database, query, output string) *athena.StartQueryExecutionInput
 //
 // An error is returned if the query couldn't be performed.
 func (c Client) DoQuery(database, query, output string) (Query, error) {
+	return c.doQuery(context.Background(), database, query, output)
+}
+
+// doQuery is DoQuery, tracing the submission as a child of the span in ctx.
+func (c Client) doQuery(ctx context.Context, database, query, output string) (Query, error) {
 	if database == "" {
 		return Query{}, emptyDatabase
 	}
@@ -164,12 +190,38 @@ func (c Client) DoQuery(database, query, output string) (Query, error) {
 	}
 
 	in := makeQuery(database, query, output)
+	if err := c.options().configure(in); err != nil {
+		return Query{}, err
+	}
+
+	if b := c.options().budget; b != nil {
+		if err := b.check(); err != nil {
+			return Query{}, err
+		}
+	}
+
+	_, span := c.startSpan(ctx, SpanSubmit)
+	span.SetAttribute(AttrDatabase, database)
+	if in.WorkGroup != nil {
+		span.SetAttribute(AttrWorkGroup, *in.WorkGroup)
+	}
+
 	out, err := c.api.StartQueryExecution(in)
+	if err == nil {
+		span.SetAttribute(AttrQueryID, *out.QueryExecutionId)
+	}
+
+	endSpan(span, err)
+	c.measureSubmission(in, err)
 
 	if err != nil {
+		c.auditSubmission("", in, err)
 		return Query{}, err
 	}
 
+	c.auditSubmission(*out.QueryExecutionId, in, nil)
+	c.logSubmission(*out.QueryExecutionId, in)
+
 	return Query{id: *out.QueryExecutionId, Client: c}, nil
 }
 
@@ -178,13 +230,14 @@ func (c Client) DoQuery(database, query, output string) (Query, error) {
 // It is advisable the caller calls Ready() until it returns
 // the job has completed (i.e. polling).
 //
-// Note: Athena pads an initial row containing column names;
-// the caller can remove it as required.
+// Note: Athena pads an initial row containing column names onto the
+// results of statements other than DDL; the caller can remove it as
+// required with WithoutHeader.
 func (q Query) Result() (Result, error) {
 	r := Result{}
 
 	in := &athena.GetQueryResultsInput{QueryExecutionId: &q.id}
-	out, err := q.api.GetQueryResults(in)
+	out, err := q.resultPage(context.Background(), in, 0)
 
 	if err != nil {
 		return Result{}, err
@@ -193,15 +246,128 @@ func (q Query) Result() (Result, error) {
 	r.Columns = columns(out.ResultSet.ResultSetMetadata.ColumnInfo)
 	r.Rows = rows(out.ResultSet.Rows)
 
+	if r.header, err = q.headed(len(r.Rows)); err != nil {
+		return Result{}, err
+	}
+
+	return r, nil
+}
+
+// Columns returns the columns of the query's results, fetching a single
+// row of them rather than a page, e.g. to check the columns are as
+// expected before fetching them all.
+func (q Query) Columns() ([]Column, error) {
+	in := &athena.GetQueryResultsInput{QueryExecutionId: &q.id, MaxResults: aws.Int64(1)}
+	out, err := q.resultPage(context.Background(), in, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return columns(out.ResultSet.ResultSetMetadata.ColumnInfo), nil
+}
+
+// AllResults is like Result, but follows pagination until every row
+// of the query has been fetched.
+func (q Query) AllResults() (Result, error) {
+	return q.allResults(context.Background())
+}
+
+// allResults is AllResults, tracing each page as a child of the span in ctx.
+func (q Query) allResults(ctx context.Context) (Result, error) {
+	r := Result{}
+
+	in := &athena.GetQueryResultsInput{QueryExecutionId: &q.id}
+
+	for page := 0; ; page++ {
+		out, err := q.resultPage(ctx, in, page)
+
+		if err != nil {
+			return Result{}, err
+		}
+
+		if page == 0 {
+			r.Columns = columns(out.ResultSet.ResultSetMetadata.ColumnInfo)
+
+			if r.header, err = q.headed(len(out.ResultSet.Rows)); err != nil {
+				return Result{}, err
+			}
+		}
+
+		r.Rows = append(r.Rows, rows(out.ResultSet.Rows)...)
+
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
+		}
+
+		in.NextToken = out.NextToken
+	}
+
 	return r, nil
 }
 
+// resultPage fetches a page of results, the page'th, tracing it as a
+// child of the span in ctx.
+func (q Query) resultPage(ctx context.Context, in *athena.GetQueryResultsInput, page int) (*athena.GetQueryResultsOutput, error) {
+	_, span := q.startSpan(ctx, SpanResults)
+	span.SetAttribute(AttrQueryID, q.id)
+	span.SetAttribute(AttrPage, page)
+
+	out, err := q.api.GetQueryResults(in)
+	if err == nil && out.ResultSet != nil {
+		span.SetAttribute(AttrRows, len(out.ResultSet.Rows))
+		q.log(LevelDebug, "results page read", map[string]interface{}{
+			"query_id": q.id,
+			"page":     page,
+			"rows":     len(out.ResultSet.Rows),
+		})
+	}
+
+	endSpan(span, err)
+
+	return out, err
+}
+
+// headed returns whether the first page of the query's results, of n rows,
+// starts with the row of column names, which Athena pads onto the results
+// of every statement but DDL.
+func (q Query) headed(n int) (bool, error) {
+	if n == 0 {
+		return false, nil
+	}
+
+	qe, err := q.execution()
+	if err != nil {
+		return false, err
+	}
+
+	return aws.StringValue(qe.QueryExecution.StatementType) != athena.StatementTypeDdl, nil
+}
+
+// WithoutHeader returns the result without the leading row of column names
+// Athena pads onto the results of statements other than DDL, as fetched by
+// Result and AllResults. The result is returned unchanged if it has no such
+// row, e.g. because it has already been removed; rows are never compared
+// with the column names, as a row of data may well hold them.
+func (r Result) WithoutHeader() Result {
+	if r.header {
+		r.Rows = r.Rows[1:]
+		r.header = false
+	}
+
+	return r
+}
+
 func columns(columnInfo []*athena.ColumnInfo) []Column {
 	columns := make([]Column, 0, len(columnInfo))
 
 	for _, ci := range columnInfo {
 		c := Column{Name: *ci.Name}
 
+		if ci.Type != nil {
+			c.Type = *ci.Type
+		}
+
 		if ci.CaseSensitive != nil {
 			c.CaseSensitive = *ci.CaseSensitive
 			c.CaseSensitiveExists = true
@@ -280,42 +446,105 @@ func (qs QueryStatus) Done() bool {
 // The location specifies (as an S3 URL) where Athena wrote the results of the
 // query.
 func (q Query) Status() (QueryStatus, error) {
+	qe, err := q.execution()
+
+	if err != nil {
+		return QueryStatus{}, err
+	}
+
+	return queryStatus(qe), nil
+}
+
+// queryStatus extracts the status from a query execution which has been
+// checked by execution().
+func queryStatus(qe *athena.GetQueryExecutionOutput) QueryStatus {
+	return QueryStatus{
+		State:          *qe.QueryExecution.Status.State,
+		OutputLocation: *qe.QueryExecution.ResultConfiguration.OutputLocation,
+	}
+}
+
+// execution fetches the query execution from athena, ensuring the fields
+// needed to report its status are present.
+func (q Query) execution() (*athena.GetQueryExecutionOutput, error) {
 	in := &athena.GetQueryExecutionInput{QueryExecutionId: &q.id}
 	qe, err := q.api.GetQueryExecution(in)
 
 	if err != nil {
-		return QueryStatus{}, err
+		return nil, err
 	}
 
 	// all of this stuff appears to be optional, so probably best to armour everything with checks
 	{
 		if qe.QueryExecution == nil {
-			return QueryStatus{}, nilQueryExecution
+			return nil, nilQueryExecution
 		}
 
 		if qe.QueryExecution.Status == nil {
-			return QueryStatus{}, nilQueryExecutionStatus
+			return nil, nilQueryExecutionStatus
 		}
 
 		if qe.QueryExecution.Status.State == nil {
-			return QueryStatus{}, nilQueryExecutionStatusState
+			return nil, nilQueryExecutionStatusState
 		}
 
 		if qe.QueryExecution.ResultConfiguration == nil {
-			return QueryStatus{}, nilQueryExecutionResultConfiguration
+			return nil, nilQueryExecutionResultConfiguration
 		}
 
 		if qe.QueryExecution.ResultConfiguration.OutputLocation == nil {
-			return QueryStatus{}, nilQueryExecutionResultConfigurationOutputLocation
+			return nil, nilQueryExecutionResultConfigurationOutputLocation
 		}
 	}
 
-	status := QueryStatus{
-		State:          *qe.QueryExecution.Status.State,
-		OutputLocation: *qe.QueryExecution.ResultConfiguration.OutputLocation,
+	return qe, nil
+}
+
+// Statistics describes the resources used by a query.
+type Statistics struct {
+	// DataScannedInBytes is the amount of data Athena scanned (and billed).
+	DataScannedInBytes int64 `json:"data_scanned_in_bytes"`
+
+	// EngineExecutionTime is how long the query took to execute.
+	EngineExecutionTime time.Duration `json:"engine_execution_time"`
+
+	// DataManifestLocation is the S3 URL of the manifest listing the files
+	// written by CTAS and INSERT INTO queries, if any.
+	DataManifestLocation string `json:"data_manifest_location,omitempty"`
+}
+
+// Statistics returns the statistics of the query so far; they are only
+// complete once the query has finished.
+func (q Query) Statistics() (Statistics, error) {
+	qe, err := q.execution()
+
+	if err != nil {
+		return Statistics{}, err
+	}
+
+	return statistics(qe.QueryExecution.Statistics), nil
+}
+
+func statistics(qes *athena.QueryExecutionStatistics) Statistics {
+	var s Statistics
+
+	if qes == nil {
+		return s
+	}
+
+	if qes.DataScannedInBytes != nil {
+		s.DataScannedInBytes = *qes.DataScannedInBytes
+	}
+
+	if qes.EngineExecutionTimeInMillis != nil {
+		s.EngineExecutionTime = time.Duration(*qes.EngineExecutionTimeInMillis) * time.Millisecond
+	}
+
+	if qes.DataManifestLocation != nil {
+		s.DataManifestLocation = *qes.DataManifestLocation
 	}
 
-	return status, nil
+	return s
 }
 
 // ID is the associated Athena query job execution ID
@@ -0,0 +1,122 @@
+package athenatest
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// Call records a call made to the fake.
+type Call struct {
+	// Operation is the name of the API operation, e.g. StartQueryExecution.
+	Operation string
+
+	// Input is the request, e.g. a *athena.StartQueryExecutionInput.
+	Input interface{}
+}
+
+// record records a call; the fake must be locked.
+func (f *Fake) record(operation string, in interface{}) {
+	f.calls = append(f.calls, Call{operation, in})
+}
+
+// Calls returns the calls made to the fake in the order made, or only
+// those of the given operations.
+func (f *Fake) Calls(operations ...string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []Call
+
+	for _, c := range f.calls {
+		if len(operations) == 0 {
+			calls = append(calls, c)
+			continue
+		}
+
+		for _, op := range operations {
+			if c.Operation == op {
+				calls = append(calls, c)
+				break
+			}
+		}
+	}
+
+	return calls
+}
+
+// Queries returns the text of the queries started, in the order started.
+func (f *Fake) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queries := make([]string, len(f.order))
+	for i, id := range f.order {
+		queries[i] = aws.StringValue(f.executions[id].in.QueryString)
+	}
+
+	return queries
+}
+
+// queried returns the number of queries started matching pattern.
+func (f *Fake) queried(t testing.TB, pattern string) int {
+	t.Helper()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatalf("invalid pattern %q: %v", pattern, err)
+	}
+
+	n := 0
+	for _, q := range f.Queries() {
+		if re.MatchString(q) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// AssertQueried fails the test unless a query matching pattern, a regular
+// expression, was started.
+func (f *Fake) AssertQueried(t testing.TB, pattern string) {
+	t.Helper()
+
+	if f.queried(t, pattern) == 0 {
+		t.Errorf("no query matching %q was started; queries were %q", pattern, f.Queries())
+	}
+}
+
+// AssertNotQueried fails the test if a query matching pattern, a regular
+// expression, was started.
+func (f *Fake) AssertNotQueried(t testing.TB, pattern string) {
+	t.Helper()
+
+	if n := f.queried(t, pattern); n > 0 {
+		t.Errorf("%d queries matching %q were started (want none)", n, pattern)
+	}
+}
+
+// AssertCalled fails the test unless operation was called n times.
+func (f *Fake) AssertCalled(t testing.TB, operation string, n int) {
+	t.Helper()
+
+	if calls := len(f.Calls(operation)); calls != n {
+		t.Errorf("%s called %d times (want %d)", operation, calls, n)
+	}
+}
+
+// AssertStopped fails the test unless the query with id was stopped.
+func (f *Fake) AssertStopped(t testing.TB, id string) {
+	t.Helper()
+
+	for _, c := range f.Calls("StopQueryExecution") {
+		if aws.StringValue(c.Input.(*aa.StopQueryExecutionInput).QueryExecutionId) == id {
+			return
+		}
+	}
+
+	t.Errorf("query %s was not stopped", id)
+}
@@ -0,0 +1,280 @@
+package athenatest
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// CreateNamedQuery saves a query, returning its ID.
+func (f *Fake) CreateNamedQuery(in *aa.CreateNamedQueryInput) (*aa.CreateNamedQueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("CreateNamedQuery", in)
+
+	if aws.StringValue(in.Name) == "" || aws.StringValue(in.Database) == "" || aws.StringValue(in.QueryString) == "" {
+		return nil, invalidRequest("Name, Database and QueryString must not be empty")
+	}
+
+	workgroup := aws.StringValue(in.WorkGroup)
+	if workgroup == "" {
+		workgroup = PrimaryWorkGroup
+	}
+
+	if _, ok := f.workgroups[workgroup]; !ok {
+		return nil, invalidRequest("WorkGroup %s is not found.", workgroup)
+	}
+
+	id := f.newID()
+
+	f.named[id] = &aa.NamedQuery{
+		NamedQueryId: aws.String(id),
+		Name:         in.Name,
+		Description:  in.Description,
+		Database:     in.Database,
+		QueryString:  in.QueryString,
+		WorkGroup:    aws.String(workgroup),
+	}
+
+	f.namedOrder = append(f.namedOrder, id)
+
+	return &aa.CreateNamedQueryOutput{NamedQueryId: aws.String(id)}, nil
+}
+
+// GetNamedQuery returns a saved query.
+func (f *Fake) GetNamedQuery(in *aa.GetNamedQueryInput) (*aa.GetNamedQueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("GetNamedQuery", in)
+
+	nq, ok := f.named[aws.StringValue(in.NamedQueryId)]
+	if !ok {
+		return nil, invalidRequest("NamedQuery %s not found", aws.StringValue(in.NamedQueryId))
+	}
+
+	return &aa.GetNamedQueryOutput{NamedQuery: nq}, nil
+}
+
+// BatchGetNamedQuery returns saved queries.
+func (f *Fake) BatchGetNamedQuery(in *aa.BatchGetNamedQueryInput) (*aa.BatchGetNamedQueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("BatchGetNamedQuery", in)
+
+	out := &aa.BatchGetNamedQueryOutput{}
+
+	for _, id := range in.NamedQueryIds {
+		nq, ok := f.named[aws.StringValue(id)]
+		if !ok {
+			out.UnprocessedNamedQueryIds = append(out.UnprocessedNamedQueryIds, &aa.UnprocessedNamedQueryId{
+				NamedQueryId: id,
+				ErrorCode:    aws.String(aa.ErrCodeInvalidRequestException),
+				ErrorMessage: aws.String("NamedQuery not found"),
+			})
+
+			continue
+		}
+
+		out.NamedQueries = append(out.NamedQueries, nq)
+	}
+
+	return out, nil
+}
+
+// ListNamedQueries lists the IDs of the queries saved in a workgroup.
+func (f *Fake) ListNamedQueries(in *aa.ListNamedQueriesInput) (*aa.ListNamedQueriesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("ListNamedQueries", in)
+
+	workgroup := aws.StringValue(in.WorkGroup)
+	if workgroup == "" {
+		workgroup = PrimaryWorkGroup
+	}
+
+	var ids []*string
+	for _, id := range f.namedOrder {
+		if aws.StringValue(f.named[id].WorkGroup) == workgroup {
+			ids = append(ids, aws.String(id))
+		}
+	}
+
+	start, end, next, err := page(in.NextToken, in.MaxResults, defaultListPage, len(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return &aa.ListNamedQueriesOutput{NamedQueryIds: ids[start:end], NextToken: next}, nil
+}
+
+// DeleteNamedQuery deletes a saved query.
+func (f *Fake) DeleteNamedQuery(in *aa.DeleteNamedQueryInput) (*aa.DeleteNamedQueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("DeleteNamedQuery", in)
+
+	id := aws.StringValue(in.NamedQueryId)
+	if _, ok := f.named[id]; !ok {
+		return nil, invalidRequest("NamedQuery %s not found", id)
+	}
+
+	delete(f.named, id)
+
+	for i, o := range f.namedOrder {
+		if o == id {
+			f.namedOrder = append(f.namedOrder[:i], f.namedOrder[i+1:]...)
+			break
+		}
+	}
+
+	return &aa.DeleteNamedQueryOutput{}, nil
+}
+
+// addWorkGroup adds a workgroup, listed after those already added.
+func (f *Fake) addWorkGroup(wg *aa.WorkGroup) {
+	f.workgroups[*wg.Name] = wg
+	f.wgOrder = append(f.wgOrder, *wg.Name)
+}
+
+// CreateWorkGroup creates a workgroup.
+func (f *Fake) CreateWorkGroup(in *aa.CreateWorkGroupInput) (*aa.CreateWorkGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("CreateWorkGroup", in)
+
+	name := aws.StringValue(in.Name)
+	if name == "" {
+		return nil, invalidRequest("Name must not be empty")
+	}
+
+	if _, ok := f.workgroups[name]; ok {
+		return nil, invalidRequest("WorkGroup %s is already created", name)
+	}
+
+	wg := &aa.WorkGroup{
+		Name:          aws.String(name),
+		Description:   in.Description,
+		State:         aws.String(aa.WorkGroupStateEnabled),
+		Configuration: in.Configuration,
+		CreationTime:  aws.Time(time.Now()),
+	}
+
+	if wg.Configuration == nil {
+		wg.Configuration = &aa.WorkGroupConfiguration{}
+	}
+
+	f.addWorkGroup(wg)
+
+	return &aa.CreateWorkGroupOutput{}, nil
+}
+
+// GetWorkGroup describes a workgroup.
+func (f *Fake) GetWorkGroup(in *aa.GetWorkGroupInput) (*aa.GetWorkGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("GetWorkGroup", in)
+
+	wg, ok := f.workgroups[aws.StringValue(in.WorkGroup)]
+	if !ok {
+		return nil, invalidRequest("WorkGroup %s is not found.", aws.StringValue(in.WorkGroup))
+	}
+
+	return &aa.GetWorkGroupOutput{WorkGroup: wg}, nil
+}
+
+// ListWorkGroups lists the workgroups, in the order created.
+func (f *Fake) ListWorkGroups(in *aa.ListWorkGroupsInput) (*aa.ListWorkGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("ListWorkGroups", in)
+
+	start, end, next, err := page(in.NextToken, in.MaxResults, defaultListPage, len(f.wgOrder))
+	if err != nil {
+		return nil, err
+	}
+
+	out := &aa.ListWorkGroupsOutput{NextToken: next}
+
+	for _, name := range f.wgOrder[start:end] {
+		wg := f.workgroups[name]
+		out.WorkGroups = append(out.WorkGroups, &aa.WorkGroupSummary{
+			Name:         wg.Name,
+			State:        wg.State,
+			Description:  wg.Description,
+			CreationTime: wg.CreationTime,
+		})
+	}
+
+	return out, nil
+}
+
+// UpdateWorkGroup changes the state, description and bytes scanned cutoff
+// of a workgroup; other configuration updates are ignored.
+func (f *Fake) UpdateWorkGroup(in *aa.UpdateWorkGroupInput) (*aa.UpdateWorkGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("UpdateWorkGroup", in)
+
+	wg, ok := f.workgroups[aws.StringValue(in.WorkGroup)]
+	if !ok {
+		return nil, invalidRequest("WorkGroup %s is not found.", aws.StringValue(in.WorkGroup))
+	}
+
+	if in.State != nil {
+		wg.State = in.State
+	}
+
+	if in.Description != nil {
+		wg.Description = in.Description
+	}
+
+	if u := in.ConfigurationUpdates; u != nil {
+		if u.BytesScannedCutoffPerQuery != nil {
+			wg.Configuration.BytesScannedCutoffPerQuery = u.BytesScannedCutoffPerQuery
+		}
+
+		if aws.BoolValue(u.RemoveBytesScannedCutoffPerQuery) {
+			wg.Configuration.BytesScannedCutoffPerQuery = nil
+		}
+	}
+
+	return &aa.UpdateWorkGroupOutput{}, nil
+}
+
+// DeleteWorkGroup deletes a workgroup other than the primary workgroup.
+func (f *Fake) DeleteWorkGroup(in *aa.DeleteWorkGroupInput) (*aa.DeleteWorkGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("DeleteWorkGroup", in)
+
+	name := aws.StringValue(in.WorkGroup)
+	if name == PrimaryWorkGroup {
+		return nil, invalidRequest("The primary WorkGroup cannot be deleted")
+	}
+
+	if _, ok := f.workgroups[name]; !ok {
+		return nil, invalidRequest("WorkGroup %s is not found.", name)
+	}
+
+	delete(f.workgroups, name)
+
+	for i, o := range f.wgOrder {
+		if o == name {
+			f.wgOrder = append(f.wgOrder[:i], f.wgOrder[i+1:]...)
+			break
+		}
+	}
+
+	return &aa.DeleteWorkGroupOutput{}, nil
+}
@@ -0,0 +1,433 @@
+package athenatest
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+)
+
+// chaosToken prefixes the tokens of the pages a Chaos truncates.
+const chaosToken = "athenatest-chaos-"
+
+// Fault is a kind of fault injected by a Chaos.
+type Fault string
+
+// Faults injected by a Chaos
+const (
+	// FaultThrottle and FaultInternalError fail a call, without making it,
+	// with a TooManyRequestsException or an InternalServerException, the
+	// latter a request failure with status 500 as from the API.
+	FaultThrottle      Fault = "throttle"
+	FaultInternalError Fault = "internal_error"
+
+	// FaultLatency delays a call.
+	FaultLatency Fault = "latency"
+
+	// FaultTruncatedPage returns some of the rows of a page of results,
+	// with a token for a page of the rest, as Athena may.
+	FaultTruncatedPage Fault = "truncated_page"
+
+	// FaultMissingStatus and FaultMissingResultConfiguration leave the
+	// Status or ResultConfiguration out of a query execution.
+	FaultMissingStatus              Fault = "missing_status"
+	FaultMissingResultConfiguration Fault = "missing_result_configuration"
+
+	// FaultStateFlip reports a query in the state before its current one:
+	// QUEUED if RUNNING, and RUNNING if finished.
+	FaultStateFlip Fault = "state_flip"
+)
+
+// Faults are the chances, from 0 to 1, of a Chaos injecting each fault
+// into a call.
+type Faults struct {
+	Throttle      float64
+	InternalError float64
+
+	// Latency is the chance of a call being delayed by up to MaxLatency.
+	Latency    float64
+	MaxLatency time.Duration
+
+	// TruncatePage applies to GetQueryResults, and the others to
+	// GetQueryExecution.
+	TruncatePage               float64
+	MissingStatus              float64
+	MissingResultConfiguration float64
+	StateFlip                  float64
+
+	// Operations, if not empty, are the names of the operations, such as
+	// "GetQueryExecution", faults are injected into; others are passed
+	// through untouched.
+	Operations []string
+}
+
+// Chaos is an athenaiface.AthenaAPI injecting faults into the calls made
+// through it to another, to test retries, timeouts and the handling of
+// incomplete responses. Faults are chosen by a seeded random source, so a
+// test making the same calls sees the same faults each run:
+//
+//	chaos := athenatest.NewChaos(fake, athenatest.Faults{Throttle: 0.2, StateFlip: 0.1}, 1)
+//	client := athena.NewClientWithAPI(chaos).WithInterceptors(retry)
+//	...
+//	if chaos.Injected(athenatest.FaultThrottle) == 0 {
+//		...
+//	}
+//
+// Faults are injected into the operations served by Server; other
+// operations are passed through. It is safe for concurrent use.
+type Chaos struct {
+	athenaiface.AthenaAPI
+
+	faults Faults
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	injected map[Fault]int
+
+	// pages are the rest of the pages truncated, by their token.
+	pages map[string]*aa.GetQueryResultsOutput
+}
+
+// NewChaos returns a Chaos injecting faults into the calls made to api,
+// chosen by a random source seeded with seed.
+func NewChaos(api athenaiface.AthenaAPI, faults Faults, seed int64) *Chaos {
+	return &Chaos{
+		AthenaAPI: api,
+		faults:    faults,
+		rand:      rand.New(rand.NewSource(seed)),
+		injected:  map[Fault]int{},
+		pages:     map[string]*aa.GetQueryResultsOutput{},
+	}
+}
+
+// Injected returns the number of times fault has been injected.
+func (c *Chaos) Injected(fault Fault) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.injected[fault]
+}
+
+// applies returns whether faults are injected into operation.
+func (c *Chaos) applies(operation string) bool {
+	if len(c.faults.Operations) == 0 {
+		return true
+	}
+
+	for _, op := range c.faults.Operations {
+		if op == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// roll returns whether to inject fault, given its chance; c must be locked.
+func (c *Chaos) roll(fault Fault, chance float64) bool {
+	if chance <= 0 || c.rand.Float64() >= chance {
+		return false
+	}
+
+	c.injected[fault]++
+
+	return true
+}
+
+// inject delays a call to operation, or fails it, as chance has it.
+func (c *Chaos) inject(operation string) error {
+	if !c.applies(operation) {
+		return nil
+	}
+
+	c.mu.Lock()
+
+	var delay time.Duration
+	if c.faults.MaxLatency > 0 && c.roll(FaultLatency, c.faults.Latency) {
+		delay = time.Duration(c.rand.Int63n(int64(c.faults.MaxLatency)))
+	}
+
+	var err error
+	switch {
+	case c.roll(FaultThrottle, c.faults.Throttle):
+		err = awserr.New(aa.ErrCodeTooManyRequestsException, "Rate exceeded", nil)
+	case c.roll(FaultInternalError, c.faults.InternalError):
+		err = awserr.NewRequestFailure(awserr.New(aa.ErrCodeInternalServerException, "We encountered an internal error. Please try again.", nil), http.StatusInternalServerError, "")
+	}
+
+	c.mu.Unlock()
+
+	time.Sleep(delay)
+
+	return err
+}
+
+// StartQueryExecution injects faults into a call to StartQueryExecution.
+func (c *Chaos) StartQueryExecution(in *aa.StartQueryExecutionInput) (*aa.StartQueryExecutionOutput, error) {
+	if err := c.inject("StartQueryExecution"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.StartQueryExecution(in)
+}
+
+// GetQueryExecution injects faults into a call to GetQueryExecution,
+// including into the execution returned.
+func (c *Chaos) GetQueryExecution(in *aa.GetQueryExecutionInput) (*aa.GetQueryExecutionOutput, error) {
+	if err := c.inject("GetQueryExecution"); err != nil {
+		return nil, err
+	}
+
+	out, err := c.AthenaAPI.GetQueryExecution(in)
+	if err != nil || out.QueryExecution == nil || !c.applies("GetQueryExecution") {
+		return out, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the execution is copied, rather than changed under the wrapped API
+	qe := *out.QueryExecution
+
+	if qe.Status != nil && qe.Status.State != nil && c.faults.StateFlip > 0 {
+		var flipped string
+
+		switch *qe.Status.State {
+		case aa.QueryExecutionStateRunning:
+			flipped = aa.QueryExecutionStateQueued
+		case aa.QueryExecutionStateSucceeded, aa.QueryExecutionStateFailed, aa.QueryExecutionStateCancelled:
+			flipped = aa.QueryExecutionStateRunning
+		}
+
+		if flipped != "" && c.roll(FaultStateFlip, c.faults.StateFlip) {
+			status := *qe.Status
+			status.State = aws.String(flipped)
+			status.StateChangeReason = nil
+			status.CompletionDateTime = nil
+			qe.Status = &status
+		}
+	}
+
+	if qe.Status != nil && c.roll(FaultMissingStatus, c.faults.MissingStatus) {
+		qe.Status = nil
+	}
+
+	if qe.ResultConfiguration != nil && c.roll(FaultMissingResultConfiguration, c.faults.MissingResultConfiguration) {
+		qe.ResultConfiguration = nil
+	}
+
+	return &aa.GetQueryExecutionOutput{QueryExecution: &qe}, nil
+}
+
+// GetQueryResults injects faults into a call to GetQueryResults, and
+// serves the rest of the pages it truncated.
+func (c *Chaos) GetQueryResults(in *aa.GetQueryResultsInput) (*aa.GetQueryResultsOutput, error) {
+	if err := c.inject("GetQueryResults"); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	rest, ok := c.pages[aws.StringValue(in.NextToken)]
+	c.mu.Unlock()
+
+	out := rest
+	if !ok {
+		var err error
+		if out, err = c.AthenaAPI.GetQueryResults(in); err != nil {
+			return out, err
+		}
+	}
+
+	if out.ResultSet == nil || len(out.ResultSet.Rows) < 2 || !c.applies("GetQueryResults") {
+		return out, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.roll(FaultTruncatedPage, c.faults.TruncatePage) {
+		return out, nil
+	}
+
+	rows := out.ResultSet.Rows
+	n := 1 + c.rand.Intn(len(rows)-1)
+	token := chaosToken + strconv.Itoa(len(c.pages))
+
+	c.pages[token] = &aa.GetQueryResultsOutput{
+		ResultSet: &aa.ResultSet{ResultSetMetadata: out.ResultSet.ResultSetMetadata, Rows: rows[n:]},
+		NextToken: out.NextToken,
+	}
+
+	return &aa.GetQueryResultsOutput{
+		ResultSet:   &aa.ResultSet{ResultSetMetadata: out.ResultSet.ResultSetMetadata, Rows: rows[:n]},
+		NextToken:   aws.String(token),
+		UpdateCount: out.UpdateCount,
+	}, nil
+}
+
+// BatchGetQueryExecution injects faults into a call to BatchGetQueryExecution.
+func (c *Chaos) BatchGetQueryExecution(in *aa.BatchGetQueryExecutionInput) (*aa.BatchGetQueryExecutionOutput, error) {
+	if err := c.inject("BatchGetQueryExecution"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.BatchGetQueryExecution(in)
+}
+
+// ListQueryExecutions injects faults into a call to ListQueryExecutions.
+func (c *Chaos) ListQueryExecutions(in *aa.ListQueryExecutionsInput) (*aa.ListQueryExecutionsOutput, error) {
+	if err := c.inject("ListQueryExecutions"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.ListQueryExecutions(in)
+}
+
+// StopQueryExecution injects faults into a call to StopQueryExecution.
+func (c *Chaos) StopQueryExecution(in *aa.StopQueryExecutionInput) (*aa.StopQueryExecutionOutput, error) {
+	if err := c.inject("StopQueryExecution"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.StopQueryExecution(in)
+}
+
+// CreateNamedQuery injects faults into a call to CreateNamedQuery.
+func (c *Chaos) CreateNamedQuery(in *aa.CreateNamedQueryInput) (*aa.CreateNamedQueryOutput, error) {
+	if err := c.inject("CreateNamedQuery"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.CreateNamedQuery(in)
+}
+
+// GetNamedQuery injects faults into a call to GetNamedQuery.
+func (c *Chaos) GetNamedQuery(in *aa.GetNamedQueryInput) (*aa.GetNamedQueryOutput, error) {
+	if err := c.inject("GetNamedQuery"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.GetNamedQuery(in)
+}
+
+// BatchGetNamedQuery injects faults into a call to BatchGetNamedQuery.
+func (c *Chaos) BatchGetNamedQuery(in *aa.BatchGetNamedQueryInput) (*aa.BatchGetNamedQueryOutput, error) {
+	if err := c.inject("BatchGetNamedQuery"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.BatchGetNamedQuery(in)
+}
+
+// ListNamedQueries injects faults into a call to ListNamedQueries.
+func (c *Chaos) ListNamedQueries(in *aa.ListNamedQueriesInput) (*aa.ListNamedQueriesOutput, error) {
+	if err := c.inject("ListNamedQueries"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.ListNamedQueries(in)
+}
+
+// DeleteNamedQuery injects faults into a call to DeleteNamedQuery.
+func (c *Chaos) DeleteNamedQuery(in *aa.DeleteNamedQueryInput) (*aa.DeleteNamedQueryOutput, error) {
+	if err := c.inject("DeleteNamedQuery"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.DeleteNamedQuery(in)
+}
+
+// CreateWorkGroup injects faults into a call to CreateWorkGroup.
+func (c *Chaos) CreateWorkGroup(in *aa.CreateWorkGroupInput) (*aa.CreateWorkGroupOutput, error) {
+	if err := c.inject("CreateWorkGroup"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.CreateWorkGroup(in)
+}
+
+// GetWorkGroup injects faults into a call to GetWorkGroup.
+func (c *Chaos) GetWorkGroup(in *aa.GetWorkGroupInput) (*aa.GetWorkGroupOutput, error) {
+	if err := c.inject("GetWorkGroup"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.GetWorkGroup(in)
+}
+
+// ListWorkGroups injects faults into a call to ListWorkGroups.
+func (c *Chaos) ListWorkGroups(in *aa.ListWorkGroupsInput) (*aa.ListWorkGroupsOutput, error) {
+	if err := c.inject("ListWorkGroups"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.ListWorkGroups(in)
+}
+
+// UpdateWorkGroup injects faults into a call to UpdateWorkGroup.
+func (c *Chaos) UpdateWorkGroup(in *aa.UpdateWorkGroupInput) (*aa.UpdateWorkGroupOutput, error) {
+	if err := c.inject("UpdateWorkGroup"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.UpdateWorkGroup(in)
+}
+
+// DeleteWorkGroup injects faults into a call to DeleteWorkGroup.
+func (c *Chaos) DeleteWorkGroup(in *aa.DeleteWorkGroupInput) (*aa.DeleteWorkGroupOutput, error) {
+	if err := c.inject("DeleteWorkGroup"); err != nil {
+		return nil, err
+	}
+
+	return c.AthenaAPI.DeleteWorkGroup(in)
+}
+
+// Retry returns an interceptor which makes a call up to attempts times
+// while it fails with an error worth retrying: one which is throttled, has
+// a code the AWS SDK retries, or is a server error, such as the faults a
+// Chaos injects. It waits backoff before the first retry, doubling the wait
+// before each of the others. Retry panics if attempts is less than one.
+func Retry(attempts int, backoff time.Duration) athena.Interceptor {
+	if attempts < 1 {
+		panic("athenatest: Retry needs at least one attempt")
+	}
+
+	return func(call *athena.Call, invoke athena.Invoker) {
+		wait := backoff
+
+		for i := 1; ; i++ {
+			call.Err = nil
+			if invoke(call); i == attempts || !retryable(call.Err) {
+				return
+			}
+
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+}
+
+// retryable returns whether a call failing with err, an AWS error, is
+// worth retrying. Other errors, e.g. from interceptors, aren't retried.
+func retryable(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	if request.IsErrorThrottle(aerr) || request.IsErrorRetryable(aerr) {
+		return true
+	}
+
+	rf, ok := aerr.(awserr.RequestFailure)
+	return ok && rf.StatusCode() >= http.StatusInternalServerError
+}
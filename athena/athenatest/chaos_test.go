@@ -0,0 +1,145 @@
+package athenatest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestChaosRun(t *testing.T) {
+	f := athenatest.NewFake()
+	f.Respond(`FROM events`, athenatest.Response{Result: events, States: []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning}})
+
+	expected, err := client(f).Run(context.Background(), "db", "SELECT * FROM events", "s3://bucket/")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	chaos := athenatest.NewChaos(f, athenatest.Faults{
+		Throttle:      0.3,
+		InternalError: 0.1,
+		Latency:       0.5,
+		MaxLatency:    time.Millisecond,
+		TruncatePage:  1,
+		StateFlip:     0.3,
+	}, 1)
+
+	c := athena.NewClientWithAPI(chaos).WithPollInterval(time.Millisecond).WithInterceptors(athenatest.Retry(10, time.Millisecond))
+
+	r, err := c.Run(context.Background(), "db", "SELECT * FROM events", "s3://bucket/")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("Result == %v (want %v)", r, expected)
+	}
+
+	for _, fault := range []athenatest.Fault{athenatest.FaultThrottle, athenatest.FaultLatency, athenatest.FaultTruncatedPage, athenatest.FaultStateFlip} {
+		if n := chaos.Injected(fault); n == 0 {
+			t.Errorf("Injected(%s) == 0 (want some)", fault)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected int
+	}{
+		{nil, 1},
+		{awserr.New(aa.ErrCodeTooManyRequestsException, "Rate exceeded", nil), 3},
+		{awserr.NewRequestFailure(awserr.New(aa.ErrCodeInternalServerException, "internal error", nil), 500, ""), 3},
+		{awserr.New(aa.ErrCodeInvalidRequestException, "bad request", nil), 1},
+		{errors.New("not an AWS error"), 1},
+	} {
+		attempts := 0
+		fail := func(call *athena.Call, invoke athena.Invoker) {
+			attempts++
+			call.Err = test.err
+		}
+
+		c := athena.NewClientWithAPI(athenatest.NewFake()).WithInterceptors(athenatest.Retry(3, time.Millisecond), fail)
+		c.DoQuery("db", "SELECT 1", "s3://bucket/")
+
+		if attempts != test.expected {
+			t.Errorf("%v: attempts == %d (want %d)", test.err, attempts, test.expected)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Retry(0) didn't panic")
+		}
+	}()
+
+	athenatest.Retry(0, 0)
+}
+
+func TestChaosMissingFields(t *testing.T) {
+	for _, test := range []struct {
+		faults athenatest.Faults
+		fault  athenatest.Fault
+	}{
+		{athenatest.Faults{MissingStatus: 1}, athenatest.FaultMissingStatus},
+		{athenatest.Faults{MissingResultConfiguration: 1}, athenatest.FaultMissingResultConfiguration},
+	} {
+		chaos := athenatest.NewChaos(athenatest.NewFake(), test.faults, 1)
+
+		q, err := athena.NewClientWithAPI(chaos).DoQuery("db", "SELECT 1", "s3://bucket/")
+		if err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		if _, err := q.Status(); err == nil {
+			t.Errorf("%s: err == nil (want an error)", test.fault)
+		}
+
+		if n := chaos.Injected(test.fault); n != 1 {
+			t.Errorf("Injected(%s) == %d (want 1)", test.fault, n)
+		}
+	}
+}
+
+func TestChaosSeed(t *testing.T) {
+	faults := athenatest.Faults{Throttle: 0.5, Operations: []string{"GetQueryExecution"}}
+
+	// failures returns which of a series of polls fail
+	failures := func(seed int64) []bool {
+		chaos := athenatest.NewChaos(athenatest.NewFake(), faults, seed)
+
+		out, err := chaos.StartQueryExecution(&aa.StartQueryExecutionInput{
+			QueryString:           aws.String("SELECT 1"),
+			QueryExecutionContext: &aa.QueryExecutionContext{Database: aws.String("db")},
+			ResultConfiguration:   &aa.ResultConfiguration{OutputLocation: aws.String("s3://bucket/")},
+		})
+		if err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			_, err := chaos.GetQueryExecution(&aa.GetQueryExecutionInput{QueryExecutionId: out.QueryExecutionId})
+			failed = append(failed, err != nil)
+		}
+
+		return failed
+	}
+
+	first, second := failures(42), failures(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("failures == %v and %v (want the same)", first, second)
+	}
+
+	if other := failures(7); reflect.DeepEqual(first, other) {
+		t.Errorf("failures == %v with another seed (want different)", other)
+	}
+}
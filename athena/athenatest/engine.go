@@ -0,0 +1,697 @@
+package athenatest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+// Backend executes the queries a Fake has no response registered for,
+// such as an Engine. Errors fail the query, with the error as its reason.
+type Backend interface {
+	Execute(database, query string) (athena.Result, error)
+}
+
+// SetBackend sets the backend executing queries matching no response.
+func (f *Fake) SetBackend(b Backend) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.backend = b
+}
+
+// table is a table registered with an Engine.
+type table struct {
+	columns []athena.Column
+	rows    [][]value
+}
+
+// Engine is a Backend executing a subset of Athena's SQL over tables
+// registered from fixtures, so that queries can be tested for their
+// semantics offline. It is safe for concurrent use.
+//
+// Queries are SELECT statements over at most one table, with WHERE, GROUP
+// BY, HAVING, ORDER BY (including by ordinal) and LIMIT clauses, DISTINCT
+// and aliases. Expressions may use comparisons, arithmetic, AND, OR, NOT,
+// IS NULL, IN, BETWEEN, LIKE, CASE, CAST and TRY_CAST, the aggregate
+// functions count, sum, avg, min and max, and the functions abs, coalesce,
+// concat, length, lower, ltrim, replace, round, rtrim, substr, trim and
+// upper. TABLESAMPLE is accepted but keeps every row, so that results are
+// repeatable. Other statements and features fail as Athena reports errors,
+// e.g. NOT_SUPPORTED or SYNTAX_ERROR.
+//
+// Values are bigint, double, boolean or varchar; other types of columns,
+// such as dates, are treated as varchar. As in Athena, bigint arithmetic
+// that overflows fails with NUMERIC_VALUE_OUT_OF_RANGE.
+type Engine struct {
+	mu     sync.RWMutex
+	tables map[string]*table
+}
+
+// NewEngine returns an engine without any tables.
+func NewEngine() *Engine {
+	return &Engine{tables: map[string]*table{}}
+}
+
+// tableKey returns the key of a table in the engine.
+func tableKey(database, name string) string {
+	return strings.ToLower(database) + "." + strings.ToLower(name)
+}
+
+// AddTable registers the rows of r as the table name in database,
+// replacing any table of that name. Values are parsed according to the
+// types of the columns, varchar if not given; empty values are NULL.
+func (e *Engine) AddTable(database, name string, r athena.Result) error {
+	t := &table{columns: make([]athena.Column, len(r.Columns))}
+
+	for i, c := range r.Columns {
+		if c.Type == "" {
+			c.Type = "varchar"
+		}
+
+		c.Name = strings.ToLower(c.Name)
+		t.columns[i] = c
+	}
+
+	for n, row := range r.Rows {
+		if len(row) != len(t.columns) {
+			return fmt.Errorf("%s: row %d has %d values (want %d)", name, n+1, len(row), len(t.columns))
+		}
+
+		values := make([]value, len(row))
+		for i, s := range row {
+			v, err := parseValue(s, t.columns[i].Type)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: column %s: %v", name, n+1, t.columns[i].Name, err)
+			}
+
+			values[i] = v
+		}
+
+		t.rows = append(t.rows, values)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.tables[tableKey(database, name)] = t
+
+	return nil
+}
+
+// parseValue parses s as a value of typ.
+func parseValue(s, typ string) (value, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	switch base := strings.ToLower(strings.SplitN(typ, "(", 2)[0]); base {
+	case "bigint", "integer", "int", "smallint", "tinyint", "double", "real", "float", "decimal", "boolean":
+		return castValue(s, base)
+	}
+
+	return s, nil
+}
+
+// LoadCSV registers a table from CSV with a header row of column names.
+// Columns are varchar unless typed in the header as name:type, e.g.
+// id:bigint.
+func (e *Engine) LoadCSV(database, name string, r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if len(records) == 0 {
+		return fmt.Errorf("%s: no header row", name)
+	}
+
+	var result athena.Result
+
+	for _, h := range records[0] {
+		c := athena.Column{Name: strings.TrimSpace(h)}
+		if i := strings.LastIndexByte(c.Name, ':'); i >= 0 {
+			c.Name, c.Type = c.Name[:i], c.Name[i+1:]
+		}
+
+		result.Columns = append(result.Columns, c)
+	}
+
+	for _, record := range records[1:] {
+		result.Rows = append(result.Rows, athena.Row(record))
+	}
+
+	return e.AddTable(database, name, result)
+}
+
+// LoadJSON registers a table from JSON objects, either in an array or one
+// after another as JSON lines. Columns are in the order they first appear,
+// typed by their values: bigint if every value is an integer, double for
+// other numbers, boolean or varchar. Nested objects and arrays are varchar
+// holding their JSON, and missing keys are NULL.
+func (e *Engine) LoadJSON(database, name string, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var objects []map[string]interface{}
+	var order []string
+	seen := map[string]bool{}
+
+	array := false
+
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		if t == json.Delim('[') && !array && len(objects) == 0 {
+			array = true
+			continue
+		}
+
+		if t == json.Delim(']') && array {
+			break
+		}
+
+		if t != json.Delim('{') {
+			return fmt.Errorf("%s: expected an object, found %v", name, t)
+		}
+
+		object := map[string]interface{}{}
+
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+
+			key := strings.ToLower(k.(string))
+
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+
+			object[key] = v
+
+			if !seen[key] {
+				seen[key] = true
+				order = append(order, key)
+			}
+		}
+
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		objects = append(objects, object)
+	}
+
+	result := athena.Result{Columns: make([]athena.Column, len(order))}
+
+	for i, k := range order {
+		result.Columns[i] = athena.Column{Name: k, Type: jsonType(objects, k)}
+	}
+
+	for _, object := range objects {
+		row := make(athena.Row, len(order))
+
+		for i, k := range order {
+			switch v := object[k].(type) {
+			case nil:
+			case string:
+				row[i] = v
+			case json.Number:
+				row[i] = v.String()
+			case bool:
+				row[i] = fmt.Sprint(v)
+			default:
+				b, _ := json.Marshal(v)
+				row[i] = string(b)
+			}
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	return e.AddTable(database, name, result)
+}
+
+// jsonType returns the type of the values of key in objects.
+func jsonType(objects []map[string]interface{}, key string) string {
+	typ := ""
+
+	for _, object := range objects {
+		t := "varchar"
+
+		switch v := object[key].(type) {
+		case nil:
+			continue
+		case json.Number:
+			t = "double"
+			if _, err := v.Int64(); err == nil {
+				t = "bigint"
+			}
+		case bool:
+			t = "boolean"
+		}
+
+		switch {
+		case typ == "":
+			typ = t
+		case typ == "bigint" && t == "double":
+			typ = "double"
+		case typ == "double" && t == "bigint":
+		case typ != t:
+			return "varchar"
+		}
+	}
+
+	if typ == "" {
+		return "varchar"
+	}
+
+	return typ
+}
+
+// LoadFile registers a table from a CSV or JSON file, by its extension:
+// .csv or .json (including JSON lines).
+func (e *Engine) LoadFile(database, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return e.LoadCSV(database, name, f)
+	case ".json", ".jsonl", ".ndjson":
+		return e.LoadJSON(database, name, f)
+	default:
+		return fmt.Errorf("%s: unknown fixture format %q", path, ext)
+	}
+}
+
+// output is a row of the result, with the environment it was evaluated in
+// for ordering.
+type output struct {
+	values []value
+	env    *env
+}
+
+// Execute runs query on database, returning its result without the row of
+// column names Athena pads onto results.
+func (e *Engine) Execute(database, query string) (athena.Result, error) {
+	s, err := parse(query)
+	if err != nil {
+		return athena.Result{}, err
+	}
+
+	t := &table{rows: [][]value{{}}}
+	base := env{columns: map[string]int{}}
+
+	if s.table != "" {
+		db := s.database
+		if db == "" {
+			db = database
+		}
+
+		e.mu.RLock()
+		t = e.tables[tableKey(db, s.table)]
+		e.mu.RUnlock()
+
+		if t == nil {
+			return athena.Result{}, syntaxError("Table awsdatacatalog.%s.%s does not exist", db, s.table)
+		}
+
+		base.table = s.table
+		if s.alias != "" {
+			base.table = s.alias
+		}
+
+		for i, c := range t.columns {
+			base.columns[c.Name] = i
+		}
+	}
+
+	// expand * into the columns of the table
+	var items []selectItem
+
+	for _, item := range s.items {
+		if !item.star {
+			items = append(items, item)
+			continue
+		}
+
+		if s.table == "" {
+			return athena.Result{}, syntaxError("SELECT * not allowed in queries without FROM clause")
+		}
+
+		for _, c := range t.columns {
+			items = append(items, selectItem{x: columnRef{name: c.Name}, alias: c.Name})
+		}
+	}
+
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.alias
+		if ref, ok := item.x.(columnRef); ok && names[i] == "" {
+			names[i] = ref.name
+		}
+
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("_col%d", i)
+		}
+	}
+
+	var rows [][]value
+
+	for _, row := range t.rows {
+		if s.where != nil {
+			if hasAggregate(s.where) {
+				return athena.Result{}, syntaxError("WHERE clause cannot contain aggregations")
+			}
+
+			v, err := eval(s.where, &env{table: base.table, columns: base.columns, row: row})
+			if err != nil {
+				return athena.Result{}, err
+			}
+
+			if v != true {
+				continue
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	aggregate := len(s.groupBy) > 0 || s.having != nil
+	for _, item := range items {
+		aggregate = aggregate || hasAggregate(item.x)
+	}
+
+	for _, o := range s.orderBy {
+		aggregate = aggregate || hasAggregate(o.x)
+	}
+
+	var envs []*env
+
+	if aggregate {
+		if envs, err = group(s, items, base, rows); err != nil {
+			return athena.Result{}, err
+		}
+	} else {
+		for _, row := range rows {
+			envs = append(envs, &env{table: base.table, columns: base.columns, row: row})
+		}
+	}
+
+	var out []output
+
+	for _, en := range envs {
+		if s.having != nil {
+			v, err := eval(s.having, en)
+			if err != nil {
+				return athena.Result{}, err
+			}
+
+			if v != true {
+				continue
+			}
+		}
+
+		values := make([]value, len(items))
+		for i, item := range items {
+			if values[i], err = eval(item.x, en); err != nil {
+				return athena.Result{}, err
+			}
+		}
+
+		out = append(out, output{values, en})
+	}
+
+	if s.distinct {
+		seen := map[string]bool{}
+		distinct := out[:0]
+
+		for _, o := range out {
+			if k := key(o.values); !seen[k] {
+				seen[k] = true
+				distinct = append(distinct, o)
+			}
+		}
+
+		out = distinct
+	}
+
+	if err := order(s.orderBy, names, out); err != nil {
+		return athena.Result{}, err
+	}
+
+	if s.limit >= 0 && s.limit < len(out) {
+		out = out[:s.limit]
+	}
+
+	return result(names, items, t, out), nil
+}
+
+// group groups rows by the GROUP BY expressions, returning the environment
+// of each group in the order groups first appear. Without GROUP BY, every
+// row is in one group.
+func group(s *statement, items []selectItem, base env, rows [][]value) ([]*env, error) {
+	groupBy := make([]expr, len(s.groupBy))
+
+	for i, g := range s.groupBy {
+		groupBy[i] = g
+
+		// GROUP BY 2 groups by the second select expression
+		if l, ok := g.(literal); ok {
+			n, ok := l.v.(int64)
+			if !ok || n < 1 || int(n) > len(items) {
+				return nil, syntaxError("GROUP BY position %v is not in select list", l.v)
+			}
+
+			groupBy[i] = items[n-1].x
+		}
+
+		if hasAggregate(groupBy[i]) {
+			return nil, syntaxError("GROUP BY clause cannot contain aggregations")
+		}
+	}
+
+	checks := []expr{s.having}
+	for _, item := range items {
+		checks = append(checks, item.x)
+	}
+
+	for _, o := range s.orderBy {
+		if _, ok := o.x.(literal); !ok {
+			checks = append(checks, o.x)
+		}
+	}
+
+	for _, x := range checks {
+		if ref, ok := x.(columnRef); ok && ref.table == "" && isOutputName(ref.name, items) && !isColumn(ref.name, base) {
+			continue
+		}
+
+		if err := checkGrouped(x, groupBy); err != nil {
+			return nil, err
+		}
+	}
+
+	var envs []*env
+	index := map[string]int{}
+
+	for _, row := range rows {
+		keys := make([]value, len(groupBy))
+		for i, g := range groupBy {
+			v, err := eval(g, &env{table: base.table, columns: base.columns, row: row})
+			if err != nil {
+				return nil, err
+			}
+
+			keys[i] = v
+		}
+
+		k := key(keys)
+		i, ok := index[k]
+
+		if !ok {
+			i = len(envs)
+			index[k] = i
+			envs = append(envs, &env{table: base.table, columns: base.columns, row: row, group: [][]value{}})
+		}
+
+		envs[i].group = append(envs[i].group, row)
+	}
+
+	// aggregating without GROUP BY gives a row even without any rows
+	if len(envs) == 0 && len(groupBy) == 0 {
+		envs = append(envs, &env{table: base.table, columns: base.columns, group: [][]value{}})
+	}
+
+	return envs, nil
+}
+
+func isOutputName(name string, items []selectItem) bool {
+	for _, item := range items {
+		if item.alias == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isColumn(name string, base env) bool {
+	_, ok := base.columns[name]
+	return ok
+}
+
+// order sorts out by the ORDER BY items. Items may be ordinals or names
+// of output columns, or expressions evaluated for each row.
+func order(items []orderItem, names []string, out []output) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	keys := make([][]value, len(out))
+
+	for i, o := range out {
+		keys[i] = make([]value, len(items))
+
+		for j, item := range items {
+			column := -1
+
+			switch x := item.x.(type) {
+			case literal:
+				n, ok := x.v.(int64)
+				if !ok || n < 1 || int(n) > len(names) {
+					return syntaxError("ORDER BY position %v is not in select list", x.v)
+				}
+
+				column = int(n - 1)
+			case columnRef:
+				if x.table == "" {
+					for k, name := range names {
+						if name == x.name {
+							column = k
+							break
+						}
+					}
+				}
+			}
+
+			if column >= 0 {
+				keys[i][j] = o.values[column]
+				continue
+			}
+
+			v, err := eval(item.x, o.env)
+			if err != nil {
+				return err
+			}
+
+			keys[i][j] = v
+		}
+	}
+
+	perm := make([]int, len(out))
+	for i := range perm {
+		perm[i] = i
+	}
+
+	sort.SliceStable(perm, func(a, b int) bool {
+		ka, kb := keys[perm[a]], keys[perm[b]]
+
+		for j, item := range items {
+			va, vb := ka[j], kb[j]
+
+			switch {
+			case va == nil && vb == nil:
+				continue
+			case va == nil:
+				return item.nullsFirst
+			case vb == nil:
+				return !item.nullsFirst
+			}
+
+			c, err := compare(va, vb)
+			if err != nil {
+				c = strings.Compare(valueType(va), valueType(vb))
+			}
+
+			if c != 0 {
+				return c < 0 != item.desc
+			}
+		}
+
+		return false
+	})
+
+	sorted := make([]output, len(out))
+	for i, p := range perm {
+		sorted[i] = out[p]
+	}
+
+	copy(out, sorted)
+
+	return nil
+}
+
+// result formats the output rows. Columns selecting a column of the table
+// have its type, and others the type of their first value which isn't NULL.
+func result(names []string, items []selectItem, t *table, out []output) athena.Result {
+	r := athena.Result{Columns: make([]athena.Column, len(names)), Rows: []athena.Row{}}
+
+	for i, name := range names {
+		typ := ""
+
+		if ref, ok := items[i].x.(columnRef); ok {
+			for _, c := range t.columns {
+				if c.Name == ref.name {
+					typ = c.Type
+				}
+			}
+		}
+
+		for _, o := range out {
+			if o.values[i] != nil && typ == "" {
+				typ = valueType(o.values[i])
+			}
+		}
+
+		if typ == "" {
+			typ = "varchar"
+		}
+
+		r.Columns[i] = athena.Column{Name: name, Type: typ}
+	}
+
+	for _, o := range out {
+		row := make(athena.Row, len(o.values))
+		for i, v := range o.values {
+			row[i] = format(v)
+		}
+
+		r.Rows = append(r.Rows, row)
+	}
+
+	return r
+}
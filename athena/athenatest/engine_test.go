@@ -0,0 +1,210 @@
+package athenatest_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+)
+
+func engine(t *testing.T) *athenatest.Engine {
+	e := athenatest.NewEngine()
+
+	for _, fixture := range []struct{ table, path string }{
+		{"events", "testdata/events.csv"},
+		{"users", "testdata/users.json"},
+	} {
+		if err := e.LoadFile("db", fixture.table, fixture.path); err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+	}
+
+	return e
+}
+
+func TestEngineExecute(t *testing.T) {
+	e := engine(t)
+
+	for _, test := range []struct {
+		query   string
+		columns string
+		rows    []athena.Row
+	}{
+		{
+			"SELECT id, kind FROM events WHERE amount > 1 ORDER BY id DESC",
+			"id:bigint kind:varchar",
+			[]athena.Row{{"4", "purchase"}, {"3", "click"}, {"1", "click"}},
+		},
+		{
+			"SELECT kind, count(*) AS n, sum(amount) total FROM events GROUP BY kind ORDER BY n DESC, kind",
+			"kind:varchar n:bigint total:double",
+			[]athena.Row{{"click", "3", "4.5"}, {"purchase", "1", "10.0"}, {"view", "1", ""}},
+		},
+		{
+			"SELECT user, count(DISTINCT dt) FROM events WHERE user IS NOT NULL GROUP BY 1 HAVING count(*) > 1 ORDER BY 1",
+			"user:varchar _col1:bigint",
+			[]athena.Row{{"alice", "2"}, {"bob", "2"}},
+		},
+		{
+			"SELECT count(*), avg(amount), min(dt), max(id) FROM events WHERE kind <> 'none'",
+			"_col0:bigint _col1:double _col2:varchar _col3:bigint",
+			[]athena.Row{{"5", "3.625", "2019-10-01", "5"}},
+		},
+		{
+			"SELECT count(*) FROM events WHERE kind = 'none'",
+			"_col0:bigint",
+			[]athena.Row{{"0"}},
+		},
+		{
+			"SELECT DISTINCT kind FROM events ORDER BY kind LIMIT 2",
+			"kind:varchar",
+			[]athena.Row{{"click"}, {"purchase"}},
+		},
+		{
+			`SELECT upper(e."user") AS u, coalesce(amount, 0.0) * 2 AS double_amount FROM db.events AS e WHERE e.id IN (2, 5) ORDER BY 1 NULLS FIRST`,
+			"u:varchar double_amount:double",
+			[]athena.Row{{"", "1.0"}, {"BOB", "0.0"}},
+		},
+		{
+			"SELECT id FROM events WHERE dt BETWEEN '2019-10-02' AND DATE '2019-10-03' AND user LIKE '%o%' OR id = 1 ORDER BY id",
+			"id:bigint",
+			[]athena.Row{{"1"}, {"3"}},
+		},
+		{
+			"SELECT CASE WHEN amount >= 10 THEN 'big' WHEN amount IS NULL THEN 'none' ELSE 'small' END AS size, id / 2, id % 2, substr(kind, 2, 3) || '!' FROM events ORDER BY id LIMIT 3",
+			"size:varchar _col1:bigint _col2:bigint _col3:varchar",
+			[]athena.Row{{"small", "0", "1", "lic!"}, {"none", "1", "0", "iew!"}, {"small", "1", "1", "lic!"}},
+		},
+		{
+			"SELECT name, age, admin, tags FROM users WHERE age > 30 ORDER BY age",
+			"name:varchar age:double admin:boolean tags:varchar",
+			[]athena.Row{{"alice", "34.0", "true", `["a","b"]`}, {"carol", "41.5", ""}},
+		},
+		{
+			"SELECT CAST(age AS bigint) AS years, try_cast(name AS integer) FROM users WHERE NOT admin",
+			"years:bigint _col1:varchar",
+			[]athena.Row{{"27", ""}},
+		},
+		{
+			"SELECT 1 + 2, 'a' AS letter, round(2.345, 2), length('héllo')",
+			"_col0:bigint letter:varchar _col2:double _col3:bigint",
+			[]athena.Row{{"3", "a", "2.35", "5"}},
+		},
+		{
+			"SELECT -9223372036854775808, abs(-9223372036854775807), 9223372036854775807 - 1 + 1, -4611686018427387904 * 2, -(-1)",
+			"_col0:bigint _col1:bigint _col2:bigint _col3:bigint _col4:bigint",
+			[]athena.Row{{"-9223372036854775808", "9223372036854775807", "9223372036854775807", "-9223372036854775808", "1"}},
+		},
+	} {
+		r, err := e.Execute("db", test.query)
+		if err != nil {
+			t.Errorf("%s: err == %v (want nil)", test.query, err)
+			continue
+		}
+
+		var columns []string
+		for _, c := range r.Columns {
+			columns = append(columns, c.Name+":"+c.Type)
+		}
+
+		if c := strings.Join(columns, " "); c != test.columns {
+			t.Errorf("%s: Columns == %v (want %v)", test.query, c, test.columns)
+		}
+
+		for i := range test.rows {
+			for len(test.rows[i]) < len(r.Columns) {
+				test.rows[i] = append(test.rows[i], "")
+			}
+		}
+
+		if !reflect.DeepEqual(r.Rows, test.rows) {
+			t.Errorf("%s: Rows == %q (want %q)", test.query, r.Rows, test.rows)
+		}
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	e := engine(t)
+
+	for _, test := range []struct {
+		query string
+		err   string
+	}{
+		{"SELECT * FROM missing", "SYNTAX_ERROR: Table awsdatacatalog.db.missing does not exist"},
+		{"SELECT nope FROM events", "SYNTAX_ERROR: Column 'nope' cannot be resolved"},
+		{"SELECT kind, count(*) FROM events", "SYNTAX_ERROR: 'kind' must be an aggregate expression or appear in GROUP BY clause"},
+		{"SELECT id FROM events WHERE count(*) > 1", "SYNTAX_ERROR: WHERE clause cannot contain aggregations"},
+		{"SELECT id FROM events WHERE kind = 1", "SYNTAX_ERROR: '=' cannot be applied to varchar, bigint"},
+		{"SELECT id / 0 FROM events", "DIVISION_BY_ZERO: Division by zero"},
+		{"SELECT 9223372036854775807 + 1", "NUMERIC_VALUE_OUT_OF_RANGE: bigint addition overflow: 9223372036854775807 + 1"},
+		{"SELECT -9223372036854775808 - 1", "NUMERIC_VALUE_OUT_OF_RANGE: bigint subtraction overflow: -9223372036854775808 - 1"},
+		{"SELECT 4611686018427387904 * 2", "NUMERIC_VALUE_OUT_OF_RANGE: bigint multiplication overflow: 4611686018427387904 * 2"},
+		{"SELECT -1 * -9223372036854775808", "NUMERIC_VALUE_OUT_OF_RANGE: bigint multiplication overflow: -1 * -9223372036854775808"},
+		{"SELECT -9223372036854775808 / -1", "NUMERIC_VALUE_OUT_OF_RANGE: bigint division overflow: -9223372036854775808 / -1"},
+		{"SELECT -(-9223372036854775808)", "NUMERIC_VALUE_OUT_OF_RANGE: bigint negation overflow: -9223372036854775808"},
+		{"SELECT abs(-9223372036854775808)", "NUMERIC_VALUE_OUT_OF_RANGE: Value -9223372036854775808 is out of range for abs(bigint)"},
+		{"SELECT sum(id + 9223372036854775800) FROM events", "NUMERIC_VALUE_OUT_OF_RANGE: bigint addition overflow: 9223372036854775801 + 9223372036854775802"},
+		{"SELECT id FROM events ORDER BY 3", "SYNTAX_ERROR: ORDER BY position 3 is not in select list"},
+		{"SELECT nope(id) FROM events", "FUNCTION_NOT_FOUND: function nope not registered"},
+		{"SELECT id FROM events e JOIN users u ON e.user = u.name", "NOT_SUPPORTED: joins are not supported"},
+		{"DROP TABLE events", "NOT_SUPPORTED: only SELECT statements can be executed"},
+		{"SELECT id FROM events WHERE", "SYNTAX_ERROR: unexpected \"\" at 27"},
+		{"SELECT 'unterminated", "SYNTAX_ERROR: unterminated quote at 7"},
+	} {
+		_, err := e.Execute("db", test.query)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: err == %v (want %v)", test.query, err, test.err)
+		}
+	}
+}
+
+// TestEngineHelpers runs the queries built by the athena package's helpers
+// through a fake, checking their semantics.
+func TestEngineHelpers(t *testing.T) {
+	f := athenatest.NewFake()
+	f.SetBackend(engine(t))
+	c := client(f)
+	ctx := context.Background()
+
+	query := func(q string, err error) string {
+		if err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		return q
+	}
+
+	for _, test := range []struct {
+		query string
+		rows  []athena.Row
+	}{
+		{query(athena.NRows("db.events", 2)), []athena.Row{{"1", "click", "alice", "1.5", "2019-10-01"}, {"2", "view", "bob", "", "2019-10-01"}}},
+		{query(athena.Count("events", map[string]interface{}{"id": 1, "kind": "click", "dt": "2019-10-01"})), []athena.Row{{"1"}}},
+		{query(athena.Sample("events", 10, 1)), []athena.Row{{"1", "click", "alice", "1.5", "2019-10-01"}}},
+		{query(athena.Distinct("events", "user", 2)), []athena.Row{{"alice", "2"}, {"bob", "2"}}},
+		{query(athena.NullCounts("events", []string{"user", "amount"})), []athena.Row{{"5", "1", "1"}}},
+	} {
+		r, err := c.Run(ctx, "db", test.query, "s3://bucket/")
+		if err != nil {
+			t.Errorf("%s: err == %v (want nil)", test.query, err)
+			continue
+		}
+
+		if rows := r.WithoutHeader().Rows; !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%s: Rows == %q (want %q)", test.query, rows, test.rows)
+		}
+	}
+
+	values, err := c.DistinctValues(ctx, "db", "events", "kind", 10, "s3://bucket/")
+	if err != nil || len(values) != 3 || values[0].Value != "click" || values[0].Count != 3 {
+		t.Errorf("DistinctValues == %v, %v (want click 3 times first)", values, err)
+	}
+
+	_, err = c.Run(ctx, "db", "SELECT * FROM missing", "s3://bucket/")
+	if qerr, ok := err.(*athena.QueryError); !ok || !strings.Contains(qerr.Reason, "does not exist") {
+		t.Errorf("err == %v (want a QueryError for the missing table)", err)
+	}
+}
@@ -0,0 +1,935 @@
+package athenatest
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// value is a SQL value: nil for NULL, or an int64, float64, string or bool.
+type value interface{}
+
+// env is the context expressions are evaluated in: a row of a table, and
+// when aggregating, the rows of its group.
+type env struct {
+	// table is the name, or alias, qualified column references must use.
+	table   string
+	columns map[string]int
+
+	row   []value
+	group [][]value
+}
+
+// aggregates are the aggregate functions.
+var aggregates = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
+
+// children returns the subexpressions of x.
+func children(x expr) []expr {
+	switch x := x.(type) {
+	case unary:
+		return []expr{x.x}
+	case binary:
+		return []expr{x.l, x.r}
+	case isNull:
+		return []expr{x.x}
+	case inList:
+		return append([]expr{x.x}, x.list...)
+	case between:
+		return []expr{x.x, x.lo, x.hi}
+	case like:
+		return []expr{x.x, x.pattern}
+	case call:
+		return x.args
+	case caseExpr:
+		list := []expr{x.operand, x.els}
+		for _, w := range x.whens {
+			list = append(list, w.cond, w.result)
+		}
+
+		return list
+	case cast:
+		return []expr{x.x}
+	}
+
+	return nil
+}
+
+// hasAggregate returns true if x calls an aggregate function.
+func hasAggregate(x expr) bool {
+	if c, ok := x.(call); ok && aggregates[c.name] {
+		return true
+	}
+
+	for _, child := range children(x) {
+		if child != nil && hasAggregate(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkGrouped returns an error if x refers to a column outside of an
+// aggregate function which isn't one of the grouping expressions.
+func checkGrouped(x expr, groupBy []expr) error {
+	if x == nil {
+		return nil
+	}
+
+	for _, g := range groupBy {
+		if equalExpr(x, g) {
+			return nil
+		}
+	}
+
+	switch x := x.(type) {
+	case call:
+		if aggregates[x.name] {
+			return nil
+		}
+	case columnRef:
+		return syntaxError("'%s' must be an aggregate expression or appear in GROUP BY clause", x.name)
+	}
+
+	for _, child := range children(x) {
+		if err := checkGrouped(child, groupBy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// equalExpr returns true if a and b are the same expression, ignoring
+// whether column references are qualified.
+func equalExpr(a, b expr) bool {
+	if ca, ok := a.(columnRef); ok {
+		cb, ok := b.(columnRef)
+		return ok && ca.name == cb.name
+	}
+
+	return fmt.Sprintf("%#v", a) == fmt.Sprintf("%#v", b)
+}
+
+// eval evaluates x in e.
+func eval(x expr, e *env) (value, error) {
+	switch x := x.(type) {
+	case literal:
+		return x.v, nil
+	case columnRef:
+		if x.table != "" && x.table != e.table {
+			return nil, syntaxError("column prefix '%s' cannot be resolved", x.table)
+		}
+
+		i, ok := e.columns[x.name]
+		if !ok {
+			return nil, syntaxError("Column '%s' cannot be resolved", x.name)
+		}
+
+		if e.row == nil {
+			return nil, nil
+		}
+
+		return e.row[i], nil
+	case unary:
+		return evalUnary(x, e)
+	case binary:
+		return evalBinary(x, e)
+	case isNull:
+		v, err := eval(x.x, e)
+		return (v == nil) != x.not, err
+	case inList:
+		return evalIn(x, e)
+	case between:
+		return evalBetween(x, e)
+	case like:
+		return evalLike(x, e)
+	case call:
+		if aggregates[x.name] {
+			return evalAggregate(x, e)
+		}
+
+		return evalFunction(x, e)
+	case caseExpr:
+		return evalCase(x, e)
+	case cast:
+		v, err := eval(x.x, e)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err = castValue(v, x.typ)
+		if err != nil && x.try {
+			return nil, nil
+		}
+
+		return v, err
+	}
+
+	return nil, fmt.Errorf("NOT_SUPPORTED: expression %#v", x)
+}
+
+func evalUnary(x unary, e *env) (value, error) {
+	v, err := eval(x.x, e)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	switch x.op {
+	case "not":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, typeError("NOT", v)
+		}
+
+		return !b, nil
+	default:
+		switch n := v.(type) {
+		case int64:
+			if n == math.MinInt64 {
+				return nil, rangeError("bigint negation overflow: %d", n)
+			}
+
+			return -n, nil
+		case float64:
+			return -n, nil
+		}
+
+		return nil, typeError("-", v)
+	}
+}
+
+// typeError reports an operator applied to a value of the wrong type.
+func typeError(op string, values ...value) error {
+	types := make([]string, len(values))
+	for i, v := range values {
+		types[i] = valueType(v)
+	}
+
+	return syntaxError("'%s' cannot be applied to %s", op, strings.Join(types, ", "))
+}
+
+// rangeError returns an error as Athena reports values out of range of
+// their type, such as bigint arithmetic overflowing.
+func rangeError(format string, args ...interface{}) error {
+	return fmt.Errorf("NUMERIC_VALUE_OUT_OF_RANGE: "+format, args...)
+}
+
+func evalBinary(x binary, e *env) (value, error) {
+	l, err := eval(x.l, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := eval(x.r, e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x.op {
+	case "and", "or":
+		return logic(x.op, l, r)
+	}
+
+	if l == nil || r == nil {
+		return nil, nil
+	}
+
+	switch x.op {
+	case "=", "<>", "<", "<=", ">", ">=":
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, typeError(x.op, l, r)
+		}
+
+		switch x.op {
+		case "=":
+			return c == 0, nil
+		case "<>":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "||":
+		return format(l) + format(r), nil
+	}
+
+	return arithmetic(x.op, l, r)
+}
+
+// logic applies AND or OR with SQL's three valued logic.
+func logic(op string, l, r value) (value, error) {
+	lb, lok := l.(bool)
+	rb, rok := r.(bool)
+
+	if l != nil && !lok || r != nil && !rok {
+		return nil, typeError(strings.ToUpper(op), l, r)
+	}
+
+	if op == "and" {
+		switch {
+		case lok && !lb || rok && !rb:
+			return false, nil
+		case lok && rok:
+			return true, nil
+		}
+
+		return nil, nil
+	}
+
+	switch {
+	case lok && lb || rok && rb:
+		return true, nil
+	case lok && rok:
+		return false, nil
+	}
+
+	return nil, nil
+}
+
+func arithmetic(op string, l, r value) (value, error) {
+	li, lint := l.(int64)
+	ri, rint := r.(int64)
+
+	if lint && rint {
+		// bigint arithmetic fails rather than wrapping around
+		switch op {
+		case "+":
+			if n := li + ri; ri > 0 && n < li || ri < 0 && n > li {
+				return nil, rangeError("bigint addition overflow: %d + %d", li, ri)
+			}
+
+			return li + ri, nil
+		case "-":
+			if n := li - ri; ri < 0 && n < li || ri > 0 && n > li {
+				return nil, rangeError("bigint subtraction overflow: %d - %d", li, ri)
+			}
+
+			return li - ri, nil
+		case "*":
+			if n := li * ri; li != 0 && (n/li != ri || li == -1 && ri == math.MinInt64) {
+				return nil, rangeError("bigint multiplication overflow: %d * %d", li, ri)
+			}
+
+			return li * ri, nil
+		}
+
+		if ri == 0 {
+			return nil, fmt.Errorf("DIVISION_BY_ZERO: Division by zero")
+		}
+
+		if op == "/" {
+			if li == math.MinInt64 && ri == -1 {
+				return nil, rangeError("bigint division overflow: %d / %d", li, ri)
+			}
+
+			return li / ri, nil
+		}
+
+		return li % ri, nil
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+
+	if !lok || !rok {
+		return nil, typeError(op, l, r)
+	}
+
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		return lf / rf, nil
+	}
+
+	return math.Mod(lf, rf), nil
+}
+
+func toFloat(v value) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// compare compares two values which are not NULL, returning an error if
+// they can't be compared.
+func compare(a, b value) (int, error) {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		if !ok {
+			return 0, fmt.Errorf("cannot compare")
+		}
+
+		switch {
+		case af < bf:
+			return -1, nil
+		case af > bf:
+			return 1, nil
+		}
+
+		return 0, nil
+	}
+
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, nil
+			case b:
+				return -1, nil
+			}
+
+			return 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot compare")
+}
+
+func evalIn(x inList, e *env) (value, error) {
+	v, err := eval(x.x, e)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	sawNull := false
+
+	for _, item := range x.list {
+		w, err := eval(item, e)
+		if err != nil {
+			return nil, err
+		}
+
+		if w == nil {
+			sawNull = true
+			continue
+		}
+
+		c, err := compare(v, w)
+		if err != nil {
+			return nil, typeError("IN", v, w)
+		}
+
+		if c == 0 {
+			return !x.not, nil
+		}
+	}
+
+	if sawNull {
+		return nil, nil
+	}
+
+	return x.not, nil
+}
+
+func evalBetween(x between, e *env) (value, error) {
+	v, err := evalBinary(binary{"and", binary{">=", x.x, x.lo}, binary{"<=", x.x, x.hi}}, e)
+	if err != nil || v == nil || !x.not {
+		return v, err
+	}
+
+	return !v.(bool), nil
+}
+
+func evalLike(x like, e *env) (value, error) {
+	v, err := eval(x.x, e)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := eval(x.pattern, e)
+	if err != nil || v == nil || p == nil {
+		return nil, err
+	}
+
+	s, ok := v.(string)
+	pattern, pok := p.(string)
+
+	if !ok || !pok {
+		return nil, typeError("LIKE", v, p)
+	}
+
+	var re strings.Builder
+	re.WriteString("(?s)^")
+
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			re.WriteString(".*")
+		case '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	re.WriteString("$")
+
+	matched, err := regexp.MatchString(re.String(), s)
+
+	return matched != x.not, err
+}
+
+func evalCase(x caseExpr, e *env) (value, error) {
+	var operand value
+
+	if x.operand != nil {
+		var err error
+		if operand, err = eval(x.operand, e); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, w := range x.whens {
+		v, err := eval(w.cond, e)
+		if err != nil {
+			return nil, err
+		}
+
+		matched := v == true
+		if x.operand != nil {
+			matched = false
+			if operand != nil && v != nil {
+				c, err := compare(operand, v)
+				matched = err == nil && c == 0
+			}
+		}
+
+		if matched {
+			return eval(w.result, e)
+		}
+	}
+
+	if x.els == nil {
+		return nil, nil
+	}
+
+	return eval(x.els, e)
+}
+
+// evalAggregate evaluates an aggregate function over the rows of the group.
+func evalAggregate(x call, e *env) (value, error) {
+	if e.group == nil {
+		return nil, syntaxError("aggregate function %s is not allowed here", x.name)
+	}
+
+	if x.star {
+		if x.name != "count" {
+			return nil, syntaxError("%s(*) is not supported", x.name)
+		}
+
+		return int64(len(e.group)), nil
+	}
+
+	if len(x.args) != 1 {
+		return nil, syntaxError("%s takes one argument", x.name)
+	}
+
+	var values []value
+	seen := map[string]bool{}
+
+	for _, row := range e.group {
+		v, err := eval(x.args[0], &env{table: e.table, columns: e.columns, row: row})
+		if err != nil {
+			return nil, err
+		}
+
+		if v == nil {
+			continue
+		}
+
+		if x.distinct {
+			k := key([]value{v})
+			if seen[k] {
+				continue
+			}
+
+			seen[k] = true
+		}
+
+		values = append(values, v)
+	}
+
+	if x.name == "count" {
+		return int64(len(values)), nil
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	switch x.name {
+	case "min", "max":
+		best := values[0]
+		for _, v := range values[1:] {
+			c, err := compare(v, best)
+			if err != nil {
+				return nil, typeError(x.name, v, best)
+			}
+
+			if c < 0 && x.name == "min" || c > 0 && x.name == "max" {
+				best = v
+			}
+		}
+
+		return best, nil
+	}
+
+	var sum value = int64(0)
+
+	for _, v := range values {
+		if _, ok := toFloat(v); !ok {
+			return nil, typeError(x.name, v)
+		}
+
+		s, err := arithmetic("+", sum, v)
+		if err != nil {
+			return nil, err
+		}
+
+		sum = s
+	}
+
+	if x.name == "sum" {
+		return sum, nil
+	}
+
+	f, _ := toFloat(sum)
+
+	return f / float64(len(values)), nil
+}
+
+// evalFunction evaluates a scalar function.
+func evalFunction(x call, e *env) (value, error) {
+	if x.star || x.distinct {
+		return nil, syntaxError("%s is not an aggregate function", x.name)
+	}
+
+	args := make([]value, len(x.args))
+	for i, a := range x.args {
+		v, err := eval(a, e)
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = v
+	}
+
+	arity := func(min, max int) error {
+		if len(args) < min || len(args) > max {
+			return syntaxError("wrong number of arguments to %s", x.name)
+		}
+
+		return nil
+	}
+
+	switch x.name {
+	case "coalesce":
+		for _, v := range args {
+			if v != nil {
+				return v, nil
+			}
+		}
+
+		return nil, nil
+	case "concat":
+		var b strings.Builder
+		for _, v := range args {
+			if v == nil {
+				return nil, nil
+			}
+
+			b.WriteString(format(v))
+		}
+
+		return b.String(), nil
+	}
+
+	for _, v := range args {
+		if v == nil {
+			return nil, nil
+		}
+	}
+
+	switch x.name {
+	case "lower", "upper", "length", "trim", "ltrim", "rtrim":
+		if err := arity(1, 1); err != nil {
+			return nil, err
+		}
+
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, typeError(x.name, args[0])
+		}
+
+		switch x.name {
+		case "lower":
+			return strings.ToLower(s), nil
+		case "upper":
+			return strings.ToUpper(s), nil
+		case "length":
+			return int64(len([]rune(s))), nil
+		case "trim":
+			return strings.TrimSpace(s), nil
+		case "ltrim":
+			return strings.TrimLeft(s, " \t\n\r"), nil
+		}
+
+		return strings.TrimRight(s, " \t\n\r"), nil
+	case "substr", "substring":
+		if err := arity(2, 3); err != nil {
+			return nil, err
+		}
+
+		return substr(args)
+	case "replace":
+		if err := arity(2, 3); err != nil {
+			return nil, err
+		}
+
+		s, ok1 := args[0].(string)
+		from, ok2 := args[1].(string)
+		to := ""
+
+		if len(args) == 3 {
+			to, _ = args[2].(string)
+		}
+
+		if !ok1 || !ok2 {
+			return nil, typeError(x.name, args...)
+		}
+
+		return strings.ReplaceAll(s, from, to), nil
+	case "abs":
+		if err := arity(1, 1); err != nil {
+			return nil, err
+		}
+
+		switch n := args[0].(type) {
+		case int64:
+			if n == math.MinInt64 {
+				return nil, rangeError("Value %d is out of range for abs(bigint)", n)
+			}
+
+			if n < 0 {
+				return -n, nil
+			}
+
+			return n, nil
+		case float64:
+			return math.Abs(n), nil
+		}
+
+		return nil, typeError(x.name, args[0])
+	case "round":
+		if err := arity(1, 2); err != nil {
+			return nil, err
+		}
+
+		var digits int64
+		if len(args) == 2 {
+			d, ok := args[1].(int64)
+			if !ok {
+				return nil, typeError(x.name, args...)
+			}
+
+			digits = d
+		}
+
+		switch n := args[0].(type) {
+		case int64:
+			return n, nil
+		case float64:
+			p := math.Pow(10, float64(digits))
+			return math.Round(n*p) / p, nil
+		}
+
+		return nil, typeError(x.name, args[0])
+	}
+
+	return nil, fmt.Errorf("FUNCTION_NOT_FOUND: function %s not registered", x.name)
+}
+
+// substr returns the substring of args[0] starting at the 1-based position
+// args[1], which counts from the end if negative, of length args[2].
+func substr(args []value) (value, error) {
+	s, ok := args[0].(string)
+	start, sok := args[1].(int64)
+
+	if !ok || !sok {
+		return nil, typeError("substr", args...)
+	}
+
+	runes := []rune(s)
+	n := int64(len(runes))
+
+	if start < 0 {
+		start = n + start + 1
+	}
+
+	if start < 1 || start > n {
+		return "", nil
+	}
+
+	end := n
+	if len(args) == 3 {
+		length, ok := args[2].(int64)
+		if !ok {
+			return nil, typeError("substr", args...)
+		}
+
+		if start-1+length < end {
+			end = start - 1 + length
+		}
+	}
+
+	if end < start-1 {
+		return "", nil
+	}
+
+	return string(runes[start-1 : end]), nil
+}
+
+// castValue converts v to typ.
+func castValue(v value, typ string) (value, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch typ {
+	case "varchar", "char", "string", "date", "timestamp":
+		return format(v), nil
+	case "bigint", "integer", "int", "smallint", "tinyint":
+		switch n := v.(type) {
+		case int64:
+			return n, nil
+		case float64:
+			return int64(math.Round(n)), nil
+		case bool:
+			if n {
+				return int64(1), nil
+			}
+
+			return int64(0), nil
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("INVALID_CAST_ARGUMENT: Cannot cast '%s' to %s", n, typ)
+			}
+
+			return i, nil
+		}
+	case "double", "real", "float", "decimal":
+		switch n := v.(type) {
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil {
+				return nil, fmt.Errorf("INVALID_CAST_ARGUMENT: Cannot cast '%s' to %s", n, typ)
+			}
+
+			return f, nil
+		}
+	case "boolean":
+		switch n := v.(type) {
+		case bool:
+			return n, nil
+		case int64:
+			return n != 0, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(n))
+			if err != nil {
+				return nil, fmt.Errorf("INVALID_CAST_ARGUMENT: Cannot cast '%s' to %s", n, typ)
+			}
+
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("NOT_SUPPORTED: cast to %s", typ)
+	}
+
+	return nil, typeError("CAST", v)
+}
+
+// format formats v as Athena does in results, with NULL as an empty string.
+func format(v value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.ContainsAny(s, ".NI") {
+			s += ".0"
+		}
+
+		return s
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// valueType returns the SQL type of v.
+func valueType(v value) string {
+	switch v.(type) {
+	case nil:
+		return "unknown"
+	case int64:
+		return "bigint"
+	case float64:
+		return "double"
+	case bool:
+		return "boolean"
+	}
+
+	return "varchar"
+}
+
+// key returns a string identifying values, for grouping and DISTINCT.
+func key(values []value) string {
+	var b strings.Builder
+
+	for _, v := range values {
+		b.WriteString(valueType(v))
+		b.WriteByte(':')
+		b.WriteString(format(v))
+		b.WriteByte(0)
+	}
+
+	return b.String()
+}
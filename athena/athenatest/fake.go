@@ -0,0 +1,640 @@
+// Package athenatest provides a fake Athena for testing code which uses the
+// athena package, or the AWS SDK's athenaiface.AthenaAPI directly.
+//
+// The fake keeps query executions, named queries and workgroups in memory.
+// Results are registered per query pattern with Respond, and executions
+// move through their states on a schedule, either scripted per poll or
+// following a clock:
+//
+//	fake := athenatest.NewFake()
+//	fake.Respond(`FROM events`, athenatest.Response{
+//		Result: athena.Result{
+//			Columns: []athena.Column{{Name: "n", Type: "bigint"}},
+//			Rows:    []athena.Row{{"42"}},
+//		},
+//		States: []string{"QUEUED", "RUNNING"},
+//	})
+//
+//	client := athena.NewClientWithAPI(fake).WithPollInterval(time.Millisecond)
+//	r, err := client.Run(ctx, "db", "SELECT count(*) AS n FROM events", "s3://bucket/")
+//	...
+//	fake.AssertQueried(t, `count\(\*\)`)
+//
+// Queries matching no response can instead be executed for real by a
+// Backend. Engine is one, evaluating a subset of Presto SQL over tables
+// loaded from CSV or JSON fixtures:
+//
+//	engine := athenatest.NewEngine()
+//	if err := engine.LoadFile("db", "events", "testdata/events.csv"); err != nil {
+//		...
+//	}
+//	fake.SetBackend(engine)
+//
+// Alternatively, a Recorder records the calls made to AWS as a golden
+// file, which a Replayer serves back offline.
+//
+// The fake, Server, Recorder and Replayer support the operations the
+// athena package calls: StartQueryExecution, GetQueryExecution,
+// BatchGetQueryExecution, ListQueryExecutions, StopQueryExecution,
+// GetQueryResults, CreateNamedQuery, GetNamedQuery, BatchGetNamedQuery,
+// ListNamedQueries, DeleteNamedQuery, CreateWorkGroup, GetWorkGroup,
+// ListWorkGroups, UpdateWorkGroup and DeleteWorkGroup. Other operations of
+// athenaiface.AthenaAPI, including the WithContext and Request variants of
+// these, panic when called on the fake or a Replayer, fail with an
+// UnknownOperationException from a Server, and are passed through a
+// Recorder unrecorded, so golden files can't be made of code using them.
+//
+// To test retries and the handling of incomplete responses, a Chaos injects
+// faults into the calls made to any of these, or to AWS, which a client
+// may retry with the interceptor Retry returns.
+package athenatest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+)
+
+// PrimaryWorkGroup is the workgroup queries run in unless another is
+// given; the fake starts with it.
+const PrimaryWorkGroup = "primary"
+
+// Page sizes used when requests don't give MaxResults, as Athena does.
+const (
+	defaultResultsPage = 1000
+	defaultListPage    = 50
+)
+
+// Response describes how the fake executes queries matching a pattern.
+type Response struct {
+	// Result is returned by GetQueryResults once the query has succeeded.
+	// Rows exclude the row of column names Athena pads onto results, which
+	// the fake adds.
+	Result athena.Result
+
+	// Reason, if not empty, fails the query with this reason instead.
+	Reason string
+
+	// States are the states reported by successive calls to
+	// GetQueryExecution, e.g. QUEUED then RUNNING, before the query follows
+	// its schedule.
+	States []string
+
+	// QueueTime and RunTime schedule the query by the fake's clock: it is
+	// QUEUED for QueueTime after submission, then RUNNING for RunTime, then
+	// finished. Without them the query finishes once States are reported.
+	QueueTime time.Duration
+	RunTime   time.Duration
+
+	// DataScannedInBytes is reported once the query is running.
+	DataScannedInBytes int64
+
+	// Err, if not nil, is returned by StartQueryExecution instead of
+	// starting the query.
+	Err error
+}
+
+// response is a Response registered for a pattern.
+type response struct {
+	pattern *regexp.Regexp
+	Response
+}
+
+// Clock is a fake clock for scheduling queries, which only moves when
+// advanced. It is safe for concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// execution is a query execution held by the fake.
+type execution struct {
+	id        string
+	in        *aa.StartQueryExecutionInput
+	workgroup string
+	resp      Response
+	submitted time.Time
+
+	// states are the scripted states still to be reported, and state the
+	// last of them reported.
+	states []string
+	state  string
+
+	// final is set once the query has finished, when it stops changing.
+	final     string
+	completed time.Time
+}
+
+// Fake is an in-memory athenaiface.AthenaAPI. It supports starting,
+// stopping and listing query executions and fetching their results, and
+// managing named queries and workgroups. Other operations panic.
+//
+// It records every call, for assertions. It is safe for concurrent use.
+type Fake struct {
+	athenaiface.AthenaAPI
+
+	mu sync.Mutex
+
+	// clock schedules queries; the wall clock is used if nil.
+	clock *Clock
+
+	responses  []response
+	backend    Backend
+	executions map[string]*execution
+	order      []string
+	named      map[string]*aa.NamedQuery
+	namedOrder []string
+	workgroups map[string]*aa.WorkGroup
+	wgOrder    []string
+	calls      []Call
+	ids        int
+}
+
+// NewFake returns a fake with only the primary workgroup, scheduling
+// queries by the wall clock.
+func NewFake() *Fake {
+	f := &Fake{
+		executions: map[string]*execution{},
+		named:      map[string]*aa.NamedQuery{},
+		workgroups: map[string]*aa.WorkGroup{},
+	}
+
+	f.addWorkGroup(&aa.WorkGroup{
+		Name:          aws.String(PrimaryWorkGroup),
+		State:         aws.String(aa.WorkGroupStateEnabled),
+		Configuration: &aa.WorkGroupConfiguration{},
+		CreationTime:  aws.Time(time.Now()),
+	})
+
+	return f
+}
+
+// NewFakeWithClock returns a fake scheduling queries by clock.
+func NewFakeWithClock(clock *Clock) *Fake {
+	f := NewFake()
+	f.clock = clock
+
+	return f
+}
+
+// Respond registers how queries matching pattern, a regular expression,
+// are executed. Responses are tried in the order registered; queries
+// matching none are executed by the backend, if set, or fail. Respond
+// panics if pattern is invalid.
+func (f *Fake) Respond(pattern string, r Response) {
+	re := regexp.MustCompile(pattern)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, response{re, r})
+}
+
+func (f *Fake) now() time.Time {
+	if f.clock != nil {
+		return f.clock.Now()
+	}
+
+	return time.Now()
+}
+
+// newID returns a new ID in the form of Athena's.
+func (f *Fake) newID() string {
+	f.ids++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", f.ids)
+}
+
+// invalidRequest returns an error as Athena does for invalid requests.
+func invalidRequest(format string, args ...interface{}) error {
+	return awserr.New(aa.ErrCodeInvalidRequestException, fmt.Sprintf(format, args...), nil)
+}
+
+// StartQueryExecution starts a query, executed according to the first
+// response matching it.
+func (f *Fake) StartQueryExecution(in *aa.StartQueryExecutionInput) (*aa.StartQueryExecutionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("StartQueryExecution", in)
+
+	query := aws.StringValue(in.QueryString)
+	if query == "" {
+		return nil, invalidRequest("QueryString must not be empty")
+	}
+
+	workgroup := aws.StringValue(in.WorkGroup)
+	if workgroup == "" {
+		workgroup = PrimaryWorkGroup
+	}
+
+	wg, ok := f.workgroups[workgroup]
+	if !ok {
+		return nil, invalidRequest("WorkGroup %s is not found.", workgroup)
+	}
+
+	if aws.StringValue(wg.State) == aa.WorkGroupStateDisabled {
+		return nil, invalidRequest("WorkGroup %s is disabled.", workgroup)
+	}
+
+	resp, ok := f.respond(in)
+	if !ok {
+		resp = Response{Reason: "athenatest: no response registered for query"}
+	}
+
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	e := &execution{
+		id:        f.newID(),
+		in:        in,
+		workgroup: workgroup,
+		resp:      resp,
+		submitted: f.now(),
+		states:    append([]string(nil), resp.States...),
+	}
+
+	f.executions[e.id] = e
+	f.order = append(f.order, e.id)
+
+	return &aa.StartQueryExecutionOutput{QueryExecutionId: aws.String(e.id)}, nil
+}
+
+// respond returns the first response matching a query, or the result of
+// executing it with the backend if there is one.
+func (f *Fake) respond(in *aa.StartQueryExecutionInput) (Response, bool) {
+	query := aws.StringValue(in.QueryString)
+
+	for _, r := range f.responses {
+		if r.pattern.MatchString(query) {
+			return r.Response, true
+		}
+	}
+
+	if f.backend == nil {
+		return Response{}, false
+	}
+
+	var database string
+	if in.QueryExecutionContext != nil {
+		database = aws.StringValue(in.QueryExecutionContext.Database)
+	}
+
+	r, err := f.backend.Execute(database, query)
+	if err != nil {
+		return Response{Reason: err.Error()}, true
+	}
+
+	return Response{Result: r}, true
+}
+
+// lookup returns the execution with id.
+func (f *Fake) lookup(id *string) (*execution, error) {
+	e, ok := f.executions[aws.StringValue(id)]
+	if !ok {
+		return nil, invalidRequest("QUERY_NOT_FOUND: Query %s not found", aws.StringValue(id))
+	}
+
+	return e, nil
+}
+
+// current returns the state of e now, advancing its scripted states if
+// poll is true.
+func (f *Fake) current(e *execution, poll bool) string {
+	if e.final != "" {
+		return e.final
+	}
+
+	if len(e.states) > 0 {
+		if !poll {
+			if e.state == "" {
+				return aa.QueryExecutionStateQueued
+			}
+
+			return e.state
+		}
+
+		e.state, e.states = e.states[0], e.states[1:]
+		if terminal(e.state) {
+			e.final, e.completed = e.state, f.now()
+		}
+
+		return e.state
+	}
+
+	elapsed := f.now().Sub(e.submitted)
+
+	switch {
+	case elapsed < e.resp.QueueTime:
+		return aa.QueryExecutionStateQueued
+	case elapsed < e.resp.QueueTime+e.resp.RunTime:
+		return aa.QueryExecutionStateRunning
+	}
+
+	e.final = aa.QueryExecutionStateSucceeded
+	if e.resp.Reason != "" {
+		e.final = aa.QueryExecutionStateFailed
+	}
+
+	e.completed = e.submitted.Add(e.resp.QueueTime + e.resp.RunTime)
+	if len(e.resp.States) > 0 {
+		// a scripted query finishes when it is seen to
+		e.completed = f.now()
+	}
+
+	return e.final
+}
+
+// terminal returns true if state is one in which a query has finished.
+func terminal(state string) bool {
+	switch state {
+	case aa.QueryExecutionStateSucceeded, aa.QueryExecutionStateFailed, aa.QueryExecutionStateCancelled:
+		return true
+	}
+
+	return false
+}
+
+// queryExecution describes e in state.
+func (f *Fake) queryExecution(e *execution, state string) *aa.QueryExecution {
+	output := ""
+	if rc := e.in.ResultConfiguration; rc != nil && rc.OutputLocation != nil {
+		output = strings.TrimSuffix(*rc.OutputLocation, "/") + "/" + e.id + ".csv"
+	}
+
+	status := &aa.QueryExecutionStatus{
+		State:              aws.String(state),
+		SubmissionDateTime: aws.Time(e.submitted),
+	}
+
+	if terminal(state) {
+		status.CompletionDateTime = aws.Time(e.completed)
+	}
+
+	if state == aa.QueryExecutionStateFailed && e.resp.Reason != "" {
+		status.StateChangeReason = aws.String(e.resp.Reason)
+	}
+
+	stats := &aa.QueryExecutionStatistics{}
+	if state != aa.QueryExecutionStateQueued {
+		stats.DataScannedInBytes = aws.Int64(e.resp.DataScannedInBytes)
+	}
+
+	if terminal(state) {
+		stats.EngineExecutionTimeInMillis = aws.Int64(int64(e.resp.RunTime / time.Millisecond))
+	}
+
+	return &aa.QueryExecution{
+		QueryExecutionId:      aws.String(e.id),
+		Query:                 e.in.QueryString,
+		QueryExecutionContext: e.in.QueryExecutionContext,
+		ResultConfiguration:   &aa.ResultConfiguration{OutputLocation: aws.String(output)},
+		StatementType:         aws.String(aa.StatementTypeDml),
+		Status:                status,
+		Statistics:            stats,
+		WorkGroup:             aws.String(e.workgroup),
+	}
+}
+
+// GetQueryExecution describes a query, advancing its scripted states.
+func (f *Fake) GetQueryExecution(in *aa.GetQueryExecutionInput) (*aa.GetQueryExecutionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("GetQueryExecution", in)
+
+	e, err := f.lookup(in.QueryExecutionId)
+	if err != nil {
+		return nil, err
+	}
+
+	state := f.current(e, true)
+
+	return &aa.GetQueryExecutionOutput{QueryExecution: f.queryExecution(e, state)}, nil
+}
+
+// BatchGetQueryExecution describes queries, without advancing their
+// scripted states.
+func (f *Fake) BatchGetQueryExecution(in *aa.BatchGetQueryExecutionInput) (*aa.BatchGetQueryExecutionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("BatchGetQueryExecution", in)
+
+	out := &aa.BatchGetQueryExecutionOutput{}
+
+	for _, id := range in.QueryExecutionIds {
+		e, err := f.lookup(id)
+		if err != nil {
+			out.UnprocessedQueryExecutionIds = append(out.UnprocessedQueryExecutionIds, &aa.UnprocessedQueryExecutionId{
+				QueryExecutionId: id,
+				ErrorCode:        aws.String(aa.ErrCodeInvalidRequestException),
+				ErrorMessage:     aws.String(err.Error()),
+			})
+
+			continue
+		}
+
+		out.QueryExecutions = append(out.QueryExecutions, f.queryExecution(e, f.current(e, false)))
+	}
+
+	return out, nil
+}
+
+// ListQueryExecutions lists the queries in a workgroup, most recent first.
+func (f *Fake) ListQueryExecutions(in *aa.ListQueryExecutionsInput) (*aa.ListQueryExecutionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("ListQueryExecutions", in)
+
+	workgroup := aws.StringValue(in.WorkGroup)
+	if workgroup == "" {
+		workgroup = PrimaryWorkGroup
+	}
+
+	var ids []*string
+	for i := len(f.order) - 1; i >= 0; i-- {
+		if e := f.executions[f.order[i]]; e.workgroup == workgroup {
+			ids = append(ids, aws.String(e.id))
+		}
+	}
+
+	start, end, next, err := page(in.NextToken, in.MaxResults, defaultListPage, len(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return &aa.ListQueryExecutionsOutput{QueryExecutionIds: ids[start:end], NextToken: next}, nil
+}
+
+// StopQueryExecution cancels a query which has not finished.
+func (f *Fake) StopQueryExecution(in *aa.StopQueryExecutionInput) (*aa.StopQueryExecutionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("StopQueryExecution", in)
+
+	e, err := f.lookup(in.QueryExecutionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !terminal(f.current(e, false)) {
+		e.final, e.completed = aa.QueryExecutionStateCancelled, f.now()
+	}
+
+	return &aa.StopQueryExecutionOutput{}, nil
+}
+
+// GetQueryResults returns a page of the results of a query which has
+// succeeded, starting with a row of column names as Athena does.
+func (f *Fake) GetQueryResults(in *aa.GetQueryResultsInput) (*aa.GetQueryResultsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("GetQueryResults", in)
+
+	e, err := f.lookup(in.QueryExecutionId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch state := f.current(e, false); state {
+	case aa.QueryExecutionStateSucceeded:
+	case aa.QueryExecutionStateFailed, aa.QueryExecutionStateCancelled:
+		return nil, invalidRequest("Query has not succeeded. Current state: %s", state)
+	default:
+		return nil, invalidRequest("Query has not yet finished. Current state: %s", state)
+	}
+
+	r := e.resp.Result
+
+	header := &aa.Row{}
+	for _, c := range r.Columns {
+		header.Data = append(header.Data, &aa.Datum{VarCharValue: aws.String(c.Name)})
+	}
+
+	rows := []*aa.Row{header}
+	for _, row := range r.Rows {
+		ar := &aa.Row{}
+		for _, v := range row {
+			ar.Data = append(ar.Data, &aa.Datum{VarCharValue: aws.String(v)})
+		}
+
+		rows = append(rows, ar)
+	}
+
+	start, end, next, err := page(in.NextToken, in.MaxResults, defaultResultsPage, len(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &aa.ResultSet{
+		ResultSetMetadata: &aa.ResultSetMetadata{ColumnInfo: columnInfo(r.Columns)},
+		Rows:              rows[start:end],
+	}
+
+	return &aa.GetQueryResultsOutput{ResultSet: rs, NextToken: next}, nil
+}
+
+// columnInfo converts columns, including the details they have.
+func columnInfo(columns []athena.Column) []*aa.ColumnInfo {
+	info := make([]*aa.ColumnInfo, len(columns))
+
+	for i, c := range columns {
+		ci := &aa.ColumnInfo{Name: aws.String(c.Name), Type: aws.String(c.Type)}
+		if c.Type == "" {
+			ci.Type = aws.String("varchar")
+		}
+
+		if c.CaseSensitiveExists {
+			ci.CaseSensitive = aws.Bool(c.CaseSensitive)
+		}
+
+		if c.CatalogNameExists {
+			ci.CatalogName = aws.String(c.CatalogName)
+		}
+
+		if c.LabelExists {
+			ci.Label = aws.String(c.Label)
+		}
+
+		if c.NullableExists {
+			ci.Nullable = aws.String(c.Nullable)
+		}
+
+		if c.PrecisionExists {
+			ci.Precision = aws.Int64(int64(c.Precision))
+		}
+
+		if c.ScaleExists {
+			ci.Scale = aws.Int64(int64(c.Scale))
+		}
+
+		if c.SchemaNameExists {
+			ci.SchemaName = aws.String(c.SchemaName)
+		}
+
+		if c.TableNameExists {
+			ci.TableName = aws.String(c.TableName)
+		}
+
+		info[i] = ci
+	}
+
+	return info
+}
+
+// page returns the bounds of the page of n items starting at token, of
+// max items or def if max is not given, and the token of the next page.
+func page(token *string, max *int64, def, n int) (int, int, *string, error) {
+	start := 0
+	if token != nil {
+		var err error
+		if start, err = strconv.Atoi(*token); err != nil || start < 0 || start > n {
+			return 0, 0, nil, invalidRequest("invalid NextToken %q", *token)
+		}
+	}
+
+	size := def
+	if max != nil && *max > 0 {
+		size = int(*max)
+	}
+
+	if start+size >= n {
+		return start, n, nil, nil
+	}
+
+	return start, start + size, aws.String(strconv.Itoa(start + size)), nil
+}
@@ -0,0 +1,239 @@
+package athenatest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+var events = athena.Result{
+	Columns: []athena.Column{{Name: "id", Type: "varchar"}, {Name: "n", Type: "bigint"}},
+	Rows:    []athena.Row{{"a", "1"}, {"b", "2"}, {"c", "3"}},
+}
+
+func client(f *athenatest.Fake) athena.Client {
+	return athena.NewClientWithAPI(f).WithPollInterval(time.Millisecond)
+}
+
+func TestFakeRun(t *testing.T) {
+	f := athenatest.NewFake()
+	f.Respond(`FROM events`, athenatest.Response{
+		Result: events,
+		States: []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning},
+	})
+	f.Respond(`FROM missing`, athenatest.Response{Reason: "Table not found"})
+
+	r, err := client(f).Run(context.Background(), "db", "SELECT * FROM events", "s3://bucket/")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	r = r.WithoutHeader()
+	for i := range r.Columns {
+		r.Columns[i] = athena.Column{Name: r.Columns[i].Name, Type: r.Columns[i].Type}
+	}
+
+	if !reflect.DeepEqual(r, events) {
+		t.Errorf("Result == %v (want %v)", r, events)
+	}
+
+	// QUEUED, RUNNING then SUCCEEDED, and the statement type of the results
+	f.AssertCalled(t, "GetQueryExecution", 4)
+	f.AssertQueried(t, `^SELECT \* FROM events$`)
+	f.AssertNotQueried(t, `missing`)
+
+	for _, query := range []string{"SELECT * FROM missing", "SELECT * FROM unknown"} {
+		_, err = client(f).Run(context.Background(), "db", query, "s3://bucket/")
+
+		var qerr *athena.QueryError
+		if !errors.As(err, &qerr) || qerr.State != aa.QueryExecutionStateFailed {
+			t.Errorf("%s: err == %v (want a failed QueryError)", query, err)
+		}
+	}
+
+	expected := []string{"SELECT * FROM events", "SELECT * FROM missing", "SELECT * FROM unknown"}
+	if queries := f.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Queries == %q (want %q)", queries, expected)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := athenatest.NewClock(time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC))
+	f := athenatest.NewFakeWithClock(clock)
+	f.Respond(``, athenatest.Response{
+		Result:             events,
+		QueueTime:          time.Second,
+		RunTime:            2 * time.Second,
+		DataScannedInBytes: 1024,
+	})
+
+	q, err := client(f).DoQuery("db", "SELECT 1", "s3://bucket/")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	for i, test := range []struct {
+		advance time.Duration
+		state   string
+		scanned int64
+	}{
+		{0, aa.QueryExecutionStateQueued, 0},
+		{time.Second, aa.QueryExecutionStateRunning, 1024},
+		{time.Second, aa.QueryExecutionStateRunning, 1024},
+		{time.Second, aa.QueryExecutionStateSucceeded, 1024},
+		{time.Hour, aa.QueryExecutionStateSucceeded, 1024},
+	} {
+		clock.Advance(test.advance)
+
+		e, err := q.Execution()
+		if err != nil {
+			t.Fatalf("%d: err == %v (want nil)", i, err)
+		}
+
+		if e.State != test.state {
+			t.Errorf("%d: State == %v (want %v)", i, e.State, test.state)
+		}
+
+		if e.DataScannedInBytes != test.scanned {
+			t.Errorf("%d: DataScannedInBytes == %v (want %v)", i, e.DataScannedInBytes, test.scanned)
+		}
+	}
+
+	e, _ := q.Execution()
+	if expected := clock.Now().Add(-time.Hour); !e.CompletionTime.Equal(expected) {
+		t.Errorf("CompletionTime == %v (want %v)", e.CompletionTime, expected)
+	}
+
+	if e.OutputLocation != "s3://bucket/"+q.ID()+".csv" {
+		t.Errorf("OutputLocation == %v", e.OutputLocation)
+	}
+}
+
+func TestFakeStop(t *testing.T) {
+	clock := athenatest.NewClock(time.Now())
+	f := athenatest.NewFakeWithClock(clock)
+	f.Respond(``, athenatest.Response{RunTime: time.Minute})
+
+	q, _ := client(f).DoQuery("db", "SELECT 1", "s3://bucket/")
+	if err := q.Stop(); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	clock.Advance(time.Hour)
+
+	if status, _ := q.Status(); status.State != aa.QueryExecutionStateCancelled {
+		t.Errorf("State == %v (want %v)", status.State, aa.QueryExecutionStateCancelled)
+	}
+
+	f.AssertStopped(t, q.ID())
+
+	if _, err := q.Result(); err == nil {
+		t.Errorf("err == nil (want an error fetching results of a cancelled query)")
+	}
+}
+
+func TestFakeResultPages(t *testing.T) {
+	f := athenatest.NewFake()
+	f.Respond(``, athenatest.Response{Result: events})
+
+	out, _ := f.StartQueryExecution(&aa.StartQueryExecutionInput{QueryString: aws.String("SELECT 1")})
+	f.GetQueryExecution(&aa.GetQueryExecutionInput{QueryExecutionId: out.QueryExecutionId})
+
+	in := &aa.GetQueryResultsInput{QueryExecutionId: out.QueryExecutionId, MaxResults: aws.Int64(3)}
+
+	var pages []int
+	for {
+		r, err := f.GetQueryResults(in)
+		if err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		pages = append(pages, len(r.ResultSet.Rows))
+
+		if r.NextToken == nil {
+			break
+		}
+
+		in.NextToken = r.NextToken
+	}
+
+	// the header row, then three rows
+	if expected := []int{3, 1}; !reflect.DeepEqual(pages, expected) {
+		t.Errorf("pages == %v (want %v)", pages, expected)
+	}
+}
+
+func TestFakeCatalog(t *testing.T) {
+	f := athenatest.NewFake()
+	c := client(f)
+
+	f.CreateWorkGroup(&aa.CreateWorkGroupInput{Name: aws.String("analytics")})
+
+	for _, name := range []string{"daily", "weekly"} {
+		f.CreateNamedQuery(&aa.CreateNamedQueryInput{
+			Name:        aws.String(name),
+			Database:    aws.String("db"),
+			QueryString: aws.String("SELECT 1"),
+			WorkGroup:   aws.String("analytics"),
+		})
+	}
+
+	queries, err := c.NamedQueries("analytics")
+	if err != nil || len(queries) != 2 || queries[0].Name != "daily" || queries[1].Name != "weekly" {
+		t.Errorf("NamedQueries == %v, %v (want daily and weekly)", queries, err)
+	}
+
+	if queries, _ := c.NamedQueries(""); len(queries) != 0 {
+		t.Errorf("NamedQueries == %v (want none in primary)", queries)
+	}
+
+	workgroups, err := c.WorkGroups()
+	if err != nil || len(workgroups) != 2 || workgroups[0].Name != "primary" || workgroups[1].Name != "analytics" {
+		t.Errorf("WorkGroups == %v, %v (want primary and analytics)", workgroups, err)
+	}
+
+	if err := c.SetWorkGroupScanLimit("analytics", 1<<30); err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	wg, _ := f.GetWorkGroup(&aa.GetWorkGroupInput{WorkGroup: aws.String("analytics")})
+	if cutoff := aws.Int64Value(wg.WorkGroup.Configuration.BytesScannedCutoffPerQuery); cutoff != 1<<30 {
+		t.Errorf("BytesScannedCutoffPerQuery == %v (want %v)", cutoff, 1<<30)
+	}
+
+	if _, err := c.WithWorkGroup("unknown").DoQuery("db", "SELECT 1", "s3://bucket/"); err == nil {
+		t.Errorf("err == nil (want an error for an unknown workgroup)")
+	}
+}
+
+func TestFakeHistory(t *testing.T) {
+	f := athenatest.NewFake()
+	f.Respond(``, athenatest.Response{})
+	c := client(f)
+
+	var ids []string
+	for _, query := range []string{"SELECT 1", "SELECT 2", "SELECT 3"} {
+		q, _ := c.DoQuery("db", query, "s3://bucket/")
+		ids = append(ids, q.ID())
+	}
+
+	history, err := c.History("", 2)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if len(history) != 2 || history[0].ID != ids[2] || history[1].ID != ids[1] {
+		t.Errorf("History == %v (want the last two queries, most recent first)", history)
+	}
+
+	if history[0].Query != "SELECT 3" || history[0].Database != "db" || history[0].WorkGroup != "primary" {
+		t.Errorf("History[0] == %v", history[0])
+	}
+}
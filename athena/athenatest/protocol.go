@@ -0,0 +1,261 @@
+package athenatest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// timeType is the type of timestamps in SDK shapes.
+var timeType = reflect.TypeOf(time.Time{})
+
+// marshalJSON returns the JSON of v, an SDK shape, as Athena's JSON 1.1
+// protocol sends it: members are named by their locationName tags, nil
+// members are left out, timestamps are seconds since the epoch and blobs
+// are base64 encoded.
+func marshalJSON(v interface{}) ([]byte, error) {
+	return json.Marshal(toJSON(reflect.ValueOf(v)))
+}
+
+// toJSON converts v to the value marshalJSON encodes with encoding/json.
+func toJSON(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return toJSON(v.Elem())
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t := v.Interface().(time.Time)
+			return json.Number(strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64))
+		}
+
+		m := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := member(v.Type().Field(i))
+			if !ok || nilValue(v.Field(i)) {
+				continue
+			}
+
+			m[name] = toJSON(v.Field(i))
+		}
+
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes()
+		}
+
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i] = toJSON(v.Index(i))
+		}
+
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			m[k.String()] = toJSON(v.MapIndex(k))
+		}
+
+		return m
+	}
+
+	return v.Interface()
+}
+
+// unmarshalJSON decodes b, as Athena's JSON 1.1 protocol sends it, into
+// v, a pointer to an SDK shape. Unknown members are ignored.
+func unmarshalJSON(v interface{}, b []byte) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return err
+	}
+
+	return fromJSON(reflect.ValueOf(v), doc)
+}
+
+// fromJSON sets v from doc, as decoded by encoding/json using numbers.
+func fromJSON(v reflect.Value, doc interface{}) error {
+	if doc == nil {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return fromJSON(v.Elem(), doc)
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot unmarshal %T into %s", doc, v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			n, ok := doc.(json.Number)
+			if !ok {
+				return mismatch()
+			}
+
+			f, err := n.Float64()
+			if err != nil {
+				return err
+			}
+
+			sec, frac := math.Modf(f)
+			v.Set(reflect.ValueOf(time.Unix(int64(sec), int64(frac*float64(time.Second))).UTC()))
+
+			return nil
+		}
+
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := member(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+
+			if err := fromJSON(v.Field(i), m[name]); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			s, ok := doc.(string)
+			if !ok {
+				return mismatch()
+			}
+
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err
+			}
+
+			v.SetBytes(b)
+
+			return nil
+		}
+
+		s, ok := doc.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+
+		v.Set(reflect.MakeSlice(v.Type(), len(s), len(s)))
+		for i, e := range s {
+			if err := fromJSON(v.Index(i), e); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(m)))
+		for k, e := range m {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := fromJSON(ev, e); err != nil {
+				return fmt.Errorf("%s: %v", k, err)
+			}
+
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), ev)
+		}
+	case reflect.String:
+		s, ok := doc.(string)
+		if !ok {
+			return mismatch()
+		}
+
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := doc.(bool)
+		if !ok {
+			return mismatch()
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := doc.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+
+		i, err := n.Int64()
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		n, ok := doc.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+
+		f, err := n.Float64()
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+// member returns the name of the JSON member for the field of an SDK
+// shape, and whether it has one: unexported fields, and those sent
+// elsewhere than the body, don't.
+func member(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" || f.Tag.Get("location") != "" {
+		return "", false
+	}
+
+	if name := f.Tag.Get("locationName"); name != "" {
+		return name, true
+	}
+
+	return f.Name, true
+}
+
+// nilValue returns whether v is a nil pointer, slice, map or interface.
+func nilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
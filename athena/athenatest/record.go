@@ -0,0 +1,641 @@
+package athenatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+)
+
+// redacted replaces redacted values in golden files.
+const redacted = "REDACTED"
+
+// Redaction configures which values a Recorder leaves out of golden files.
+// A Replayer given the same redaction matches calls on their redacted
+// inputs.
+//
+// Calls are recorded above the SDK, so credentials and request signatures
+// are never seen; redaction covers what's in the requests and responses
+// themselves.
+type Redaction struct {
+	// Fields are the names of fields, such as "KmsKey", whose values are
+	// redacted wherever they appear.
+	Fields []string
+
+	// Strings, such as account IDs, are redacted wherever they appear in
+	// values, including error messages.
+	Strings []string
+
+	// Query, if not nil, is applied to the SQL text of queries; see
+	// athena.RedactLiterals.
+	Query func(query string) string
+}
+
+// normalize returns the canonical JSON of an SDK input or output with the
+// redaction applied.
+func (r Redaction) normalize(v interface{}) (json.RawMessage, error) {
+	b, err := marshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(r.redact("", doc))
+}
+
+// redact applies the redaction to a decoded JSON value, found at key.
+func (r Redaction) redact(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, x := range v {
+			switch {
+			case k == "ClientRequestToken":
+				// generated afresh for every request
+				delete(v, k)
+			case r.field(k):
+				if _, ok := x.(string); ok {
+					v[k] = redacted
+				} else {
+					delete(v, k)
+				}
+			default:
+				v[k] = r.redact(k, x)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redact(key, v[i])
+		}
+	case string:
+		if r.Query != nil && (key == "QueryString" || key == "Query") {
+			v = r.Query(v)
+		}
+
+		return r.replace(v)
+	}
+
+	return v
+}
+
+// field returns whether the field name is redacted.
+func (r Redaction) field(name string) bool {
+	for _, f := range r.Fields {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replace redacts the redacted strings within s.
+func (r Redaction) replace(s string) string {
+	for _, x := range r.Strings {
+		if x != "" {
+			s = strings.Replace(s, x, redacted, -1)
+		}
+	}
+
+	return s
+}
+
+// decode decodes JSON, keeping numbers as they are written.
+func decode(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// golden is the content of a golden file.
+type golden struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// interaction is a recorded call.
+type interaction struct {
+	Operation string          `json:"operation"`
+	Input     json.RawMessage `json:"input"`
+	Output    json.RawMessage `json:"output,omitempty"`
+	Error     *recordedError  `json:"error,omitempty"`
+}
+
+// recordedError is the error of a recorded call; Code is empty if the
+// error was not from AWS.
+type recordedError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// replay unmarshals the recorded output into out, or returns the recorded
+// error.
+func (i interaction) replay(out interface{}) error {
+	if i.Error != nil {
+		if i.Error.Code == "" {
+			return fmt.Errorf("%s", i.Error.Message)
+		}
+
+		return awserr.New(i.Error.Code, i.Error.Message, nil)
+	}
+
+	if len(i.Output) == 0 {
+		return nil
+	}
+
+	return unmarshalJSON(out, i.Output)
+}
+
+// Recorder is an athenaiface.AthenaAPI recording the calls made through
+// it to another, for saving as a golden file to be served by a Replayer.
+// The operations served by Server are recorded, which include all those
+// the athena package calls; other operations are passed through unrecorded.
+//
+// Record a test's calls once against AWS, then replay them offline:
+//
+//	var record = flag.Bool("record", false, "record golden files against AWS")
+//
+//	redaction := athenatest.Redaction{Strings: []string{account}}
+//
+//	var api athenaiface.AthenaAPI
+//	if *record {
+//		rec := athenatest.NewRecorder(aa.New(sess), redaction)
+//		defer rec.Save("testdata/report.json")
+//		api = rec
+//	} else {
+//		api, err = athenatest.NewReplayer("testdata/report.json", redaction)
+//		...
+//	}
+//
+// It is safe for concurrent use.
+type Recorder struct {
+	athenaiface.AthenaAPI
+
+	redaction Redaction
+
+	mu           sync.Mutex
+	interactions []interaction
+	err          error
+}
+
+// NewRecorder returns a recorder of the calls made to api.
+func NewRecorder(api athenaiface.AthenaAPI, redaction Redaction) *Recorder {
+	return &Recorder{AthenaAPI: api, redaction: redaction}
+}
+
+// record records a call.
+func (r *Recorder) record(operation string, in, out interface{}, err error) {
+	i := interaction{Operation: operation}
+
+	var nerr error
+	i.Input, nerr = r.redaction.normalize(in)
+
+	if err != nil {
+		i.Error = &recordedError{Message: r.redaction.replace(err.Error())}
+		if aerr, ok := err.(awserr.Error); ok {
+			i.Error.Code = aerr.Code()
+			i.Error.Message = r.redaction.replace(aerr.Message())
+		}
+	} else if nerr == nil {
+		i.Output, nerr = r.redaction.normalize(out)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if nerr != nil {
+		if r.err == nil {
+			r.err = fmt.Errorf("athenatest: recording %s: %v", operation, nerr)
+		}
+
+		return
+	}
+
+	r.interactions = append(r.interactions, i)
+}
+
+// Write writes the calls recorded as a golden file, or the first error
+// recording them.
+func (r *Recorder) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	b, err := json.MarshalIndent(golden{r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Save writes the calls recorded to the golden file at path, creating its
+// directory if needed.
+func (r *Recorder) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// StartQueryExecution records a call to StartQueryExecution.
+func (r *Recorder) StartQueryExecution(in *aa.StartQueryExecutionInput) (*aa.StartQueryExecutionOutput, error) {
+	out, err := r.AthenaAPI.StartQueryExecution(in)
+	r.record("StartQueryExecution", in, out, err)
+	return out, err
+}
+
+// GetQueryExecution records a call to GetQueryExecution.
+func (r *Recorder) GetQueryExecution(in *aa.GetQueryExecutionInput) (*aa.GetQueryExecutionOutput, error) {
+	out, err := r.AthenaAPI.GetQueryExecution(in)
+	r.record("GetQueryExecution", in, out, err)
+	return out, err
+}
+
+// BatchGetQueryExecution records a call to BatchGetQueryExecution.
+func (r *Recorder) BatchGetQueryExecution(in *aa.BatchGetQueryExecutionInput) (*aa.BatchGetQueryExecutionOutput, error) {
+	out, err := r.AthenaAPI.BatchGetQueryExecution(in)
+	r.record("BatchGetQueryExecution", in, out, err)
+	return out, err
+}
+
+// ListQueryExecutions records a call to ListQueryExecutions.
+func (r *Recorder) ListQueryExecutions(in *aa.ListQueryExecutionsInput) (*aa.ListQueryExecutionsOutput, error) {
+	out, err := r.AthenaAPI.ListQueryExecutions(in)
+	r.record("ListQueryExecutions", in, out, err)
+	return out, err
+}
+
+// StopQueryExecution records a call to StopQueryExecution.
+func (r *Recorder) StopQueryExecution(in *aa.StopQueryExecutionInput) (*aa.StopQueryExecutionOutput, error) {
+	out, err := r.AthenaAPI.StopQueryExecution(in)
+	r.record("StopQueryExecution", in, out, err)
+	return out, err
+}
+
+// GetQueryResults records a call to GetQueryResults.
+func (r *Recorder) GetQueryResults(in *aa.GetQueryResultsInput) (*aa.GetQueryResultsOutput, error) {
+	out, err := r.AthenaAPI.GetQueryResults(in)
+	r.record("GetQueryResults", in, out, err)
+	return out, err
+}
+
+// CreateNamedQuery records a call to CreateNamedQuery.
+func (r *Recorder) CreateNamedQuery(in *aa.CreateNamedQueryInput) (*aa.CreateNamedQueryOutput, error) {
+	out, err := r.AthenaAPI.CreateNamedQuery(in)
+	r.record("CreateNamedQuery", in, out, err)
+	return out, err
+}
+
+// GetNamedQuery records a call to GetNamedQuery.
+func (r *Recorder) GetNamedQuery(in *aa.GetNamedQueryInput) (*aa.GetNamedQueryOutput, error) {
+	out, err := r.AthenaAPI.GetNamedQuery(in)
+	r.record("GetNamedQuery", in, out, err)
+	return out, err
+}
+
+// BatchGetNamedQuery records a call to BatchGetNamedQuery.
+func (r *Recorder) BatchGetNamedQuery(in *aa.BatchGetNamedQueryInput) (*aa.BatchGetNamedQueryOutput, error) {
+	out, err := r.AthenaAPI.BatchGetNamedQuery(in)
+	r.record("BatchGetNamedQuery", in, out, err)
+	return out, err
+}
+
+// ListNamedQueries records a call to ListNamedQueries.
+func (r *Recorder) ListNamedQueries(in *aa.ListNamedQueriesInput) (*aa.ListNamedQueriesOutput, error) {
+	out, err := r.AthenaAPI.ListNamedQueries(in)
+	r.record("ListNamedQueries", in, out, err)
+	return out, err
+}
+
+// DeleteNamedQuery records a call to DeleteNamedQuery.
+func (r *Recorder) DeleteNamedQuery(in *aa.DeleteNamedQueryInput) (*aa.DeleteNamedQueryOutput, error) {
+	out, err := r.AthenaAPI.DeleteNamedQuery(in)
+	r.record("DeleteNamedQuery", in, out, err)
+	return out, err
+}
+
+// CreateWorkGroup records a call to CreateWorkGroup.
+func (r *Recorder) CreateWorkGroup(in *aa.CreateWorkGroupInput) (*aa.CreateWorkGroupOutput, error) {
+	out, err := r.AthenaAPI.CreateWorkGroup(in)
+	r.record("CreateWorkGroup", in, out, err)
+	return out, err
+}
+
+// GetWorkGroup records a call to GetWorkGroup.
+func (r *Recorder) GetWorkGroup(in *aa.GetWorkGroupInput) (*aa.GetWorkGroupOutput, error) {
+	out, err := r.AthenaAPI.GetWorkGroup(in)
+	r.record("GetWorkGroup", in, out, err)
+	return out, err
+}
+
+// ListWorkGroups records a call to ListWorkGroups.
+func (r *Recorder) ListWorkGroups(in *aa.ListWorkGroupsInput) (*aa.ListWorkGroupsOutput, error) {
+	out, err := r.AthenaAPI.ListWorkGroups(in)
+	r.record("ListWorkGroups", in, out, err)
+	return out, err
+}
+
+// UpdateWorkGroup records a call to UpdateWorkGroup.
+func (r *Recorder) UpdateWorkGroup(in *aa.UpdateWorkGroupInput) (*aa.UpdateWorkGroupOutput, error) {
+	out, err := r.AthenaAPI.UpdateWorkGroup(in)
+	r.record("UpdateWorkGroup", in, out, err)
+	return out, err
+}
+
+// DeleteWorkGroup records a call to DeleteWorkGroup.
+func (r *Recorder) DeleteWorkGroup(in *aa.DeleteWorkGroupInput) (*aa.DeleteWorkGroupOutput, error) {
+	out, err := r.AthenaAPI.DeleteWorkGroup(in)
+	r.record("DeleteWorkGroup", in, out, err)
+	return out, err
+}
+
+// Replayer is an athenaiface.AthenaAPI serving the calls recorded in a
+// golden file by a Recorder. A call is answered by the first recorded call
+// not yet replayed of the same operation and input, after redaction, so
+// the polls of a query see its states in the order recorded; once those
+// are used up, the last of them is repeated. Calls matching none fail, and
+// operations a Recorder doesn't record panic.
+//
+// It is safe for concurrent use.
+type Replayer struct {
+	athenaiface.AthenaAPI
+
+	redaction Redaction
+
+	mu           sync.Mutex
+	interactions []interaction
+	replayed     []bool
+}
+
+// NewReplayer returns a replayer of the golden file at path, recorded
+// with redaction.
+func NewReplayer(path string, redaction Redaction) (*Replayer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var g golden
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, fmt.Errorf("athenatest: reading %s: %v", path, err)
+	}
+
+	// inputs are compared in their canonical form
+	for i, x := range g.Interactions {
+		doc, err := decode(x.Input)
+		if err != nil {
+			return nil, fmt.Errorf("athenatest: reading %s: %v", path, err)
+		}
+
+		if g.Interactions[i].Input, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Replayer{
+		redaction:    redaction,
+		interactions: g.Interactions,
+		replayed:     make([]bool, len(g.Interactions)),
+	}, nil
+}
+
+// replay unmarshals the output of the call recorded for an operation and
+// its input into out, or returns its error.
+func (p *Replayer) replay(operation string, in, out interface{}) error {
+	input, err := p.redaction.normalize(in)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last := -1
+
+	for i, x := range p.interactions {
+		if x.Operation != operation || !bytes.Equal(x.Input, input) {
+			continue
+		}
+
+		if !p.replayed[i] {
+			p.replayed[i] = true
+			return x.replay(out)
+		}
+
+		last = i
+	}
+
+	if last < 0 {
+		return fmt.Errorf("athenatest: no %s recorded with input %s", operation, input)
+	}
+
+	return p.interactions[last].replay(out)
+}
+
+// AssertReplayed fails the test if any recorded calls were not replayed.
+func (p *Replayer) AssertReplayed(t testing.TB) {
+	t.Helper()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, x := range p.interactions {
+		if !p.replayed[i] {
+			t.Errorf("%s with input %s was not replayed", x.Operation, x.Input)
+		}
+	}
+}
+
+// StartQueryExecution replays a call to StartQueryExecution.
+func (p *Replayer) StartQueryExecution(in *aa.StartQueryExecutionInput) (*aa.StartQueryExecutionOutput, error) {
+	out := &aa.StartQueryExecutionOutput{}
+	if err := p.replay("StartQueryExecution", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetQueryExecution replays a call to GetQueryExecution.
+func (p *Replayer) GetQueryExecution(in *aa.GetQueryExecutionInput) (*aa.GetQueryExecutionOutput, error) {
+	out := &aa.GetQueryExecutionOutput{}
+	if err := p.replay("GetQueryExecution", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// BatchGetQueryExecution replays a call to BatchGetQueryExecution.
+func (p *Replayer) BatchGetQueryExecution(in *aa.BatchGetQueryExecutionInput) (*aa.BatchGetQueryExecutionOutput, error) {
+	out := &aa.BatchGetQueryExecutionOutput{}
+	if err := p.replay("BatchGetQueryExecution", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ListQueryExecutions replays a call to ListQueryExecutions.
+func (p *Replayer) ListQueryExecutions(in *aa.ListQueryExecutionsInput) (*aa.ListQueryExecutionsOutput, error) {
+	out := &aa.ListQueryExecutionsOutput{}
+	if err := p.replay("ListQueryExecutions", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// StopQueryExecution replays a call to StopQueryExecution.
+func (p *Replayer) StopQueryExecution(in *aa.StopQueryExecutionInput) (*aa.StopQueryExecutionOutput, error) {
+	out := &aa.StopQueryExecutionOutput{}
+	if err := p.replay("StopQueryExecution", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetQueryResults replays a call to GetQueryResults.
+func (p *Replayer) GetQueryResults(in *aa.GetQueryResultsInput) (*aa.GetQueryResultsOutput, error) {
+	out := &aa.GetQueryResultsOutput{}
+	if err := p.replay("GetQueryResults", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// CreateNamedQuery replays a call to CreateNamedQuery.
+func (p *Replayer) CreateNamedQuery(in *aa.CreateNamedQueryInput) (*aa.CreateNamedQueryOutput, error) {
+	out := &aa.CreateNamedQueryOutput{}
+	if err := p.replay("CreateNamedQuery", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetNamedQuery replays a call to GetNamedQuery.
+func (p *Replayer) GetNamedQuery(in *aa.GetNamedQueryInput) (*aa.GetNamedQueryOutput, error) {
+	out := &aa.GetNamedQueryOutput{}
+	if err := p.replay("GetNamedQuery", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// BatchGetNamedQuery replays a call to BatchGetNamedQuery.
+func (p *Replayer) BatchGetNamedQuery(in *aa.BatchGetNamedQueryInput) (*aa.BatchGetNamedQueryOutput, error) {
+	out := &aa.BatchGetNamedQueryOutput{}
+	if err := p.replay("BatchGetNamedQuery", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ListNamedQueries replays a call to ListNamedQueries.
+func (p *Replayer) ListNamedQueries(in *aa.ListNamedQueriesInput) (*aa.ListNamedQueriesOutput, error) {
+	out := &aa.ListNamedQueriesOutput{}
+	if err := p.replay("ListNamedQueries", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// DeleteNamedQuery replays a call to DeleteNamedQuery.
+func (p *Replayer) DeleteNamedQuery(in *aa.DeleteNamedQueryInput) (*aa.DeleteNamedQueryOutput, error) {
+	out := &aa.DeleteNamedQueryOutput{}
+	if err := p.replay("DeleteNamedQuery", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// CreateWorkGroup replays a call to CreateWorkGroup.
+func (p *Replayer) CreateWorkGroup(in *aa.CreateWorkGroupInput) (*aa.CreateWorkGroupOutput, error) {
+	out := &aa.CreateWorkGroupOutput{}
+	if err := p.replay("CreateWorkGroup", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetWorkGroup replays a call to GetWorkGroup.
+func (p *Replayer) GetWorkGroup(in *aa.GetWorkGroupInput) (*aa.GetWorkGroupOutput, error) {
+	out := &aa.GetWorkGroupOutput{}
+	if err := p.replay("GetWorkGroup", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ListWorkGroups replays a call to ListWorkGroups.
+func (p *Replayer) ListWorkGroups(in *aa.ListWorkGroupsInput) (*aa.ListWorkGroupsOutput, error) {
+	out := &aa.ListWorkGroupsOutput{}
+	if err := p.replay("ListWorkGroups", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// UpdateWorkGroup replays a call to UpdateWorkGroup.
+func (p *Replayer) UpdateWorkGroup(in *aa.UpdateWorkGroupInput) (*aa.UpdateWorkGroupOutput, error) {
+	out := &aa.UpdateWorkGroupOutput{}
+	if err := p.replay("UpdateWorkGroup", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// DeleteWorkGroup replays a call to DeleteWorkGroup.
+func (p *Replayer) DeleteWorkGroup(in *aa.DeleteWorkGroupInput) (*aa.DeleteWorkGroupOutput, error) {
+	out := &aa.DeleteWorkGroupOutput{}
+	if err := p.replay("DeleteWorkGroup", in, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
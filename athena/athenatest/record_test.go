@@ -0,0 +1,103 @@
+package athenatest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenatest")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "testdata", "events.json")
+	redaction := athenatest.Redaction{
+		Fields:  []string{"KmsKey"},
+		Strings: []string{"123456789012"},
+		Query:   athena.RedactLiterals,
+	}
+
+	const (
+		query  = "SELECT kind, count(*) AS n FROM events WHERE user = 'alice' GROUP BY kind"
+		output = "s3://results-123456789012/"
+	)
+
+	run := func(api athena.Client) (athena.Result, error) {
+		api = api.WithPollInterval(time.Millisecond)
+
+		r, err := api.Run(context.Background(), "db", query, output)
+		if err != nil {
+			return r, err
+		}
+
+		_, err = api.WithWorkGroup("unknown").DoQuery("db", query, output)
+		return r, err
+	}
+
+	f := athenatest.NewFake()
+	f.Respond(`FROM events`, athenatest.Response{Result: events, States: []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning}})
+
+	rec := athenatest.NewRecorder(f, redaction)
+
+	recorded, recErr := run(athena.NewClientWithAPI(rec))
+	if err := rec.Save(path); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	for _, s := range []string{"alice", "123456789012", "ClientRequestToken"} {
+		if strings.Contains(string(b), s) {
+			t.Errorf("golden file contains %q", s)
+		}
+	}
+
+	p, err := athenatest.NewReplayer(path, redaction)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	replayed, err := run(athena.NewClientWithAPI(p))
+
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("Result == %v (want %v)", replayed, recorded)
+	}
+
+	aerr, ok := err.(awserr.Error)
+	if !ok || aerr.Code() != aa.ErrCodeInvalidRequestException || err.Error() != recErr.Error() {
+		t.Errorf("err == %v (want %v)", err, recErr)
+	}
+
+	p.AssertReplayed(t)
+
+	// the last matching call is repeated once all are replayed
+	q, err := athena.NewClientWithAPI(p).DoQuery("db", query, output)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if e, err := q.Execution(); err != nil || e.State != aa.QueryExecutionStateSucceeded {
+		t.Errorf("Execution == %v, %v (want %s)", e, err, aa.QueryExecutionStateSucceeded)
+	}
+
+	_, err = athena.NewClientWithAPI(p).DoQuery("db", "SELECT 1", output)
+	if err == nil || !strings.Contains(err.Error(), "no StartQueryExecution recorded") {
+		t.Errorf("err == %v (want no StartQueryExecution recorded)", err)
+	}
+}
@@ -0,0 +1,220 @@
+package athenatest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// Credentials and region clients of a Server must use; requests signed
+// otherwise are rejected.
+const (
+	AccessKeyID     = "AKIDATHENATEST"
+	SecretAccessKey = "athenatest-secret"
+	Region          = "us-east-1"
+)
+
+// targetPrefix prefixes the operation in the X-Amz-Target header.
+const targetPrefix = "AmazonAthena."
+
+// operations are the operations a Server serves, which are those the
+// fake implements.
+var operations = map[string]bool{
+	"StartQueryExecution":    true,
+	"GetQueryExecution":      true,
+	"BatchGetQueryExecution": true,
+	"ListQueryExecutions":    true,
+	"StopQueryExecution":     true,
+	"GetQueryResults":        true,
+	"CreateNamedQuery":       true,
+	"GetNamedQuery":          true,
+	"BatchGetNamedQuery":     true,
+	"ListNamedQueries":       true,
+	"DeleteNamedQuery":       true,
+	"CreateWorkGroup":        true,
+	"GetWorkGroup":           true,
+	"ListWorkGroups":         true,
+	"UpdateWorkGroup":        true,
+	"DeleteWorkGroup":        true,
+}
+
+// Server is a local HTTP server speaking Athena's JSON 1.1 protocol,
+// backed by a Fake. An SDK session pointed at it with Config exercises
+// request signing, serialization and pagination without calling AWS:
+//
+//	srv := athenatest.NewServer(nil)
+//	defer srv.Close()
+//
+//	srv.Fake.Respond(`FROM events`, athenatest.Response{...})
+//
+//	sess, err := srv.Session()
+//	client, err := athena.NewClient(sess)
+//
+// Programs, such as the CLI, can be pointed at it with its URL as their
+// endpoint and the AccessKeyID and SecretAccessKey credentials.
+type Server struct {
+	*httptest.Server
+
+	// Fake holds the server's queries.
+	Fake *Fake
+}
+
+// NewServer starts a server backed by fake, or a new fake if nil. The
+// server should be closed when no longer needed.
+func NewServer(fake *Fake) *Server {
+	if fake == nil {
+		fake = NewFake()
+	}
+
+	s := &Server{Fake: fake}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Config returns the configuration of an SDK client using the server.
+func (s *Server) Config() *aws.Config {
+	return aws.NewConfig().
+		WithEndpoint(s.URL).
+		WithRegion(Region).
+		WithCredentials(credentials.NewStaticCredentials(AccessKeyID, SecretAccessKey, ""))
+}
+
+// Session returns a session whose clients use the server.
+func (s *Server) Session() (*session.Session, error) {
+	return session.NewSession(s.Config())
+}
+
+// serveHTTP handles a request, calling the operation named by its target
+// on the fake.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "UnknownOperationException", "method "+r.Method+" not allowed")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return
+	}
+
+	if err := verifySignature(r, body); err != nil {
+		writeError(w, http.StatusForbidden, "InvalidSignatureException", err.Error())
+		return
+	}
+
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), targetPrefix)
+	if !operations[op] {
+		writeError(w, http.StatusBadRequest, "UnknownOperationException", "unsupported operation "+op)
+		return
+	}
+
+	method := reflect.ValueOf(s.Fake).MethodByName(op)
+	in := reflect.New(method.Type().In(0).Elem())
+
+	if len(body) > 0 {
+		if err := unmarshalJSON(in.Interface(), body); err != nil {
+			writeError(w, http.StatusBadRequest, "SerializationException", err.Error())
+			return
+		}
+	}
+
+	results := method.Call([]reflect.Value{in})
+
+	if err, _ := results[1].Interface().(error); err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			writeError(w, http.StatusBadRequest, aerr.Code(), aerr.Message())
+		} else {
+			writeError(w, http.StatusInternalServerError, aa.ErrCodeInternalServerException, err.Error())
+		}
+
+		return
+	}
+
+	b, err := marshalJSON(results[0].Interface())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, aa.ErrCodeInternalServerException, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Write(b)
+}
+
+// writeError writes an error response as Athena does.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	b, _ := marshalJSON(&struct {
+		Type    *string `locationName:"__type" type:"string"`
+		Message *string `locationName:"message" type:"string"`
+	}{aws.String(code), aws.String(message)})
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// verifySignature checks the request is signed with the server's
+// credentials, by signing the headers it signed again.
+func verifySignature(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+
+	var signed []string
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		if strings.HasPrefix(part, "SignedHeaders=") {
+			signed = strings.Split(strings.TrimPrefix(part, "SignedHeaders="), ";")
+		}
+	}
+
+	if signed == nil {
+		return fmt.Errorf("request is not signed")
+	}
+
+	t, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return fmt.Errorf("invalid X-Amz-Date: %v", err)
+	}
+
+	req, err := http.NewRequest(r.Method, r.URL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Host = r.Host
+
+	for _, name := range signed {
+		key := http.CanonicalHeaderKey(name)
+
+		switch {
+		case key == "Host":
+		case key == "Content-Length" && len(r.Header[key]) == 0:
+			req.Header.Set(key, strconv.FormatInt(r.ContentLength, 10))
+		default:
+			req.Header[key] = r.Header[key]
+		}
+	}
+
+	signer := v4.NewSigner(credentials.NewStaticCredentials(AccessKeyID, SecretAccessKey, ""))
+	if _, err := signer.Sign(req, bytes.NewReader(body), "athena", Region, t); err != nil {
+		return err
+	}
+
+	if req.Header.Get("Authorization") != auth {
+		return fmt.Errorf("signature does not match; sign requests with the athenatest credentials")
+	}
+
+	return nil
+}
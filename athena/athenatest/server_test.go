@@ -0,0 +1,111 @@
+package athenatest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestServer(t *testing.T) {
+	srv := athenatest.NewServer(nil)
+	defer srv.Close()
+
+	// enough rows for two pages of results
+	big := athena.Result{Columns: []athena.Column{{Name: "i", Type: "integer"}}}
+	for i := 0; i < 1500; i++ {
+		big.Rows = append(big.Rows, athena.Row{fmt.Sprint(i)})
+	}
+
+	srv.Fake.Respond(`FROM big`, athenatest.Response{
+		Result:             big,
+		States:             []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning},
+		DataScannedInBytes: 4096,
+	})
+
+	sess, err := srv.Session()
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	c, err := athena.NewClient(sess)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	c = c.WithPollInterval(time.Millisecond)
+
+	q, err := c.DoQuery("db", "SELECT i FROM big", "s3://bucket/results/")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if _, err := q.Wait(context.Background()); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	r, err := q.AllResults()
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	r = r.WithoutHeader()
+	if len(r.Rows) != 1500 || r.Rows[1499][0] != "1499" || r.Columns[0].Type != "integer" {
+		t.Errorf("Result has %d rows, columns %v (want 1500 rows of i)", len(r.Rows), r.Columns)
+	}
+
+	srv.Fake.AssertCalled(t, "GetQueryResults", 2)
+
+	e, err := q.Execution()
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if e.DataScannedInBytes != 4096 || e.SubmissionTime.IsZero() || e.CompletionTime.IsZero() {
+		t.Errorf("Execution == %+v", e)
+	}
+
+	history, err := c.History("", 10)
+	if err != nil || len(history) != 1 || history[0].ID != q.ID() {
+		t.Errorf("History == %v, %v (want the query)", history, err)
+	}
+
+	_, err = c.WithWorkGroup("unknown").DoQuery("db", "SELECT 1", "s3://bucket/")
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != aa.ErrCodeInvalidRequestException {
+		t.Errorf("err == %v (want %s)", err, aa.ErrCodeInvalidRequestException)
+	}
+}
+
+func TestServerSignature(t *testing.T) {
+	srv := athenatest.NewServer(nil)
+	defer srv.Close()
+
+	cfg := srv.Config().WithCredentials(credentials.NewStaticCredentials(athenatest.AccessKeyID, "wrong", "")).WithMaxRetries(0)
+
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	_, err = aa.New(sess).ListWorkGroups(&aa.ListWorkGroupsInput{})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "InvalidSignatureException" {
+		t.Errorf("err == %v (want InvalidSignatureException)", err)
+	}
+
+	sess, _ = srv.Session()
+
+	out, err := aa.New(sess).ListWorkGroups(&aa.ListWorkGroupsInput{MaxResults: aws.Int64(1)})
+	if err != nil || len(out.WorkGroups) != 1 || aws.StringValue(out.WorkGroups[0].Name) != athenatest.PrimaryWorkGroup {
+		t.Errorf("ListWorkGroups == %v, %v (want primary)", out, err)
+	}
+
+	srv.Fake.AssertCalled(t, "ListWorkGroups", 1)
+}
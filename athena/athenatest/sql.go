@@ -0,0 +1,930 @@
+package athenatest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind is the kind of a SQL token.
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tQuoted
+	tString
+	tNumber
+	tSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// syntaxError returns an error as Athena reports syntax errors.
+func syntaxError(format string, args ...interface{}) error {
+	return fmt.Errorf("SYNTAX_ERROR: "+format, args...)
+}
+
+// lex splits a SQL statement into tokens. Unquoted identifiers and
+// keywords are lower cased, as Athena treats them case insensitively.
+func lex(sql string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(sql[i:], "--"):
+			if j := strings.IndexByte(sql[i:], '\n'); j < 0 {
+				i = len(sql)
+			} else {
+				i += j
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			j := strings.Index(sql[i+2:], "*/")
+			if j < 0 {
+				return nil, syntaxError("unterminated comment at %d", i)
+			}
+
+			i += j + 4
+		case c == '\'' || c == '"':
+			text, n, err := quoted(sql, i)
+			if err != nil {
+				return nil, err
+			}
+
+			kind := tString
+			if c == '"' {
+				kind = tQuoted
+			}
+
+			tokens = append(tokens, token{kind, text, i})
+			i += n
+		case isDigit(c) || c == '.' && i+1 < len(sql) && isDigit(sql[i+1]):
+			j := i
+			for j < len(sql) && (isDigit(sql[j]) || sql[j] == '.') {
+				j++
+			}
+
+			if j < len(sql) && (sql[j] == 'e' || sql[j] == 'E') {
+				j++
+				if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+					j++
+				}
+
+				for j < len(sql) && isDigit(sql[j]) {
+					j++
+				}
+			}
+
+			tokens = append(tokens, token{tNumber, sql[i:j], i})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(sql) && (isIdentStart(sql[j]) || isDigit(sql[j])) {
+				j++
+			}
+
+			tokens = append(tokens, token{tIdent, strings.ToLower(sql[i:j]), i})
+			i = j
+		default:
+			symbol := string(c)
+			for _, s := range []string{"<>", "!=", "<=", ">=", "||"} {
+				if strings.HasPrefix(sql[i:], s) {
+					symbol = s
+				}
+			}
+
+			if !strings.Contains("=<>!+-*/%(),.;|", symbol[:1]) || symbol == "!" || symbol == "|" {
+				return nil, syntaxError("unexpected character %q at %d", c, i)
+			}
+
+			tokens = append(tokens, token{tSymbol, symbol, i})
+			i += len(symbol)
+		}
+	}
+
+	return append(tokens, token{tEOF, "", len(sql)}), nil
+}
+
+// quoted returns the contents of the quoted string or identifier at i,
+// where doubled quotes are escaped quotes, and its length.
+func quoted(sql string, i int) (string, int, error) {
+	q := sql[i]
+
+	var b strings.Builder
+
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] != q {
+			b.WriteByte(sql[j])
+			continue
+		}
+
+		if j+1 < len(sql) && sql[j+1] == q {
+			b.WriteByte(q)
+			j++
+			continue
+		}
+
+		return b.String(), j + 1 - i, nil
+	}
+
+	return "", 0, syntaxError("unterminated quote at %d", i)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// Expressions
+type (
+	literal struct {
+		v value
+	}
+
+	columnRef struct {
+		table, name string
+	}
+
+	unary struct {
+		op string
+		x  expr
+	}
+
+	binary struct {
+		op   string
+		l, r expr
+	}
+
+	isNull struct {
+		x   expr
+		not bool
+	}
+
+	inList struct {
+		x    expr
+		list []expr
+		not  bool
+	}
+
+	between struct {
+		x, lo, hi expr
+		not       bool
+	}
+
+	like struct {
+		x, pattern expr
+		not        bool
+	}
+
+	call struct {
+		name     string
+		args     []expr
+		star     bool
+		distinct bool
+	}
+
+	when struct {
+		cond, result expr
+	}
+
+	caseExpr struct {
+		operand expr
+		whens   []when
+		els     expr
+	}
+
+	cast struct {
+		x   expr
+		typ string
+
+		// try is set for TRY_CAST, which gives NULL if the cast fails.
+		try bool
+	}
+)
+
+// expr is one of the expression types above.
+type expr interface{}
+
+// selectItem is an expression of the select list, or * for every column.
+type selectItem struct {
+	x     expr
+	alias string
+	star  bool
+}
+
+type orderItem struct {
+	x    expr
+	desc bool
+
+	// nullsFirst sorts nulls first; by default they are last.
+	nullsFirst bool
+}
+
+// statement is a parsed SELECT statement.
+type statement struct {
+	distinct bool
+	items    []selectItem
+
+	// database and table are empty if there is no FROM clause.
+	database, table, alias string
+
+	where   expr
+	groupBy []expr
+	having  expr
+	orderBy []orderItem
+
+	// limit is -1 without a LIMIT clause.
+	limit int
+}
+
+// parser parses a SELECT statement by recursive descent.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// parse parses a SELECT statement.
+func parse(sql string) (*statement, error) {
+	tokens, err := lex(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	if !p.peekKeyword("select") {
+		return nil, fmt.Errorf("NOT_SUPPORTED: only SELECT statements can be executed")
+	}
+
+	s, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	p.acceptSymbol(";")
+
+	if t := p.peek(); t.kind != tEOF {
+		return nil, syntaxError("unexpected %q at %d", t.text, t.pos)
+	}
+
+	return s, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+
+	return t
+}
+
+func (p *parser) peekKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tIdent && t.text == kw
+}
+
+func (p *parser) acceptKeyword(kws ...string) bool {
+	for j, kw := range kws {
+		if p.i+j >= len(p.tokens) {
+			return false
+		}
+
+		t := p.tokens[p.i+j]
+		if t.kind != tIdent || t.text != kw {
+			return false
+		}
+	}
+
+	p.i += len(kws)
+
+	return true
+}
+
+func (p *parser) expectKeyword(kws ...string) error {
+	if !p.acceptKeyword(kws...) {
+		t := p.peek()
+		return syntaxError("expected %s at %d, found %q", strings.ToUpper(strings.Join(kws, " ")), t.pos, t.text)
+	}
+
+	return nil
+}
+
+func (p *parser) acceptSymbol(s string) bool {
+	if t := p.peek(); t.kind == tSymbol && t.text == s {
+		p.i++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectSymbol(s string) error {
+	if !p.acceptSymbol(s) {
+		t := p.peek()
+		return syntaxError("expected %q at %d, found %q", s, t.pos, t.text)
+	}
+
+	return nil
+}
+
+// reserved are keywords which cannot be used as unquoted aliases.
+var reserved = map[string]bool{
+	"select": true, "from": true, "where": true, "group": true, "having": true,
+	"order": true, "limit": true, "by": true, "and": true, "or": true, "not": true,
+	"as": true, "on": true, "join": true, "tablesample": true, "union": true,
+	"case": true, "when": true, "then": true, "else": true, "end": true,
+	"is": true, "in": true, "between": true, "like": true, "asc": true, "desc": true,
+	"nulls": true, "distinct": true,
+}
+
+// identifier parses an identifier, quoted or not.
+func (p *parser) identifier() (string, error) {
+	t := p.peek()
+	if t.kind == tQuoted || t.kind == tIdent && !reserved[t.text] {
+		p.i++
+		return strings.ToLower(t.text), nil
+	}
+
+	return "", syntaxError("expected an identifier at %d, found %q", t.pos, t.text)
+}
+
+// alias parses an optional alias, with or without AS.
+func (p *parser) alias() (string, error) {
+	if p.acceptKeyword("as") {
+		return p.identifier()
+	}
+
+	if t := p.peek(); t.kind == tQuoted || t.kind == tIdent && !reserved[t.text] {
+		return p.identifier()
+	}
+
+	return "", nil
+}
+
+func (p *parser) statement() (*statement, error) {
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+
+	s := &statement{limit: -1}
+	s.distinct = p.acceptKeyword("distinct")
+	p.acceptKeyword("all")
+
+	for {
+		if p.acceptSymbol("*") {
+			s.items = append(s.items, selectItem{star: true})
+		} else {
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+
+			alias, err := p.alias()
+			if err != nil {
+				return nil, err
+			}
+
+			s.items = append(s.items, selectItem{x: x, alias: alias})
+		}
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if p.acceptKeyword("from") {
+		if err := p.from(s); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+
+	if p.acceptKeyword("where") {
+		if s.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("group", "by") {
+		if s.groupBy, err = p.exprList(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("having") {
+		if s.having, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("order", "by") {
+		for {
+			var o orderItem
+			if o.x, err = p.expr(); err != nil {
+				return nil, err
+			}
+
+			if p.acceptKeyword("desc") {
+				o.desc = true
+			} else {
+				p.acceptKeyword("asc")
+			}
+
+			if p.acceptKeyword("nulls", "first") {
+				o.nullsFirst = true
+			} else {
+				p.acceptKeyword("nulls", "last")
+			}
+
+			s.orderBy = append(s.orderBy, o)
+
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("limit") {
+		if p.acceptKeyword("all") {
+			return s, nil
+		}
+
+		t := p.next()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != tNumber || err != nil || n < 0 {
+			return nil, syntaxError("invalid LIMIT %q at %d", t.text, t.pos)
+		}
+
+		s.limit = n
+	}
+
+	return s, nil
+}
+
+// from parses the table of a FROM clause, which may be qualified by its
+// database and sampled.
+func (p *parser) from(s *statement) error {
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+
+	s.table = name
+
+	if p.acceptSymbol(".") {
+		if s.table, err = p.identifier(); err != nil {
+			return err
+		}
+
+		s.database = name
+	}
+
+	// samples keep every row, so that results are repeatable
+	if p.acceptKeyword("tablesample") {
+		if _, err := p.identifier(); err != nil {
+			return err
+		}
+
+		if err := p.expectSymbol("("); err != nil {
+			return err
+		}
+
+		if _, err := p.expr(); err != nil {
+			return err
+		}
+
+		if err := p.expectSymbol(")"); err != nil {
+			return err
+		}
+	}
+
+	if s.alias, err = p.alias(); err != nil {
+		return err
+	}
+
+	if t := p.peek(); t.kind == tSymbol && t.text == "," || p.peekKeyword("join") {
+		return fmt.Errorf("NOT_SUPPORTED: joins are not supported")
+	}
+
+	return nil
+}
+
+func (p *parser) exprList() ([]expr, error) {
+	var list []expr
+
+	for {
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, x)
+
+		if !p.acceptSymbol(",") {
+			return list, nil
+		}
+	}
+}
+
+func (p *parser) expr() (expr, error) {
+	return p.or()
+}
+
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("or") {
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		l = binary{"or", l, r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) and() (expr, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("and") {
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		l = binary{"and", l, r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) not() (expr, error) {
+	if p.acceptKeyword("not") {
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return unary{"not", x}, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expr, error) {
+	l, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+
+		switch {
+		case t.kind == tSymbol && strings.Contains(" = <> != < <= > >= ", " "+t.text+" "):
+			p.i++
+
+			r, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+
+			op := t.text
+			if op == "!=" {
+				op = "<>"
+			}
+
+			l = binary{op, l, r}
+		case p.acceptKeyword("is"):
+			not := p.acceptKeyword("not")
+			if err := p.expectKeyword("null"); err != nil {
+				return nil, err
+			}
+
+			l = isNull{l, not}
+		default:
+			not := p.peekKeyword("not")
+			if not {
+				p.i++
+			}
+
+			switch {
+			case p.acceptKeyword("in"):
+				if err := p.expectSymbol("("); err != nil {
+					return nil, err
+				}
+
+				list, err := p.exprList()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expectSymbol(")"); err != nil {
+					return nil, err
+				}
+
+				l = inList{l, list, not}
+			case p.acceptKeyword("between"):
+				lo, err := p.additive()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expectKeyword("and"); err != nil {
+					return nil, err
+				}
+
+				hi, err := p.additive()
+				if err != nil {
+					return nil, err
+				}
+
+				l = between{l, lo, hi, not}
+			case p.acceptKeyword("like"):
+				pattern, err := p.additive()
+				if err != nil {
+					return nil, err
+				}
+
+				l = like{l, pattern, not}
+			default:
+				if not {
+					p.i--
+				}
+
+				return l, nil
+			}
+		}
+	}
+}
+
+func (p *parser) additive() (expr, error) {
+	l, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tSymbol || t.text != "+" && t.text != "-" && t.text != "||" {
+			return l, nil
+		}
+
+		p.i++
+
+		r, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+
+		l = binary{t.text, l, r}
+	}
+}
+
+func (p *parser) multiplicative() (expr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tSymbol || t.text != "*" && t.text != "/" && t.text != "%" {
+			return l, nil
+		}
+
+		p.i++
+
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		l = binary{t.text, l, r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.acceptSymbol("-") {
+		// a negative literal is a bigint if it fits, as its magnitude
+		// may not, e.g. -9223372036854775808
+		if t := p.peek(); t.kind == tNumber {
+			if n, err := strconv.ParseInt("-"+t.text, 10, 64); err == nil {
+				p.i++
+				return literal{n}, nil
+			}
+		}
+
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return unary{"-", x}, nil
+	}
+
+	p.acceptSymbol("+")
+
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tNumber:
+		p.i++
+
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return literal{n}, nil
+		}
+
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, syntaxError("invalid number %q at %d", t.text, t.pos)
+		}
+
+		return literal{f}, nil
+	case t.kind == tString:
+		p.i++
+		return literal{t.text}, nil
+	case t.kind == tSymbol && t.text == "(":
+		p.i++
+
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		return x, p.expectSymbol(")")
+	case t.kind == tQuoted:
+		return p.column()
+	case t.kind != tIdent:
+		return nil, syntaxError("unexpected %q at %d", t.text, t.pos)
+	}
+
+	switch t.text {
+	case "null":
+		p.i++
+		return literal{nil}, nil
+	case "true", "false":
+		p.i++
+		return literal{t.text == "true"}, nil
+	case "date", "timestamp":
+		// typed literals are compared as their text
+		if next := p.tokens[p.i+1]; next.kind == tString {
+			p.i += 2
+			return literal{next.text}, nil
+		}
+	case "case":
+		p.i++
+		return p.caseExpr()
+	case "cast", "try_cast":
+		p.i++
+		return p.cast(t.text == "try_cast")
+	}
+
+	if next := p.tokens[p.i+1]; next.kind == tSymbol && next.text == "(" {
+		p.i += 2
+		return p.call(t.text)
+	}
+
+	return p.column()
+}
+
+// column parses a column reference, which may be qualified by its table.
+func (p *parser) column() (expr, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptSymbol(".") {
+		column, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+
+		return columnRef{name, column}, nil
+	}
+
+	return columnRef{"", name}, nil
+}
+
+func (p *parser) call(name string) (expr, error) {
+	c := call{name: name}
+
+	if p.acceptSymbol("*") {
+		c.star = true
+		return c, p.expectSymbol(")")
+	}
+
+	if p.acceptSymbol(")") {
+		return c, nil
+	}
+
+	c.distinct = p.acceptKeyword("distinct")
+
+	var err error
+	if c.args, err = p.exprList(); err != nil {
+		return nil, err
+	}
+
+	return c, p.expectSymbol(")")
+}
+
+func (p *parser) caseExpr() (expr, error) {
+	var c caseExpr
+	var err error
+
+	if !p.peekKeyword("when") {
+		if c.operand, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	for p.acceptKeyword("when") {
+		var w when
+		if w.cond, err = p.expr(); err != nil {
+			return nil, err
+		}
+
+		if err := p.expectKeyword("then"); err != nil {
+			return nil, err
+		}
+
+		if w.result, err = p.expr(); err != nil {
+			return nil, err
+		}
+
+		c.whens = append(c.whens, w)
+	}
+
+	if len(c.whens) == 0 {
+		return nil, syntaxError("CASE without WHEN at %d", p.peek().pos)
+	}
+
+	if p.acceptKeyword("else") {
+		if c.els, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, p.expectKeyword("end")
+}
+
+func (p *parser) cast(try bool) (expr, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword("as"); err != nil {
+		return nil, err
+	}
+
+	typ, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	// ignore the length or precision of the type
+	if p.acceptSymbol("(") {
+		for !p.acceptSymbol(")") {
+			if p.next().kind == tEOF {
+				return nil, syntaxError("unterminated type %s", typ)
+			}
+		}
+	}
+
+	return cast{x, typ, try}, p.expectSymbol(")")
+}
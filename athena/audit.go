@@ -0,0 +1,326 @@
+package athena
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// Audit events
+const (
+	// AuditSubmitted is recorded when a query is started, or fails to start.
+	AuditSubmitted = "submitted"
+
+	// AuditCompleted is recorded when Wait sees a query finish.
+	AuditCompleted = "completed"
+)
+
+// Identity is the AWS identity queries are run as.
+type Identity struct {
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+	UserID  string `json:"user_id"`
+}
+
+// CallerIdentity returns the identity of the credentials used by api.
+func CallerIdentity(api stsiface.STSAPI) (Identity, error) {
+	out, err := api.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return Identity{}, err
+	}
+
+	var id Identity
+
+	if out.Account != nil {
+		id.Account = *out.Account
+	}
+
+	if out.Arn != nil {
+		id.ARN = *out.Arn
+	}
+
+	if out.UserId != nil {
+		id.UserID = *out.UserId
+	}
+
+	return id, nil
+}
+
+// AuditRecord records the submission or completion of a query.
+type AuditRecord struct {
+	// Event is AuditSubmitted or AuditCompleted.
+	Event string `json:"event"`
+
+	// Time is when the event was recorded.
+	Time time.Time `json:"time"`
+
+	// QueryID is the Athena query execution ID; it is empty if the query
+	// could not be started.
+	QueryID string `json:"query_id,omitempty"`
+
+	// Query is the SQL text of the query, redacted if the auditor redacts.
+	Query string `json:"query,omitempty"`
+
+	Database  string   `json:"database,omitempty"`
+	WorkGroup string   `json:"workgroup,omitempty"`
+	Identity  Identity `json:"identity"`
+
+	// SubmissionTime and CompletionTime are Athena's timings of a
+	// completed query, and EngineExecutionTime how long it ran for.
+	SubmissionTime      time.Time     `json:"submission_time"`
+	CompletionTime      time.Time     `json:"completion_time"`
+	EngineExecutionTime time.Duration `json:"engine_execution_time,omitempty"`
+
+	// State is the final state of a completed query.
+	State string `json:"state,omitempty"`
+
+	DataScannedInBytes int64 `json:"data_scanned_in_bytes,omitempty"`
+
+	// Error describes why the query could not be started, or did not
+	// succeed.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives audit records. Sinks may be called concurrently by
+// clients used concurrently.
+type AuditSink interface {
+	Audit(AuditRecord) error
+}
+
+// AuditFunc adapts a function to an AuditSink.
+type AuditFunc func(AuditRecord) error
+
+// Audit calls f.
+func (f AuditFunc) Audit(r AuditRecord) error {
+	return f(r)
+}
+
+// auditWriter writes audit records as JSON lines.
+type auditWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewAuditWriter returns a sink writing records to w as JSON, one per line.
+func NewAuditWriter(w io.Writer) AuditSink {
+	return &auditWriter{enc: json.NewEncoder(w)}
+}
+
+func (aw *auditWriter) Audit(r AuditRecord) error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	return aw.enc.Encode(r)
+}
+
+// AuditFile is a sink appending records to a file as JSON lines.
+type AuditFile struct {
+	AuditSink
+	f *os.File
+}
+
+// OpenAuditFile opens the named file for appending audit records to,
+// creating it if needed.
+func OpenAuditFile(name string) (*AuditFile, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditFile{AuditSink: NewAuditWriter(f), f: f}, nil
+}
+
+// Close closes the file.
+func (af *AuditFile) Close() error {
+	return af.f.Close()
+}
+
+// Auditor records the queries of clients configured WithAuditor.
+type Auditor struct {
+	// Identity is the identity queries are run as; see CallerIdentity.
+	Identity Identity
+
+	// Redact, if not nil, is applied to the SQL text of queries before
+	// it is recorded; see RedactLiterals.
+	Redact func(query string) string
+
+	// Sinks receive each record, in order.
+	Sinks []AuditSink
+
+	// OnError, if not nil, is called with errors returned by sinks. Errors
+	// do not affect queries.
+	OnError func(error)
+}
+
+// NewAuditor returns an auditor recording to sinks, with the identity of
+// the credentials of session.
+func NewAuditor(session *session.Session, sinks ...AuditSink) (*Auditor, error) {
+	if session == nil {
+		return nil, nilSession
+	}
+
+	id, err := CallerIdentity(sts.New(session))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Auditor{Identity: id, Sinks: sinks}, nil
+}
+
+// WithAuditor returns a copy of the client which records the submission
+// of its queries, and their completion when seen by Wait, with auditor.
+// As completion is recorded by Wait, waiting for a query more than once
+// records its completion more than once.
+func (c Client) WithAuditor(auditor *Auditor) Client {
+	return c.withOptions(func(o *options) {
+		o.auditor = auditor
+	})
+}
+
+// record sends r to the sinks, filling in the common fields.
+func (a *Auditor) record(r AuditRecord) {
+	r.Time = time.Now()
+	r.Identity = a.Identity
+
+	if a.Redact != nil && r.Query != "" {
+		r.Query = a.Redact(r.Query)
+	}
+
+	for _, s := range a.Sinks {
+		if err := s.Audit(r); err != nil && a.OnError != nil {
+			a.OnError(err)
+		}
+	}
+}
+
+// auditSubmission records the start of a query, or the failure to start it.
+func (c Client) auditSubmission(id string, in *athena.StartQueryExecutionInput, err error) {
+	a := c.options().auditor
+	if a == nil {
+		return
+	}
+
+	r := AuditRecord{
+		Event:    AuditSubmitted,
+		QueryID:  id,
+		Query:    *in.QueryString,
+		Database: *in.QueryExecutionContext.Database,
+	}
+
+	if in.WorkGroup != nil {
+		r.WorkGroup = *in.WorkGroup
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	a.record(r)
+}
+
+// auditCompletion records the completion of a query; err is the reason it
+// was stopped, if it was.
+func (q Query) auditCompletion(qe *athena.QueryExecution, err error) {
+	a := q.options().auditor
+	if a == nil {
+		return
+	}
+
+	e := execution(qe)
+
+	r := AuditRecord{
+		Event:               AuditCompleted,
+		QueryID:             q.id,
+		Query:               e.Query,
+		Database:            e.Database,
+		WorkGroup:           e.WorkGroup,
+		SubmissionTime:      e.SubmissionTime,
+		CompletionTime:      e.CompletionTime,
+		EngineExecutionTime: e.Statistics.EngineExecutionTime,
+		State:               e.State,
+		DataScannedInBytes:  e.Statistics.DataScannedInBytes,
+		Error:               e.StateChangeReason,
+	}
+
+	// a query stopped by the client is being cancelled
+	if err != nil {
+		r.Error = err.Error()
+		if !terminal(r.State) {
+			r.State = athena.QueryExecutionStateCancelled
+		}
+	}
+
+	a.record(r)
+}
+
+// RedactLiterals replaces the contents of the string literals in query
+// with ***, so that values such as email addresses are not recorded.
+func RedactLiterals(query string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '"' || c == '`':
+			// identifiers are kept, skipping over them so quotes within
+			// aren't taken to start literals
+			j := closingQuote(query, i)
+			b.WriteString(query[i:j])
+			i = j - 1
+		case c == '\'':
+			j := closingQuote(query, i)
+			b.WriteString("'***'")
+			i = j - 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+
+			b.WriteString(query[i : i+j])
+			i += j - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := len(query)
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				end = i + j + 4
+			}
+
+			b.WriteString(query[i:end])
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// closingQuote returns the index after the quote closing the string
+// starting at i, where a doubled quote is an escaped quote.
+func closingQuote(s string, i int) int {
+	q := s[i]
+
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != q {
+			continue
+		}
+
+		if j+1 < len(s) && s[j+1] == q {
+			j++
+			continue
+		}
+
+		return j + 1
+	}
+
+	return len(s)
+}
@@ -0,0 +1,189 @@
+package athena_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// memorySink records audit records in memory.
+type memorySink struct {
+	mu      sync.Mutex
+	records []athena.AuditRecord
+}
+
+func (ms *memorySink) Audit(r athena.AuditRecord) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.records = append(ms.records, r)
+
+	return nil
+}
+
+type mockSTS struct {
+	out *sts.GetCallerIdentityOutput
+	err error
+
+	stsiface.STSAPI
+}
+
+func (m mockSTS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return m.out, m.err
+}
+
+func TestCallerIdentity(t *testing.T) {
+	out := &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/analyst"),
+		UserId:  aws.String("AIDAEXAMPLE"),
+	}
+
+	id, err := athena.CallerIdentity(mockSTS{out: out})
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := athena.Identity{Account: "123456789012", ARN: "arn:aws:iam::123456789012:user/analyst", UserID: "AIDAEXAMPLE"}
+	if id != expected {
+		t.Errorf("CallerIdentity() == %v (want %v)", id, expected)
+	}
+
+	errSTS := errors.New("GetCallerIdentity error")
+	if _, err := athena.CallerIdentity(mockSTS{err: errSTS}); err != errSTS {
+		t.Errorf("err == %v (want %v)", err, errSTS)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	identity := athena.Identity{Account: "123456789012", ARN: "arn:aws:iam::123456789012:user/analyst"}
+
+	mc := mockClient{
+		startQueryExecution:  startQueryExecution{id: "jobid"},
+		getQueryExecution:    getQueryExecution{state: "FAILED", outLocation: "s3://output"},
+		queryExecutionDetail: queryExecutionDetail{reason: "SYNTAX_ERROR", statistics: (&aa.QueryExecutionStatistics{}).SetDataScannedInBytes(42)},
+	}
+
+	var sink memorySink
+	auditor := &athena.Auditor{Identity: identity, Redact: athena.RedactLiterals, Sinks: []athena.AuditSink{&sink}}
+	c := athena.NewCustomClient(mc).WithWorkGroup("adhoc").WithAuditor(auditor)
+
+	q, err := c.DoQuery("db", "SELECT * FROM users WHERE email = 'someone@example.com'", "s3://output")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if _, err := q.Wait(context.Background()); err == nil {
+		t.Fatal("err == nil (want query error)")
+	}
+
+	mc.startQueryExecution = startQueryExecution{err: errors.New("throttled")}
+	if _, err := athena.NewCustomClient(mc).WithAuditor(auditor).DoQuery("db", "SELECT 1", "s3://output"); err == nil {
+		t.Fatal("err == nil (want error)")
+	}
+
+	expected := []athena.AuditRecord{
+		{
+			Event:     athena.AuditSubmitted,
+			QueryID:   "jobid",
+			Query:     "SELECT * FROM users WHERE email = '***'",
+			Database:  "db",
+			WorkGroup: "adhoc",
+		},
+		{
+			Event:              athena.AuditCompleted,
+			QueryID:            "jobid",
+			State:              "FAILED",
+			DataScannedInBytes: 42,
+			Error:              "SYNTAX_ERROR",
+		},
+		{
+			Event:    athena.AuditSubmitted,
+			Query:    "SELECT 1",
+			Database: "db",
+			Error:    "throttled",
+		},
+	}
+
+	if len(sink.records) != len(expected) {
+		t.Fatalf("len(records) == %d (want %d)", len(sink.records), len(expected))
+	}
+
+	for i, r := range sink.records {
+		if r.Time.IsZero() {
+			t.Errorf("%d: Time is zero", i)
+		}
+
+		if r.Identity != identity {
+			t.Errorf("%d: Identity == %v (want %v)", i, r.Identity, identity)
+		}
+
+		r.Time, r.Identity = expected[i].Time, expected[i].Identity
+		if r != expected[i] {
+			t.Errorf("%d: record == %+v (want %+v)", i, r, expected[i])
+		}
+	}
+}
+
+func TestAuditWriter(t *testing.T) {
+	var b bytes.Buffer
+	var sinkErr error
+
+	failing := athena.AuditFunc(func(athena.AuditRecord) error { return errors.New("sink failed") })
+	auditor := &athena.Auditor{
+		Sinks:   []athena.AuditSink{athena.NewAuditWriter(&b), failing},
+		OnError: func(err error) { sinkErr = err },
+	}
+
+	mc := mockClient{startQueryExecution: startQueryExecution{id: "jobid"}}
+	if _, err := athena.NewCustomClient(mc).WithAuditor(auditor).DoQuery("db", "SELECT 1", "s3://output"); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("lines == %q (want 1 line)", lines)
+	}
+
+	var r athena.AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	if r.Event != athena.AuditSubmitted || r.QueryID != "jobid" || r.Query != "SELECT 1" {
+		t.Errorf("record == %+v", r)
+	}
+
+	if sinkErr == nil || sinkErr.Error() != "sink failed" {
+		t.Errorf("sink error == %v (want sink failed)", sinkErr)
+	}
+}
+
+func TestRedactLiterals(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM t WHERE a = 'x' AND b = 'it''s'", "SELECT * FROM t WHERE a = '***' AND b = '***'"},
+		{`SELECT "it's" FROM t WHERE a = 'x'`, `SELECT "it's" FROM t WHERE a = '***'`},
+		{"SELECT 1 -- don't\nFROM t WHERE a = 'x'", "SELECT 1 -- don't\nFROM t WHERE a = '***'"},
+		{"SELECT 1 /* don't */ FROM t WHERE a = 'x' AND b = 'y'", "SELECT 1 /* don't */ FROM t WHERE a = '***' AND b = '***'"},
+		{"SELECT 1 /* 'x' */ FROM t /* unterminated 'y'", "SELECT 1 /* 'x' */ FROM t /* unterminated 'y'"},
+		{"WHERE a = 'unterminated", "WHERE a = '***'"},
+	} {
+		if r := athena.RedactLiterals(test.query); r != test.expected {
+			t.Errorf("RedactLiterals(%q) == %q (want %q)", test.query, r, test.expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package athena
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// minScanLimit is the smallest bytes scanned cutoff Athena accepts for a
+// workgroup.
+const minScanLimit = 10 * billingUnit
+
+// invalidScanLimit is returned when setting a workgroup's cutoff below
+// Athena's minimum.
+const invalidScanLimit = constError("bytes scanned cutoff must be at least 10 MB")
+
+// ScanLimitError is returned by Wait when a query scanned more than the
+// limit set with WithQueryLimit, and so was stopped.
+type ScanLimitError struct {
+	// ID is the Athena query execution ID.
+	ID string
+
+	// Limit is the number of bytes the query was allowed to scan.
+	Limit int64
+
+	// Scanned is the number of bytes the query had scanned when stopped.
+	Scanned int64
+}
+
+// Error satisfies the error interface.
+func (e *ScanLimitError) Error() string {
+	return fmt.Sprintf("query %s stopped: scanned %d bytes, over its limit of %d", e.ID, e.Scanned, e.Limit)
+}
+
+// BudgetError is returned by DoQuery when the session budget has been
+// spent, so the query was not started.
+type BudgetError struct {
+	// Limit is the number of bytes the session was allowed to scan.
+	Limit int64
+
+	// Spent is the number of bytes the session has scanned.
+	Spent int64
+}
+
+// Error satisfies the error interface.
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("budget exceeded: scanned %d bytes of %d", e.Spent, e.Limit)
+}
+
+// Spend is an entry in a budget's ledger, recording the data scanned by
+// a query.
+type Spend struct {
+	QueryID            string    `json:"query_id"`
+	State              string    `json:"state"`
+	DataScannedInBytes int64     `json:"data_scanned_in_bytes"`
+	Cost               float64   `json:"cost"`
+	Time               time.Time `json:"time"`
+}
+
+// Budget limits the total data scanned by the queries of one or more
+// clients, e.g. over an interactive session, and keeps a ledger of what
+// each query scanned. It is safe for concurrent use.
+//
+// A query's spend is recorded when Wait sees it finish; queries which
+// are never waited for are not accounted for.
+type Budget struct {
+	limit int64
+
+	mu     sync.Mutex
+	spent  int64
+	ledger []Spend
+	index  map[string]int
+}
+
+// NewBudget returns a budget allowing limit bytes to be scanned.
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit, index: map[string]int{}}
+}
+
+// Limit returns the number of bytes the budget allows to be scanned.
+func (b *Budget) Limit() int64 {
+	return b.limit
+}
+
+// Spent returns the number of bytes scanned by the queries recorded.
+func (b *Budget) Spent() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.spent
+}
+
+// Remaining returns the number of bytes which may still be scanned, which
+// is negative once the budget is exceeded.
+func (b *Budget) Remaining() int64 {
+	return b.limit - b.Spent()
+}
+
+// Ledger returns the queries recorded, in the order they finished.
+func (b *Budget) Ledger() []Spend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Spend(nil), b.ledger...)
+}
+
+// check returns a *BudgetError if the budget has been spent.
+func (b *Budget) check() error {
+	if spent := b.Spent(); spent >= b.limit {
+		return &BudgetError{Limit: b.limit, Spent: spent}
+	}
+
+	return nil
+}
+
+// record adds the spend of a query to the ledger, replacing any earlier
+// record of the same query.
+func (b *Budget) record(s Spend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if i, ok := b.index[s.QueryID]; ok {
+		b.spent -= b.ledger[i].DataScannedInBytes
+		b.ledger[i] = s
+	} else {
+		b.index[s.QueryID] = len(b.ledger)
+		b.ledger = append(b.ledger, s)
+	}
+
+	b.spent += s.DataScannedInBytes
+}
+
+// WithQueryLimit returns a copy of the client which stops queries once
+// they have scanned more than limit bytes, as seen when Wait checks their
+// status; Wait then returns a *ScanLimitError. As checks happen every poll
+// interval, a query may scan somewhat more than limit before being stopped.
+//
+// Athena enforces a workgroup's cutoff itself, precisely and whether or
+// not queries are waited for; prefer it where possible. See
+// SetWorkGroupScanLimit.
+func (c Client) WithQueryLimit(limit int64) Client {
+	return c.withOptions(func(o *options) {
+		o.queryLimit = limit
+	})
+}
+
+// WithBudget returns a copy of the client which records the data scanned by
+// its queries in budget, and refuses to start queries with a *BudgetError
+// once the budget is spent. The budget may be shared between clients.
+func (c Client) WithBudget(budget *Budget) Client {
+	return c.withOptions(func(o *options) {
+		o.budget = budget
+	})
+}
+
+// SetWorkGroupScanLimit sets the bytes scanned cutoff per query of the
+// named workgroup, so that Athena cancels queries which scan more than
+// limit bytes. A limit of zero removes the cutoff.
+//
+// This changes the workgroup for all of its users, and requires the
+// athena:UpdateWorkGroup permission.
+func (c Client) SetWorkGroupScanLimit(workgroup string, limit int64) error {
+	update := &athena.WorkGroupConfigurationUpdates{}
+
+	switch {
+	case limit == 0:
+		update.SetRemoveBytesScannedCutoffPerQuery(true)
+	case limit < minScanLimit:
+		return invalidScanLimit
+	default:
+		update.SetBytesScannedCutoffPerQuery(limit)
+	}
+
+	in := (&athena.UpdateWorkGroupInput{}).SetWorkGroup(workgroup).SetConfigurationUpdates(update)
+	_, err := c.api.UpdateWorkGroup(in)
+
+	return err
+}
+
+// account applies the scan limit and budget to the latest status of a query
+// being waited for, returning a *ScanLimitError if the query was stopped.
+func (q Query) account(qe *athena.QueryExecution) error {
+	o := q.options()
+	scanned := statistics(qe.Statistics).DataScannedInBytes
+	state := *qe.Status.State
+
+	if !terminal(state) {
+		if o.queryLimit <= 0 || scanned <= o.queryLimit {
+			return nil
+		}
+
+		if err := q.Stop(); err != nil {
+			return err
+		}
+
+		state = athena.QueryExecutionStateCancelled
+	}
+
+	if o.budget != nil {
+		o.budget.record(Spend{
+			QueryID:            q.id,
+			State:              state,
+			DataScannedInBytes: scanned,
+			Cost:               q.Cost(scanned),
+			Time:               time.Now(),
+		})
+	}
+
+	if !terminal(*qe.Status.State) {
+		return &ScanLimitError{ID: q.id, Limit: o.queryLimit, Scanned: scanned}
+	}
+
+	return nil
+}
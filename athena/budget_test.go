@@ -0,0 +1,146 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestQueryLimit(t *testing.T) {
+	for i, test := range []struct {
+		state   string
+		scanned int64
+		limit   int64
+		stopped []string
+		err     error
+	}{
+		{"RUNNING", 200, 100, []string{"jobid"}, &athena.ScanLimitError{ID: "jobid", Limit: 100, Scanned: 200}},
+		{"SUCCEEDED", 200, 100, nil, nil},
+		{"SUCCEEDED", 50, 100, nil, nil},
+	} {
+		var stopped []string
+
+		mc := mockClient{
+			getQueryExecution:    getQueryExecution{state: test.state, outLocation: "s3://output"},
+			queryExecutionDetail: queryExecutionDetail{statistics: (&aa.QueryExecutionStatistics{}).SetDataScannedInBytes(test.scanned)},
+			listing:              listing{stopped: &stopped},
+		}
+
+		budget := athena.NewBudget(1000)
+		c := athena.NewCustomClient(mc).WithQueryLimit(test.limit).WithBudget(budget)
+
+		_, err := c.CreateQuery("jobid").Wait(context.Background())
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if !reflect.DeepEqual(stopped, test.stopped) {
+			t.Errorf("%d: stopped == %v (want %v)", i, stopped, test.stopped)
+		}
+
+		if spent := budget.Spent(); spent != test.scanned {
+			t.Errorf("%d: Spent() == %d (want %d)", i, spent, test.scanned)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	const id = "jobid"
+
+	mc := mockClient{
+		startQueryExecution:  startQueryExecution{id: id},
+		getQueryExecution:    getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output"},
+		queryExecutionDetail: queryExecutionDetail{statistics: (&aa.QueryExecutionStatistics{}).SetDataScannedInBytes(60 << 20)},
+	}
+
+	budget := athena.NewBudget(100 << 20)
+	c := athena.NewCustomClient(mc).WithBudget(budget)
+
+	run := func() error {
+		q, err := c.DoQuery("database", "query", "s3://output")
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Wait(context.Background())
+		return err
+	}
+
+	if err := run(); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	// waiting again for the same query does not count it twice
+	if _, err := c.CreateQuery(id).Wait(context.Background()); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if remaining := budget.Remaining(); remaining != 40<<20 {
+		t.Errorf("Remaining() == %d (want %d)", remaining, 40<<20)
+	}
+
+	// the budget is not yet spent, so the second query runs and overspends
+	mc.startQueryExecution.id = "jobid2"
+	c = athena.NewCustomClient(mc).WithBudget(budget)
+
+	if err := run(); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	err := run()
+
+	var berr *athena.BudgetError
+	if !errors.As(err, &berr) || berr.Limit != 100<<20 || berr.Spent != 120<<20 {
+		t.Errorf("err == %v (want budget exceeded)", err)
+	}
+
+	ledger := budget.Ledger()
+	if len(ledger) != 2 {
+		t.Fatalf("len(Ledger()) == %d (want 2)", len(ledger))
+	}
+
+	for i, s := range ledger {
+		expectedID := []string{"jobid", "jobid2"}[i]
+		if s.QueryID != expectedID || s.State != "SUCCEEDED" || s.DataScannedInBytes != 60<<20 || s.Cost != athena.Cost(60<<20, athena.DefaultPricePerTB) {
+			t.Errorf("Ledger()[%d] == %+v", i, s)
+		}
+	}
+}
+
+func TestSetWorkGroupScanLimit(t *testing.T) {
+	for i, test := range []struct {
+		limit    int64
+		expected *aa.WorkGroupConfigurationUpdates
+		err      error
+	}{
+		{1 << 40, &aa.WorkGroupConfigurationUpdates{BytesScannedCutoffPerQuery: aws.Int64(1 << 40)}, nil},
+		{0, &aa.WorkGroupConfigurationUpdates{RemoveBytesScannedCutoffPerQuery: aws.Bool(true)}, nil},
+		{1 << 20, nil, athena.ErrInvalidScanLimit},
+	} {
+		var updated []*aa.UpdateWorkGroupInput
+
+		c := athena.NewCustomClient(mockClient{listing: listing{updated: &updated}})
+
+		if err := c.SetWorkGroupScanLimit("adhoc", test.limit); err != test.err {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if test.expected == nil {
+			if len(updated) != 0 {
+				t.Errorf("%d: updated == %v (want none)", i, updated)
+			}
+
+			continue
+		}
+
+		expected := []*aa.UpdateWorkGroupInput{{WorkGroup: aws.String("adhoc"), ConfigurationUpdates: test.expected}}
+		if !reflect.DeepEqual(updated, expected) {
+			t.Errorf("%d: updated == %v (want %v)", i, updated, expected)
+		}
+	}
+}
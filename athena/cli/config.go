@@ -0,0 +1,475 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+// setting is a value which may be given by a flag, an environment variable
+// or a profile in the config file, in that order of precedence.
+type setting struct {
+	// name is the key of the setting in config file profiles.
+	name string
+
+	// flag is the name of the flag setting it.
+	flag string
+
+	// env is the environment variable setting it.
+	env string
+
+	// def is the default value.
+	def string
+
+	usage string
+
+	// secret is set for values config show shouldn't print.
+	secret bool
+}
+
+// settings are the configurable settings, in the order config show lists
+// them. Settings without a flag are only given by the environment or a
+// profile, so that they aren't seen in the arguments of the process.
+var settings = []setting{
+	{"database", "database", "ATHENA_DATABASE", "", "database to query", false},
+	{"workgroup", "workgroup", "ATHENA_WORKGROUP", "", "workgroup to run queries in (default primary)", false},
+	{"output", "output", "ATHENA_OUTPUT", "", "S3 URL where Athena stores results", false},
+	{"encryption", "encryption", "ATHENA_ENCRYPTION", "", "encryption of results: SSE_S3, SSE_KMS or CSE_KMS", false},
+	{"kms_key", "kms-key", "ATHENA_KMS_KEY", "", "KMS key to encrypt results with", true},
+	{"poll", "poll", "ATHENA_POLL", "1s", "interval between checks of query status", false},
+	{"timeout", "timeout", "ATHENA_TIMEOUT", "5s", "time to wait for a query to complete", false},
+	{"price_per_tb", "price-per-tb", "ATHENA_PRICE_PER_TB", strconv.FormatFloat(athena.DefaultPricePerTB, 'f', -1, 64), "price in US dollars per TB scanned, to compute costs", false},
+	{"query_limit", "query-limit", "ATHENA_QUERY_LIMIT", "", "stop queries which scan more than this, e.g. 100GB", false},
+	{"session_budget", "session-budget", "ATHENA_SESSION_BUDGET", "", "refuse to start queries once the session has scanned this, e.g. 1TB", false},
+	{"audit_log", "audit-log", "ATHENA_AUDIT_LOG", "", "append a JSON record of each query to this file", false},
+	{"audit_redact", "audit-redact", "ATHENA_AUDIT_REDACT", "false", "redact string literals from queries in the audit log", false},
+	{"log_level", "log-level", "ATHENA_LOG_LEVEL", "warn", "log messages of at least this level to stderr: debug, info, warn or error", false},
+	{"log_format", "log-format", "ATHENA_LOG_FORMAT", "text", "format of log messages: text or json", false},
+	{"log_queries", "log-queries", "ATHENA_LOG_QUERIES", "false", "include the text of queries in log messages", false},
+	{"log_query_length", "log-query-length", "ATHENA_LOG_QUERY_LENGTH", "200", "truncate queries logged to this many characters, or 0 for no limit", false},
+	{"format", "format", "ATHENA_FORMAT", string(athena.OutputJSON), "output format: " + outputFormats(), false},
+	{"aws_profile", "aws-profile", "AWS_PROFILE", "", "AWS shared configuration profile", false},
+	{"region", "region", "AWS_REGION", "", "AWS region", false},
+	{"endpoint", "endpoint", "ATHENA_ENDPOINT", "", "URL of the Athena API, e.g. a local stand-in for testing", false},
+	{"role_arn", "role-arn", "ATHENA_ROLE_ARN", "", "IAM role to assume, e.g. in another account", false},
+	{"external_id", "external-id", "ATHENA_EXTERNAL_ID", "", "external ID required to assume the role", false},
+	{"role_session_name", "role-session-name", "ATHENA_ROLE_SESSION_NAME", "", "name of the role session, as seen in CloudTrail", false},
+	{"role_duration", "role-duration", "ATHENA_ROLE_DURATION", "", "lifetime of the role's credentials (default 15m)", false},
+	{"mfa_serial", "mfa-serial", "ATHENA_MFA_SERIAL", "", "MFA device required to assume the role; its code is read from the terminal", false},
+	{"mfa_code", "", "ATHENA_MFA_CODE", "", "code of the MFA device for the first time the role is assumed, rather than reading it from the terminal", true},
+	{"account", "account", "ATHENA_ACCOUNT", "", "check queries run in this AWS account", false},
+}
+
+// Settings used by all commands which call AWS.
+var awsSettings = []string{"aws_profile", "region", "endpoint", "role_arn", "external_id", "role_session_name", "role_duration", "mfa_serial", "mfa_code", "account", "log_level", "log_format", "log_queries", "log_query_length"}
+
+// value is the resolved value of a setting.
+type value struct {
+	value string
+
+	// source describes where the value came from.
+	source string
+}
+
+// config holds the settings of a command, resolved from its flags, the
+// environment, the config file and defaults.
+type config struct {
+	fs      *flag.FlagSet
+	flags   map[string]*string
+	file    string
+	profile string
+
+	// fileSource and profileSource describe where the config file and
+	// profile were chosen.
+	fileSource    string
+	profileSource string
+
+	values map[string]value
+}
+
+// addConfigFlags adds the -config and -profile flags, and flags for the
+// named settings, to fs.
+func addConfigFlags(fs *flag.FlagSet, names ...string) *config {
+	c := config{fs: fs, flags: map[string]*string{}}
+
+	fs.StringVar(&c.file, "config", "", "read profiles from `FILE` (default "+defaultConfigFile()+")")
+	fs.StringVar(&c.profile, "profile", "", "use the settings of the named `PROFILE` in the config file")
+
+	c.addFlags(names...)
+
+	return &c
+}
+
+// addFlags adds flags for the named settings.
+func (c *config) addFlags(names ...string) {
+	for _, name := range names {
+		for _, s := range settings {
+			if s.name == name && s.flag != "" {
+				help := s.usage
+				if s.def != "" {
+					help += " (default " + s.def + ")"
+				}
+
+				c.flags[name] = c.fs.String(s.flag, "", help)
+			}
+		}
+	}
+}
+
+// defaultConfigFile returns the path of the config file used if neither
+// -config nor ATHENA_CONFIG is given.
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "athena-cli", "config.yaml")
+}
+
+// load resolves the settings, once the flags have been parsed.
+func (c *config) load() error {
+	set := map[string]bool{}
+	c.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	c.fileSource = "flag -config"
+	if !set["config"] {
+		c.file, c.fileSource = os.Getenv("ATHENA_CONFIG"), "env ATHENA_CONFIG"
+	}
+
+	required := true
+	if c.file == "" {
+		c.file, c.fileSource, required = defaultConfigFile(), "default", false
+	}
+
+	profiles, defaultProfile, err := readConfig(c.file)
+	if os.IsNotExist(err) && !required {
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.profileSource = "flag -profile"
+	if !set["profile"] {
+		c.profile, c.profileSource = os.Getenv("ATHENA_PROFILE"), "env ATHENA_PROFILE"
+	}
+
+	if c.profile == "" {
+		c.profile, c.profileSource = defaultProfile, "config default_profile"
+	}
+
+	if c.profile == "" {
+		c.profile, c.profileSource = "default", "default"
+	}
+
+	profile, ok := profiles[c.profile]
+	if !ok && c.profileSource != "default" {
+		return fmt.Errorf("profile %s not found in %s", c.profile, c.file)
+	}
+
+	c.values = map[string]value{}
+
+	for _, s := range settings {
+		v := value{s.def, "default"}
+
+		if f, ok := c.flags[s.name]; ok && set[s.flag] {
+			v = value{*f, "flag -" + s.flag}
+		} else if e := os.Getenv(s.env); e != "" {
+			v = value{e, "env " + s.env}
+		} else if p, ok := profile[s.name]; ok {
+			v = value{p, "profile " + c.profile}
+		}
+
+		c.values[s.name] = v
+	}
+
+	return c.validate()
+}
+
+// validate checks the values of settings which have a fixed form.
+func (c *config) validate() error {
+	for _, name := range []string{"poll", "timeout"} {
+		if _, err := time.ParseDuration(c.get(name)); err != nil {
+			return fmt.Errorf("%s (from %s): %v", name, c.values[name].source, err)
+		}
+	}
+
+	if d := c.get("role_duration"); d != "" {
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("role_duration (from %s): %v", c.values["role_duration"].source, err)
+		}
+	}
+
+	if p, err := strconv.ParseFloat(c.get("price_per_tb"), 64); err != nil || p <= 0 {
+		return fmt.Errorf("price_per_tb (from %s): must be a positive number", c.values["price_per_tb"].source)
+	}
+
+	if _, err := strconv.ParseBool(c.get("audit_redact")); err != nil {
+		return fmt.Errorf("audit_redact (from %s): must be true or false", c.values["audit_redact"].source)
+	}
+
+	if _, err := athena.ParseLevel(c.get("log_level")); err != nil {
+		return fmt.Errorf("log_level (from %s): %v", c.values["log_level"].source, err)
+	}
+
+	if f := c.get("log_format"); f != "text" && f != "json" {
+		return fmt.Errorf("log_format (from %s): must be text or json", c.values["log_format"].source)
+	}
+
+	if _, err := strconv.ParseBool(c.get("log_queries")); err != nil {
+		return fmt.Errorf("log_queries (from %s): must be true or false", c.values["log_queries"].source)
+	}
+
+	if n, err := strconv.Atoi(c.get("log_query_length")); err != nil || n < 0 {
+		return fmt.Errorf("log_query_length (from %s): must be a non-negative integer", c.values["log_query_length"].source)
+	}
+
+	for _, name := range []string{"query_limit", "session_budget"} {
+		if _, err := parseBytes(c.get(name)); err != nil {
+			return fmt.Errorf("%s (from %s): %v", name, c.values[name].source, err)
+		}
+	}
+
+	for _, f := range athena.OutputFormats {
+		if c.get("format") == string(f) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("format (from %s): unknown output format %q", c.values["format"].source, c.get("format"))
+}
+
+// get returns the value of the named setting.
+func (c *config) get(name string) string {
+	return c.values[name].value
+}
+
+// set overrides the value of the named setting, e.g. with an argument.
+func (c *config) set(name, v, source string) {
+	c.values[name] = value{v, source}
+}
+
+// duration returns the value of the named setting as a duration; the
+// value has been validated by load.
+func (c *config) duration(name string) time.Duration {
+	d, _ := time.ParseDuration(c.get(name))
+	return d
+}
+
+// float returns the value of the named setting as a number; the value has
+// been validated by load.
+func (c *config) float(name string) float64 {
+	f, _ := strconv.ParseFloat(c.get(name), 64)
+	return f
+}
+
+// bool returns the value of the named setting as a boolean; the value has
+// been validated by load.
+func (c *config) bool(name string) bool {
+	b, _ := strconv.ParseBool(c.get(name))
+	return b
+}
+
+// int returns the value of the named setting as an integer; the value has
+// been validated by load.
+func (c *config) int(name string) int {
+	n, _ := strconv.Atoi(c.get(name))
+	return n
+}
+
+// bytes returns the value of the named setting as a number of bytes; the
+// value has been validated by load.
+func (c *config) bytes(name string) int64 {
+	n, _ := parseBytes(c.get(name))
+	return n
+}
+
+// parseBytes parses an amount of data such as 512MB or 1.5TB, where units
+// are powers of 1024 as Athena bills them. A plain number is in bytes, and
+// an empty string is zero.
+func parseBytes(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		n      float64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	number, unit := strings.ToUpper(strings.TrimSpace(s)), 1.0
+	for _, u := range units {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, u.suffix)), u.n
+			break
+		}
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid amount of data %q", s)
+	}
+
+	return int64(f * unit), nil
+}
+
+// require returns an error if any of the named settings are empty.
+func (c *config) require(names ...string) error {
+	var missing []string
+
+	for _, name := range names {
+		if c.get(name) != "" {
+			continue
+		}
+
+		for _, s := range settings {
+			if s.name == name {
+				missing = append(missing, fmt.Sprintf("%s (specify -%s, %s or set it in a profile)", name, s.flag, s.env))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.New("missing " + strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// readConfig reads the profiles from a config file, in YAML (see parseYAML)
+// or, if the file has a .json extension, JSON:
+//
+//	default_profile: dev
+//	profiles:
+//	  dev:
+//	    database: events_dev
+//	    output: s3://query-results-dev/
+//
+// The name of the default profile is returned too.
+func readConfig(file string) (map[string]map[string]string, string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var doc map[string]interface{}
+
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		err = json.Unmarshal(b, &doc)
+	} else {
+		doc, err = parseYAML(string(b))
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", file, err)
+	}
+
+	defaultProfile, _ := doc["default_profile"].(string)
+
+	raw, _ := doc["profiles"].(map[string]interface{})
+	profiles := make(map[string]map[string]string, len(raw))
+
+	for name, p := range raw {
+		m, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("%s: profile %s is not a mapping", file, name)
+		}
+
+		profile := make(map[string]string, len(m))
+		for k, v := range m {
+			known := false
+			for _, s := range settings {
+				known = known || s.name == k
+			}
+
+			if !known {
+				return nil, "", fmt.Errorf("%s: profile %s: unknown setting %s", file, name, k)
+			}
+
+			profile[k] = fmt.Sprint(v)
+		}
+
+		profiles[name] = profile
+	}
+
+	return profiles, defaultProfile, nil
+}
+
+// outputFormats returns the names of the output formats, for usage messages.
+func outputFormats() string {
+	formats := make([]string, len(athena.OutputFormats))
+	for i, f := range athena.OutputFormats {
+		formats[i] = string(f)
+	}
+
+	return strings.Join(formats, ", ")
+}
+
+func configCommand(name string, args []string) int {
+	if len(args) == 0 || args[0] != "show" {
+		newFlagSet(name).Usage()
+		return exitUsage
+	}
+
+	fs := newFlagSet(name)
+	names := make([]string, len(settings))
+	for i, s := range settings {
+		names[i] = s.name
+	}
+
+	cfg := addConfigFlags(fs, names...)
+
+	if code, ok := parse(fs, args[1:], 0); !ok {
+		return code
+	}
+
+	if err := cfg.load(); err != nil {
+		return fail("invalid configuration", err)
+	}
+
+	if err := athena.WriteTable(os.Stdout, cfg.show()); err != nil {
+		return fail("unable to write output", err)
+	}
+
+	return exitOK
+}
+
+// show returns the settings, and where each came from, as a table. The
+// values of secret settings are masked.
+func (c *config) show() athena.Result {
+	r := athena.Result{
+		Columns: []athena.Column{{Name: "setting"}, {Name: "value"}, {Name: "source"}},
+		Rows: []athena.Row{
+			{"config", c.file, c.fileSource},
+			{"profile", c.profile, c.profileSource},
+		},
+	}
+
+	for _, s := range settings {
+		v := c.values[s.name]
+		if s.secret && v.value != "" {
+			v.value = "********"
+		}
+
+		r.Rows = append(r.Rows, athena.Row{s.name, v.value, v.source})
+	}
+
+	return r
+}
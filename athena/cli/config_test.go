@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file to a temporary directory, returning its
+// path and a function removing it.
+func writeConfig(t *testing.T, name, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "athena-cli")
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// setenv sets the environment variables, returning a function restoring
+// their previous values.
+func setenv(vars map[string]string) func() {
+	previous := map[string]*string{}
+
+	for k, v := range vars {
+		if p, ok := os.LookupEnv(k); ok {
+			previous[k] = &p
+		} else {
+			previous[k] = nil
+		}
+
+		os.Setenv(k, v)
+	}
+
+	return func() {
+		for k, p := range previous {
+			if p == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *p)
+			}
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path, cleanup := writeConfig(t, "config.yaml", `
+default_profile: dev
+profiles:
+  dev:
+    database: profile_db
+    workgroup: profile_wg
+    output: s3://profile/
+    poll: 3s
+  prod:
+    database: prod_db
+`)
+	defer cleanup()
+
+	defer setenv(map[string]string{
+		"ATHENA_PROFILE":   "",
+		"ATHENA_DATABASE":  "env_db",
+		"ATHENA_WORKGROUP": "env_wg",
+		"ATHENA_OUTPUT":    "",
+		"ATHENA_POLL":      "",
+		"ATHENA_TIMEOUT":   "",
+	})()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := addConfigFlags(fs, "database", "workgroup", "output", "poll", "timeout")
+
+	if err := fs.Parse([]string{"-config", path, "-database", "flag_db"}); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if err := cfg.load(); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	for _, test := range []struct {
+		name, value, source string
+	}{
+		{"database", "flag_db", "flag -database"},
+		{"workgroup", "env_wg", "env ATHENA_WORKGROUP"},
+		{"output", "s3://profile/", "profile dev"},
+		{"poll", "3s", "profile dev"},
+		{"timeout", "5s", "default"},
+	} {
+		if v := cfg.values[test.name]; v.value != test.value || v.source != test.source {
+			t.Errorf("%s == %q from %s (want %q from %s)", test.name, v.value, v.source, test.value, test.source)
+		}
+	}
+
+	if cfg.profile != "dev" || cfg.profileSource != "config default_profile" {
+		t.Errorf("profile == %s from %s (want dev from config default_profile)", cfg.profile, cfg.profileSource)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		id, config string
+		args       []string
+		err        string
+	}{
+		{
+			id:     "unknown profile",
+			config: "profiles:\n  dev:\n    database: db\n",
+			args:   []string{"-profile", "prod"},
+			err:    "profile prod not found in ",
+		},
+		{
+			id:     "unknown setting",
+			config: "profiles:\n  dev:\n    databse: db\n",
+			err:    "profile dev: unknown setting databse",
+		},
+		{
+			id:     "malformed",
+			config: "profiles:\n  dev\n",
+			err:    "line 2: expected key: value",
+		},
+		{
+			id:     "invalid duration",
+			config: "profiles:\n  default:\n    poll: soon\n",
+			err:    "poll (from profile default): time: invalid duration",
+		},
+		{
+			id:     "zero price",
+			config: "profiles:\n  default:\n    price_per_tb: 0\n",
+			err:    "price_per_tb (from profile default): must be a positive number",
+		},
+		{
+			id:     "invalid amount of data",
+			config: "profiles:\n  default:\n    query_limit: 10XB\n",
+			err:    `query_limit (from profile default): invalid amount of data "10XB"`,
+		},
+	} {
+		t.Run(test.id, func(tt *testing.T) {
+			path, cleanup := writeConfig(tt, "config.yaml", test.config)
+			defer cleanup()
+
+			defer setenv(map[string]string{"ATHENA_PROFILE": "", "ATHENA_POLL": "", "ATHENA_QUERY_LIMIT": "", "ATHENA_PRICE_PER_TB": ""})()
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			cfg := addConfigFlags(fs, "database")
+
+			if err := fs.Parse(append([]string{"-config", path}, test.args...)); err != nil {
+				tt.Fatalf("err == %v (want nil)", err)
+			}
+
+			err := cfg.load()
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				tt.Errorf("err == %v (want containing %q)", err, test.err)
+			}
+		})
+	}
+}
+
+func TestConfigShowSecrets(t *testing.T) {
+	path, cleanup := writeConfig(t, "config.yaml", "profiles:\n  default:\n    database: db\n    mfa_code: 123456\n    kms_key: key\n")
+	defer cleanup()
+
+	defer setenv(map[string]string{"ATHENA_PROFILE": "", "ATHENA_DATABASE": "", "ATHENA_MFA_CODE": "", "ATHENA_KMS_KEY": ""})()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := addConfigFlags(fs, "mfa_code", "kms_key")
+
+	if fs.Lookup("mfa-code") != nil {
+		t.Errorf("-mfa-code is a flag (want env and profile only)")
+	}
+
+	if err := fs.Parse([]string{"-config", path}); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if err := cfg.load(); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if code := cfg.get("mfa_code"); code != "123456" {
+		t.Errorf("mfa_code == %q (want 123456)", code)
+	}
+
+	for _, row := range cfg.show().Rows {
+		switch row[0] {
+		case "mfa_code", "kms_key":
+			if row[1] != "********" {
+				t.Errorf("%s shown as %q (want masked)", row[0], row[1])
+			}
+		case "database":
+			if row[1] != "db" {
+				t.Errorf("%s shown as %q (want db)", row[0], row[1])
+			}
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected int64
+		err      bool
+	}{
+		{"", 0, false},
+		{"512", 512, false},
+		{"10B", 10, false},
+		{"1KB", 1 << 10, false},
+		{"512MB", 512 << 20, false},
+		{"1.5 GB", 3 << 29, false},
+		{"2tb", 2 << 40, false},
+		{"100XB", 0, true},
+		{"-1GB", 0, true},
+		{"GB", 0, true},
+	} {
+		n, err := parseBytes(test.s)
+
+		if n != test.expected || (err != nil) != test.err {
+			t.Errorf("parseBytes(%q) == %d, %v (want %d, error %v)", test.s, n, err, test.expected, test.err)
+		}
+	}
+}
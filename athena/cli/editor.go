@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode"
+)
+
+// errInterrupted is returned by an editor when Ctrl-C is pressed.
+var errInterrupted = errors.New("interrupted")
+
+// maxHistory is the number of lines an editor can recall.
+const maxHistory = 500
+
+// Keys read by an editor
+const (
+	keyCtrlA     = 'A' - '@'
+	keyCtrlB     = 'B' - '@'
+	keyCtrlC     = 'C' - '@'
+	keyCtrlD     = 'D' - '@'
+	keyCtrlE     = 'E' - '@'
+	keyCtrlF     = 'F' - '@'
+	keyCtrlH     = 'H' - '@'
+	keyCtrlK     = 'K' - '@'
+	keyCtrlN     = 'N' - '@'
+	keyCtrlP     = 'P' - '@'
+	keyCtrlU     = 'U' - '@'
+	keyCtrlW     = 'W' - '@'
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// lineReader prompts for and reads a line of input, returning io.EOF at its
+// end and errInterrupted if Ctrl-C is read.
+type lineReader func(prompt string) (string, error)
+
+// newLineReader returns a lineReader for f: an editor if f is a terminal,
+// recalling history, otherwise one reading plain lines.
+func newLineReader(f *os.File, history []string) lineReader {
+	if fd := int(f.Fd()); editable(fd) {
+		return newEditor(fd, f, os.Stderr, history).readLine
+	}
+
+	s := bufio.NewScanner(f)
+
+	return func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return "", err
+			}
+
+			return "", io.EOF
+		}
+
+		return s.Text(), nil
+	}
+}
+
+// editor reads lines from a terminal, in raw mode while reading, so that
+// they can be edited with the usual keys: the arrow keys, Home, End,
+// Backspace and Delete, and Ctrl-A, E, B, F, K, U and W. Earlier lines are
+// recalled with the up and down arrows, or Ctrl-P and N.
+//
+// Characters are assumed to be one column wide, and lines to fit the
+// width of the terminal.
+type editor struct {
+	// fd is the terminal, or -1 if it shouldn't be put in raw mode.
+	fd  int
+	in  *bufio.Reader
+	out io.Writer
+
+	// history holds the lines which can be recalled, oldest first.
+	history []string
+}
+
+// newEditor returns an editor reading from in, the terminal fd, and
+// writing to out, which recalls history before the lines it reads.
+func newEditor(fd int, in io.Reader, out io.Writer, history []string) *editor {
+	e := &editor{fd: fd, in: bufio.NewReader(in), out: out}
+
+	for _, line := range history {
+		e.add(line)
+	}
+
+	return e
+}
+
+// add adds line to the history, unless it is empty or the same as the
+// last line.
+func (e *editor) add(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// edit is the state of a line being edited.
+type edit struct {
+	line []rune
+	pos  int
+
+	// recalled is the index in history of the line shown, or the length
+	// of history for the line being entered, which is kept in entered
+	// while others are shown.
+	recalled int
+	entered  []rune
+}
+
+// readLine writes prompt and reads a line, which is added to the history.
+// It returns io.EOF if Ctrl-D is pressed on an empty line, and
+// errInterrupted if Ctrl-C is pressed.
+func (e *editor) readLine(prompt string) (string, error) {
+	if e.fd >= 0 {
+		state, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+
+		defer restore(e.fd, state)
+	}
+
+	fmt.Fprint(e.out, prompt)
+
+	ed := edit{recalled: len(e.history)}
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+
+			line := string(ed.line)
+			e.add(line)
+
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(ed.line) == 0 {
+				return "", io.EOF
+			}
+
+			ed.delete()
+		case keyBackspace, keyCtrlH:
+			if ed.pos > 0 {
+				ed.pos--
+				ed.delete()
+			}
+		case keyCtrlA:
+			ed.pos = 0
+		case keyCtrlE:
+			ed.pos = len(ed.line)
+		case keyCtrlB:
+			ed.move(-1)
+		case keyCtrlF:
+			ed.move(1)
+		case keyCtrlK:
+			ed.line = ed.line[:ed.pos]
+		case keyCtrlU:
+			ed.line = append([]rune(nil), ed.line[ed.pos:]...)
+			ed.pos = 0
+		case keyCtrlW:
+			ed.deleteWord()
+		case keyCtrlP:
+			ed.recall(e.history, -1)
+		case keyCtrlN:
+			ed.recall(e.history, 1)
+		case keyEscape:
+			if err := e.escape(&ed); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				ed.insert(r)
+			}
+		}
+
+		e.refresh(prompt, ed)
+	}
+}
+
+// escape reads the rest of an escape sequence, e.g. for an arrow key, and
+// applies it to ed. Unknown sequences are ignored.
+func (e *editor) escape(ed *edit) error {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return err
+	}
+
+	// the sequence ends with a letter or ~, after any parameters
+	var seq []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+
+		seq = append(seq, r)
+		if r >= '@' && r <= '~' {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		ed.recall(e.history, -1)
+	case "B":
+		ed.recall(e.history, 1)
+	case "C":
+		ed.move(1)
+	case "D":
+		ed.move(-1)
+	case "H", "1~", "7~":
+		ed.pos = 0
+	case "F", "4~", "8~":
+		ed.pos = len(ed.line)
+	case "3~":
+		ed.delete()
+	}
+
+	return nil
+}
+
+// refresh redraws the line, clearing the rest of it, and places the cursor.
+func (e *editor) refresh(prompt string, ed edit) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(ed.line))
+
+	if n := len(ed.line) - ed.pos; n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n)
+	}
+}
+
+// insert inserts r at the cursor.
+func (ed *edit) insert(r rune) {
+	ed.line = append(ed.line, 0)
+	copy(ed.line[ed.pos+1:], ed.line[ed.pos:])
+	ed.line[ed.pos] = r
+	ed.pos++
+}
+
+// delete deletes the character at the cursor.
+func (ed *edit) delete() {
+	if ed.pos < len(ed.line) {
+		ed.line = append(ed.line[:ed.pos], ed.line[ed.pos+1:]...)
+	}
+}
+
+// deleteWord deletes the word before the cursor, and the spaces after it.
+func (ed *edit) deleteWord() {
+	start := ed.pos
+	for start > 0 && unicode.IsSpace(ed.line[start-1]) {
+		start--
+	}
+
+	for start > 0 && !unicode.IsSpace(ed.line[start-1]) {
+		start--
+	}
+
+	ed.line = append(ed.line[:start], ed.line[ed.pos:]...)
+	ed.pos = start
+}
+
+// move moves the cursor by n characters, within the line.
+func (ed *edit) move(n int) {
+	ed.pos += n
+
+	if ed.pos < 0 {
+		ed.pos = 0
+	}
+
+	if ed.pos > len(ed.line) {
+		ed.pos = len(ed.line)
+	}
+}
+
+// recall replaces the line with the one n lines later in history, or the
+// line being entered after the last, placing the cursor at its end.
+func (ed *edit) recall(history []string, n int) {
+	i := ed.recalled + n
+	if i < 0 || i > len(history) {
+		return
+	}
+
+	if ed.recalled == len(history) {
+		ed.entered = ed.line
+	}
+
+	if i == len(history) {
+		ed.line = ed.entered
+	} else {
+		ed.line = []rune(history[i])
+	}
+
+	ed.recalled = i
+	ed.pos = len(ed.line)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	const (
+		up    = "\x1b[A"
+		down  = "\x1b[B"
+		left  = "\x1b[D"
+		right = "\x1b[C"
+		home  = "\x1b[H"
+		del   = "\x1b[3~"
+	)
+
+	for _, test := range []struct {
+		id       string
+		keys     string
+		history  []string
+		expected []string
+		err      error
+	}{
+		{id: "plain", keys: "SELECT 1;\r", expected: []string{"SELECT 1;"}},
+		{id: "backspace", keys: "SELECT 12\x7f;\r", expected: []string{"SELECT 1;"}},
+		{id: "insert", keys: "SELECT 1" + left + left + "x" + right + right + ";\r", expected: []string{"SELECTx 1;"}},
+		{id: "home and delete", keys: "xSELECT 1" + home + del + "\x05;\r", expected: []string{"SELECT 1;"}},
+		{id: "kill", keys: "SELECT 1 FROM t" + left + left + "\x0b;\r" + "a b\x15c\r", expected: []string{"SELECT 1 FROM;", "c"}},
+		{id: "delete word", keys: "SELECT 1 FROM t  \x17x\r", expected: []string{"SELECT 1 FROM x"}},
+		{id: "unicode", keys: "'héllo'" + left + "\x7f\r", expected: []string{"'héll'"}},
+		{
+			id:       "recall",
+			keys:     "SELECT 2;\r" + up + up + "\r" + up + up + down + "\r",
+			history:  []string{"SELECT 1;"},
+			expected: []string{"SELECT 2;", "SELECT 1;", "SELECT 1;"},
+		},
+		{
+			id:       "recall keeps the line entered",
+			keys:     "SELECT" + up + down + " 3;\r",
+			history:  []string{"SELECT 1;"},
+			expected: []string{"SELECT 3;"},
+		},
+		{id: "ctrl-c", keys: "SELECT\x03", err: errInterrupted},
+		{id: "ctrl-d", keys: "\x04", err: io.EOF},
+		{id: "ctrl-d deletes", keys: "SELECT 1" + left + "\x04\r", expected: []string{"SELECT "}},
+		{id: "end of input", keys: "SELECT", err: io.EOF},
+	} {
+		t.Run(test.id, func(tt *testing.T) {
+			e := newEditor(-1, strings.NewReader(test.keys), ioutil.Discard, test.history)
+
+			var lines []string
+			var err error
+
+			for {
+				var line string
+				if line, err = e.readLine("> "); err != nil {
+					break
+				}
+
+				lines = append(lines, line)
+			}
+
+			if strings.Join(lines, "\n") != strings.Join(test.expected, "\n") {
+				tt.Errorf("lines == %q (want %q)", lines, test.expected)
+			}
+
+			if test.err == nil {
+				test.err = io.EOF
+			}
+
+			if err != test.err {
+				tt.Errorf("err == %v (want %v)", err, test.err)
+			}
+		})
+	}
+}
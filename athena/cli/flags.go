@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+)
+
+// newFlagSet returns a flag set for the named command, whose usage
+// message describes the command's arguments.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s %s %s\n\n%s\n", path.Base(os.Args[0]), name, commands[name].usage, commands[name].summary)
+
+		var n int
+		fs.VisitAll(func(*flag.Flag) { n++ })
+
+		if n > 0 {
+			fmt.Fprint(os.Stderr, "\nFlags:\n\n")
+			fs.PrintDefaults()
+		}
+	}
+
+	return fs
+}
+
+// parse parses args with fs, requiring exactly nargs positional arguments,
+// or one of several numbers of them. The exit code to use is returned if
+// parsing fails.
+func parse(fs *flag.FlagSet, args []string, nargs ...int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+
+		return exitUsage, false
+	}
+
+	for _, n := range nargs {
+		if fs.NArg() == n {
+			return exitOK, true
+		}
+	}
+
+	fs.Usage()
+
+	return exitUsage, false
+}
+
+// printJSON writes v to stdout as JSON.
+func printJSON(v interface{}) int {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fail("unable to encode output", err)
+	}
+
+	fmt.Println(string(j))
+
+	return exitOK
+}
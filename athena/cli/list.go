@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func historyCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, append(awsSettings, "workgroup")...)
+	max := fs.Int("max", 50, "maximum number of queries to list")
+
+	if code, ok := parse(fs, args, 0); !ok {
+		return code
+	}
+
+	if err := cfg.load(); err != nil {
+		return fail("invalid configuration", err)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail("unable to create client", err)
+	}
+
+	executions, err := client.History(cfg.get("workgroup"), *max)
+
+	var uerr *athena.UnprocessedError
+	if errors.As(err, &uerr) {
+		for _, u := range uerr.Executions {
+			newLogger(cfg).Log(athena.LevelWarn, "query not described", map[string]interface{}{
+				"query_id":   u.ID,
+				"error_code": u.ErrorCode,
+				"error":      u.ErrorMessage,
+			})
+		}
+	} else if err != nil {
+		return fail("error listing queries", err)
+	}
+
+	return printJSON(executions)
+}
+
+func namedCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, append(awsSettings, "workgroup")...)
+
+	if code, ok := parse(fs, args, 0); !ok {
+		return code
+	}
+
+	if err := cfg.load(); err != nil {
+		return fail("invalid configuration", err)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail("unable to create client", err)
+	}
+
+	queries, err := client.NamedQueries(cfg.get("workgroup"))
+	if err != nil {
+		return fail("error listing named queries", err)
+	}
+
+	return printJSON(queries)
+}
+
+func workgroupsCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, awsSettings...)
+
+	if code, ok := parse(fs, args, 0); !ok {
+		return code
+	}
+
+	if err := cfg.load(); err != nil {
+		return fail("invalid configuration", err)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail("unable to create client", err)
+	}
+
+	workgroups, err := client.WorkGroups()
+	if err != nil {
+		return fail("error listing workgroups", err)
+	}
+
+	return printJSON(workgroups)
+}
@@ -1,128 +1,332 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"os"
 	"path"
-	"time"
+	"sort"
+	"strings"
 
 	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
-var poll time.Duration
-var timeout time.Duration
-var skipHeaderRow bool
+// Exit codes, so that shell pipelines can branch on the outcome.
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitUsage     = 2
+	exitFailed    = 3
+	exitCancelled = 4
+	exitTimeout   = 5
+)
+
+// command is a subcommand of the CLI.
+type command struct {
+	// usage describes the arguments of the command.
+	usage string
+
+	// summary is a one line description of the command.
+	summary string
+
+	// run runs the command with its arguments, returning the exit code.
+	run func(name string, args []string) int
+}
 
+var commands map[string]command
+
+// commands refer to the command table for their usage, so it must be
+// populated at init rather than statically initialised.
 func init() {
-	const (
-		defaultPoll    = 1 * time.Second
-		defaultTimeout = 5 * time.Second
-	)
-
-	flag.DurationVar(&poll, "poll", defaultPoll, "specify polling interval in milliseconds")
-	flag.DurationVar(&timeout, "timeout", defaultTimeout, "specify timeout in milliseconds")
-	flag.BoolVar(&skipHeaderRow, "skip-header-row", false, "skip header row containing column names")
+	commands = map[string]command{
+		"run":        {"[FLAG...] [DATABASE_NAME] {QUERY_STATEMENT | -f FILE} [S3_OUTPUT_URL]", "run a query and print its results", runCommand},
+		"submit":     {"[FLAG...] [DATABASE_NAME] {QUERY_STATEMENT | -f FILE} [S3_OUTPUT_URL]", "start a query and print its execution ID", submitCommand},
+		"status":     {"QUERY_ID", "print the status of a query", statusCommand},
+		"results":    {"[FLAG...] QUERY_ID", "print the results of a completed query", resultsCommand},
+		"stop":       {"QUERY_ID", "cancel a query", stopCommand},
+		"history":    {"[FLAG...]", "list recent queries", historyCommand},
+		"named":      {"[FLAG...]", "list named queries", namedCommand},
+		"workgroups": {"", "list workgroups", workgroupsCommand},
+		"config":     {"show [FLAG...]", "show the settings and where each came from", configCommand},
+		"repl":       {"[FLAG...]", "run statements interactively", replCommand},
+	}
+}
+
+func usage() {
+	name := path.Base(os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "%s COMMAND [ARG...]\n\nCommands:\n\n", name)
+
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s%s\n", n, commands[n].summary)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun '%s COMMAND -h' for help with a command.\n", name)
+	fmt.Fprintf(os.Stderr, "\nExit codes: %d success, %d error, %d usage error, %d query failed, %d query cancelled, %d timeout\n",
+		exitOK, exitError, exitUsage, exitFailed, exitCancelled, exitTimeout)
 }
 
 func main() {
-	flag.Usage = func() {
-		usage := fmt.Sprintf("%s [FLAG...] DATABASE_NAME QUERY_STATEMENT S3_OUTPUT_URL\n\nFlags:\n\n", path.Base(os.Args[0]))
-		fmt.Fprint(os.Stderr, usage)
-		flag.PrintDefaults()
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+// dispatch runs the command named by the first of args, returning the exit
+// code.
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
 	}
 
-	flag.Parse()
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage()
+		return exitOK
+	}
 
-	remaining := flag.Args()
+	cmd, ok := commands[args[0]]
+	if ok {
+		return cmd.run(args[0], args[1:])
+	}
 
-	if len(remaining) < 3 {
-		flag.Usage()
-		os.Exit(1)
+	// before subcommands, the CLI only ran queries: keep accepting that form
+	if legacyArgs(args) {
+		return runCommand("run", args)
 	}
 
-	database := remaining[0]
-	queryStatement := remaining[1]
-	s3url := remaining[2]
+	fmt.Fprintf(os.Stderr, "unknown command %s\n\n", args[0])
+	usage()
 
-	sess, err := session.NewSessionWithOptions(session.Options{
+	return exitUsage
+}
+
+// legacyArgs returns whether args are in the form the CLI took before it
+// had subcommands: its flags, then a database, query and output location.
+// Anything else, such as a mistyped command, isn't run as a query.
+func legacyArgs(args []string) bool {
+	fs := flag.NewFlagSet("legacy", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.String("poll", "", "")
+	fs.String("timeout", "", "")
+	fs.Bool("skip-header-row", false, "")
+
+	return fs.Parse(args) == nil && fs.NArg() == 3
+}
+
+// newClient creates an Athena client using the shared AWS configuration,
+// configured by the settings of cfg.
+func newClient(cfg *config) (athena.Client, error) {
+	opts := session.Options{
+		Profile:           cfg.get("aws_profile"),
 		SharedConfigState: session.SharedConfigEnable,
-	})
+	}
+
+	if region := cfg.get("region"); region != "" {
+		opts.Config.Region = aws.String(region)
+	}
+
+	if endpoint := cfg.get("endpoint"); endpoint != "" {
+		opts.Config.EndpointResolver = athenaEndpoint(endpoint)
+	}
+
+	sess, err := session.NewSessionWithOptions(opts)
 
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "error: unable to create AWS session:", err)
-		os.Exit(1)
+		return athena.Client{}, fmt.Errorf("unable to create AWS session: %v", err)
 	}
 
 	if sess.Config.Region == nil || *sess.Config.Region == "" {
-		fmt.Fprintln(os.Stderr, "error: AWS region unknown, specify AWS_REGION and/or AWS_PROFILE environment variable")
-		os.Exit(1)
+		return athena.Client{}, errors.New("AWS region unknown, specify -region, AWS_REGION or a region in the AWS or athena-cli profile")
 	}
 
-	client, err := athena.NewClient(sess)
+	var client athena.Client
+	var id athena.Identity
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "error: unable to create Athena client:", err)
-		os.Exit(1)
+	switch {
+	case cfg.get("role_arn") != "":
+		client, id, err = athena.NewClientWithRole(sess, role(cfg))
+	case cfg.get("account") != "" || cfg.get("audit_log") != "":
+		// the identity is checked, or needed for the audit log
+		client, id, err = athena.NewVerifiedClient(sess, cfg.get("account"))
+	default:
+		client, err = athena.NewClient(sess)
 	}
 
-	q, err := client.DoQuery(database, queryStatement, s3url)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "failed to create Athena query:", err)
-		os.Exit(1)
+		return athena.Client{}, fmt.Errorf("unable to create Athena client: %v", err)
 	}
 
-	deadline := make(chan time.Time)
+	logger := newLogger(cfg)
 
-	go func() {
-		deadline <- <-time.After(timeout)
-	}()
+	client = client.WithWorkGroup(cfg.get("workgroup")).
+		WithEncryption(cfg.get("encryption"), cfg.get("kms_key")).
+		WithLogger(logger)
 
-	var r athena.Result
-	var qs athena.QueryStatus
+	if cfg.bool("log_queries") {
+		client = client.WithQueryLogging(cfg.int("log_query_length"))
+	}
 
-	for {
-		select {
-		case <-deadline:
-			fmt.Fprintf(os.Stderr, "deadline reached (%s)\n", timeout)
-			os.Exit(1)
-		default:
-			break
+	if file := cfg.get("audit_log"); file != "" {
+		auditor, err := newAuditor(id, file, cfg.bool("audit_redact"), logger)
+		if err != nil {
+			return athena.Client{}, err
 		}
 
-		var done bool
-		qs, err = q.Status()
+		client = client.WithAuditor(auditor)
+	}
 
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "error getting query status:", err)
-			os.Exit(1)
+	if limit := cfg.bytes("query_limit"); limit > 0 {
+		client = client.WithQueryLimit(limit)
+	}
+
+	// the price has a default, and is validated to be positive
+	client = client.WithPricePerTB(cfg.float("price_per_tb"))
+
+	if poll := cfg.duration("poll"); poll > 0 {
+		client = client.WithPollInterval(poll)
+	}
+
+	return client, nil
+}
+
+// athenaEndpoint returns a resolver using endpoint for Athena, and the
+// usual endpoints of other services such as STS.
+func athenaEndpoint(endpoint string) endpoints.Resolver {
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if service == endpoints.AthenaServiceID {
+			return endpoints.ResolvedEndpoint{URL: endpoint, SigningRegion: region}, nil
 		}
 
-		if done {
-			r, err = q.Result()
+		return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+	})
+}
+
+// role returns the role to assume given by the settings.
+func role(cfg *config) athena.AssumeRole {
+	r := athena.AssumeRole{
+		RoleARN:     cfg.get("role_arn"),
+		ExternalID:  cfg.get("external_id"),
+		SessionName: cfg.get("role_session_name"),
+		MFASerial:   cfg.get("mfa_serial"),
+		Account:     cfg.get("account"),
+	}
 
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "error getting query result:", err)
-				os.Exit(1)
-			}
+	if cfg.get("role_duration") != "" {
+		r.Duration = cfg.duration("role_duration")
+	}
 
-			break
+	if r.MFASerial != "" {
+		r.TokenProvider = ttyTokenProvider
+
+		// a code can't be used twice, so once the credentials expire
+		// they are refreshed with a code read from the terminal
+		if code := cfg.get("mfa_code"); code != "" {
+			used := false
+			r.TokenProvider = func() (string, error) {
+				if used {
+					return ttyTokenProvider()
+				}
+
+				used = true
+				return code, nil
+			}
 		}
+	}
+
+	return r
+}
 
-		time.Sleep(poll)
+// ttyTokenProvider prompts for an MFA code on the terminal. Unlike
+// stscreds.StdinTokenProvider it doesn't read stdin, which may hold the
+// query (with -f -) or be read by the REPL.
+func ttyTokenProvider() (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("unable to prompt for MFA code, set mfa_code instead: %v", err)
 	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, "Assume Role MFA token code: ")
 
-	if skipHeaderRow {
-		r.Rows = r.Rows[1:]
+	code, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && code == "" {
+		return "", err
 	}
 
-	out := struct {
-		OutputLocation string `json:"s3_output_location"`
-		athena.Result
-	}{qs.OutputLocation, r}
+	return strings.TrimSpace(code), nil
+}
+
+// newLogger returns a logger writing to stderr, configured by the log
+// settings of cfg.
+func newLogger(cfg *config) athena.Logger {
+	level, _ := athena.ParseLevel(cfg.get("log_level"))
+
+	if cfg.get("log_format") == "json" {
+		return athena.NewJSONLogger(os.Stderr, level)
+	}
+
+	return athena.NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), level)
+}
+
+// newAuditor returns an auditor recording queries run as id to the named
+// file, logging failures to write it to logger. The file is left open
+// until the CLI exits.
+func newAuditor(id athena.Identity, file string, redact bool, logger athena.Logger) (*athena.Auditor, error) {
+	f, err := athena.OpenAuditFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %v", err)
+	}
+
+	auditor := &athena.Auditor{Identity: id, Sinks: []athena.AuditSink{f}}
+
+	if redact {
+		auditor.Redact = athena.RedactLiterals
+	}
+
+	auditor.OnError = func(err error) {
+		logger.Log(athena.LevelWarn, "unable to write audit log", map[string]interface{}{"error": err})
+	}
+
+	return auditor, nil
+}
+
+// exitCode returns the exit code corresponding to err.
+func exitCode(err error) int {
+	var qerr *athena.QueryError
+	var lerr *athena.ScanLimitError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.As(err, &lerr):
+		return exitCancelled
+	case errors.As(err, &qerr) && qerr.State == "CANCELLED":
+		return exitCancelled
+	case errors.As(err, &qerr):
+		return exitFailed
+	}
+
+	return exitError
+}
 
-	j, _ := json.Marshal(out)
-	fmt.Println(string(j))
+// fail reports err to stderr and returns the corresponding exit code.
+func fail(msg string, err error) int {
+	fmt.Fprintln(os.Stderr, "error:", msg+":", err)
+	return exitCode(err)
 }
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestDispatchUnknownCommand(t *testing.T) {
+	for _, args := range [][]string{
+		{"stauts", "ID"},
+		{"resutls", "-format", "json", "ID"},
+		{"db", "SELECT 1"},
+	} {
+		if code := dispatch(args); code != exitUsage {
+			t.Errorf("dispatch(%q) == %d (want %d)", args, code, exitUsage)
+		}
+	}
+}
+
+func TestLegacyArgs(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"db", "SELECT 1", "s3://bucket/"}, true},
+		{[]string{"-poll", "2s", "-skip-header-row", "db", "SELECT 1", "s3://bucket/"}, true},
+		{[]string{"stauts", "ID"}, false},
+		{[]string{"db", "SELECT 1", "s3://bucket/", "extra"}, false},
+		{[]string{"-format", "json", "db", "SELECT 1", "s3://bucket/"}, false},
+	} {
+		if actual := legacyArgs(test.args); actual != test.expected {
+			t.Errorf("legacyArgs(%q) == %v (want %v)", test.args, actual, test.expected)
+		}
+	}
+}
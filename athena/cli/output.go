@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+// output holds the flags of commands which print query results.
+type output struct {
+	cfg      *config
+	file     string
+	noHeader bool
+	columns  columnList
+}
+
+// columnList is a comma separated list of column names, as a flag.
+type columnList []string
+
+func (l *columnList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *columnList) Set(s string) error {
+	names := strings.Split(s, ",")
+	for _, name := range names {
+		if name == "" {
+			return errors.New("empty column name")
+		}
+	}
+
+	*l = names
+
+	return nil
+}
+
+// addOutputFlags adds the result output flags to fs. The output format
+// is the format setting of cfg.
+func addOutputFlags(fs *flag.FlagSet, cfg *config) *output {
+	o := output{cfg: cfg}
+
+	fs.StringVar(&o.file, "o", "", "write results to `FILE` rather than stdout")
+	fs.BoolVar(&o.noHeader, "no-header", false, "omit the column names")
+	fs.BoolVar(&o.noHeader, "skip-header-row", false, "deprecated: same as -no-header")
+	fs.Var(&o.columns, "columns", "comma separated `COLUMNS` to output, in order")
+
+	return &o
+}
+
+// check checks the columns to output are in the results of q, before they
+// are all fetched, returning exitOK if so.
+func (o *output) check(q athena.Query) int {
+	if len(o.columns) == 0 {
+		return exitOK
+	}
+
+	columns, err := q.Columns()
+	if err != nil {
+		return fail("error getting query result", err)
+	}
+
+	if _, err := (athena.Result{Columns: columns}).Select(o.columns...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	return exitOK
+}
+
+// write writes r, the results of a query stored at location, as specified
+// by the flags.
+func (o *output) write(location string, r athena.Result) int {
+	if len(o.columns) > 0 {
+		var err error
+		if r, err = r.Select(o.columns...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	if o.file == "" {
+		if err := o.encode(os.Stdout, location, r); err != nil {
+			return fail("unable to write output", err)
+		}
+
+		return exitOK
+	}
+
+	f, err := os.Create(o.file)
+	if err != nil {
+		return fail("unable to create output file", err)
+	}
+
+	err = o.encode(f, location, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fail("unable to write output", err)
+	}
+
+	return exitOK
+}
+
+// encode writes r to w in the output format.
+func (o *output) encode(w io.Writer, location string, r athena.Result) error {
+	format := athena.OutputFormat(o.cfg.get("format"))
+
+	if format != athena.OutputJSON {
+		return athena.WriteResult(w, r, format, !o.noHeader)
+	}
+
+	// JSON output has always included the location of the results
+	if o.noHeader {
+		r = r.WithoutHeader()
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		OutputLocation string `json:"s3_output_location"`
+		athena.Result
+	}{location, r})
+}
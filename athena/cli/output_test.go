@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestColumnsFlag(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected []string
+		ok       bool
+	}{
+		{"id", []string{"id"}, true},
+		{"name,id", []string{"name", "id"}, true},
+		{"", nil, false},
+		{"id,", nil, false},
+		{"id,,name", nil, false},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		out := addOutputFlags(fs, nil)
+
+		err := fs.Parse([]string{"-columns", test.value})
+		if (err == nil) != test.ok {
+			t.Errorf("%q: err == %v (want ok %v)", test.value, err, test.ok)
+		}
+
+		if test.ok && !reflect.DeepEqual([]string(out.columns), test.expected) {
+			t.Errorf("%q: columns == %q (want %q)", test.value, out.columns, test.expected)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+// progressLogInterval is how often progress is logged when stderr is not a
+// terminal, unless the query changes state.
+const progressLogInterval = 30 * time.Second
+
+// isTerminal returns true if f is a terminal (or other character device).
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progressReporter reports the progress of queries to stderr. On a terminal
+// a single status line is rewritten on each check; otherwise a line is
+// logged when the query changes state, and every progressLogInterval.
+type progressReporter struct {
+	terminal bool
+	logged   time.Duration
+	shown    bool
+}
+
+func newProgressReporter() *progressReporter {
+	return &progressReporter{terminal: isTerminal(os.Stderr)}
+}
+
+// report is passed to athena.Client.WithProgress.
+func (pr *progressReporter) report(p athena.Progress) {
+	line := fmt.Sprintf("%s %-9s %s elapsed (%s queued, %s running), %s scanned",
+		p.ID, p.State, round(p.Elapsed), round(p.QueueTime), round(p.RunTime), humanBytes(p.DataScannedInBytes))
+
+	if pr.terminal {
+		// return to the start of the line and clear it
+		fmt.Fprint(os.Stderr, "\r\x1b[K"+line)
+		pr.shown = true
+
+		return
+	}
+
+	if p.StateChanged || p.Elapsed-pr.logged >= progressLogInterval {
+		fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format(time.RFC3339), line)
+		pr.logged = p.Elapsed
+	}
+}
+
+// clear removes the status line from the terminal, once the query is done.
+func (pr *progressReporter) clear() {
+	if pr.shown {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+		pr.shown = false
+	}
+
+	pr.logged = 0
+}
+
+// round rounds d for display.
+func round(d time.Duration) time.Duration {
+	return d.Round(100 * time.Millisecond)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+// queryFromArgs parses the arguments of a command taking a single query ID.
+// Flags may be added to the command's flag set by the optional flags
+// functions, which are passed the command's configuration. The exit code to
+// use is returned if the query can't be created.
+func queryFromArgs(name string, args []string, flags ...func(*flag.FlagSet, *config)) (athena.Query, int, bool) {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, awsSettings...)
+
+	for _, f := range flags {
+		f(fs, cfg)
+	}
+
+	if code, ok := parse(fs, args, 1); !ok {
+		return athena.Query{}, code, false
+	}
+
+	if err := cfg.load(); err != nil {
+		return athena.Query{}, fail("invalid configuration", err), false
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return athena.Query{}, fail("unable to create client", err), false
+	}
+
+	q, err := client.QueryByID(fs.Arg(0))
+	if err != nil {
+		return athena.Query{}, fail("invalid query ID", err), false
+	}
+
+	return q, exitOK, true
+}
+
+// statusCommand prints the execution details of a query. The exit code
+// reflects a failed or cancelled query.
+func statusCommand(name string, args []string) int {
+	q, code, ok := queryFromArgs(name, args)
+	if !ok {
+		return code
+	}
+
+	e, err := q.Execution()
+	if err != nil {
+		return fail("error getting query status", err)
+	}
+
+	if code := printJSON(e); code != exitOK {
+		return code
+	}
+
+	switch e.State {
+	case "FAILED":
+		return exitFailed
+	case "CANCELLED":
+		return exitCancelled
+	}
+
+	return exitOK
+}
+
+func resultsCommand(name string, args []string) int {
+	var out *output
+
+	q, code, ok := queryFromArgs(name, args, func(fs *flag.FlagSet, cfg *config) {
+		cfg.addFlags("format")
+		out = addOutputFlags(fs, cfg)
+	})
+	if !ok {
+		return code
+	}
+
+	e, err := q.Execution()
+	if err != nil {
+		return fail("error getting query status", err)
+	}
+
+	if !e.Done() {
+		err := &athena.QueryError{ID: e.ID, State: e.State, Reason: e.StateChangeReason}
+		return fail("query has no results", err)
+	}
+
+	if code := out.check(q); code != exitOK {
+		return code
+	}
+
+	r, err := q.AllResults()
+	if err != nil {
+		return fail("error getting query result", err)
+	}
+
+	return out.write(e.OutputLocation, r)
+}
+
+func stopCommand(name string, args []string) int {
+	q, code, ok := queryFromArgs(name, args)
+	if !ok {
+		return code
+	}
+
+	if err := q.Stop(); err != nil {
+		return fail("error stopping query", err)
+	}
+
+	return exitOK
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+const replHelp = `Enter SQL statements terminated by ';', or a meta-command:
+
+  \d TABLE   describe a table
+  \dt        list tables in the current database
+  \use DB    change the current database
+  \s         show statement history
+  \?         show this help
+  \q         quit
+
+Ctrl-C cancels the running query, or discards the statement being entered.
+On a terminal, lines can be edited, and earlier lines and the statements in
+the history recalled with the up and down arrows.
+`
+
+// repl is an interactive session reading and running statements.
+type repl struct {
+	client   athena.Client
+	database string
+	output   string
+	history  string
+
+	// logger logs failures to save the history.
+	logger athena.Logger
+
+	// budget is the session budget, if any.
+	budget *athena.Budget
+
+	// progress shows the progress of running queries.
+	progress *progressReporter
+
+	// interrupts receives Ctrl-C presses.
+	interrupts chan os.Signal
+}
+
+func replCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, append(awsSettings, "database", "workgroup", "output", "encryption", "kms_key", "poll", "price_per_tb", "query_limit", "session_budget")...)
+	history := fs.String("history", defaultHistoryFile(), "file to save statement history to")
+
+	if code, ok := parse(fs, args, 0); !ok {
+		return code
+	}
+
+	if err := cfg.load(); err != nil {
+		return fail("invalid configuration", err)
+	}
+
+	if err := cfg.require("database", "output"); err != nil {
+		return fail("invalid configuration", err)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail("unable to create client", err)
+	}
+
+	var budget *athena.Budget
+	if limit := cfg.bytes("session_budget"); limit > 0 {
+		budget = athena.NewBudget(limit)
+		client = client.WithBudget(budget)
+	}
+
+	pr := newProgressReporter()
+
+	r := repl{
+		client:     client.WithProgress(pr.report),
+		budget:     budget,
+		progress:   pr,
+		database:   cfg.get("database"),
+		output:     cfg.get("output"),
+		history:    *history,
+		logger:     newLogger(cfg),
+		interrupts: make(chan os.Signal, 1),
+	}
+
+	signal.Notify(r.interrupts, os.Interrupt)
+	defer signal.Stop(r.interrupts)
+
+	r.loop(newLineReader(os.Stdin, r.recent()))
+
+	return exitOK
+}
+
+// defaultHistoryFile returns ~/.athena_history, or nothing if the home
+// directory is unknown.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".athena_history")
+}
+
+// loop reads and runs statements with read until EOF or \q.
+func (r *repl) loop(read lineReader) {
+	type input struct {
+		line string
+		err  error
+	}
+
+	lines := make(chan input)
+	prompts := make(chan string)
+	defer close(prompts)
+
+	// a line is only read when asked for, so that nothing reads the
+	// terminal while a statement runs, e.g. when the MFA code is asked for
+	// to refresh credentials
+	go func() {
+		for prompt := range prompts {
+			line, err := read(prompt)
+			lines <- input{line, err}
+		}
+	}()
+
+	reading := false
+
+	fmt.Fprintln(os.Stderr, `Type \? for help.`)
+
+	var buf strings.Builder
+
+	for {
+		prompt := r.database + "> "
+		if buf.Len() > 0 {
+			prompt = strings.Repeat(" ", len(r.database)) + "-> "
+		}
+
+		// a line still being read after Ctrl-C is read after a new prompt
+		if reading {
+			fmt.Fprint(os.Stderr, prompt)
+		} else {
+			prompts <- prompt
+			reading = true
+		}
+
+		var line string
+
+		select {
+		case in := <-lines:
+			reading = false
+
+			if in.err == errInterrupted {
+				fmt.Fprintln(os.Stderr)
+				buf.Reset()
+				continue
+			}
+
+			if in.err != nil {
+				if in.err != io.EOF {
+					fmt.Fprintln(os.Stderr, "\nerror:", in.err)
+				}
+
+				fmt.Fprintln(os.Stderr)
+				return
+			}
+
+			line = in.line
+		case <-r.interrupts:
+			fmt.Fprintln(os.Stderr)
+			buf.Reset()
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		if buf.Len() == 0 && strings.HasPrefix(trimmed, `\`) {
+			if quit := r.meta(trimmed); quit {
+				return
+			}
+
+			continue
+		}
+
+		if buf.Len() == 0 && trimmed == "" {
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteString("\n")
+
+		// a statement is complete once a line ends with its terminator
+		if !strings.HasSuffix(trimmed, ";") {
+			continue
+		}
+
+		for _, statement := range athena.SplitStatements(buf.String()) {
+			r.save(statement)
+			r.run(statement)
+		}
+
+		buf.Reset()
+	}
+}
+
+// meta runs a meta-command, returning true if the REPL should exit.
+func (r *repl) meta(line string) bool {
+	fields := strings.Fields(line)
+
+	switch fields[0] {
+	case `\q`:
+		return true
+	case `\?`:
+		fmt.Fprint(os.Stderr, replHelp)
+	case `\use`:
+		if len(fields) != 2 {
+			fmt.Fprintln(os.Stderr, `usage: \use DATABASE`)
+			break
+		}
+
+		r.database = fields[1]
+	case `\dt`:
+		r.run("SHOW TABLES")
+	case `\d`:
+		if len(fields) != 2 {
+			fmt.Fprintln(os.Stderr, `usage: \d TABLE`)
+			break
+		}
+
+		r.describe(fields[1])
+	case `\s`:
+		r.showHistory()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s, type \\? for help\n", fields[0])
+	}
+
+	return false
+}
+
+// interruptible returns a context which is cancelled if Ctrl-C is pressed
+// before the returned cancel function is called.
+func (r *repl) interruptible() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-r.interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// wait waits for q to complete, stopping it if Ctrl-C is pressed.
+func (r *repl) wait(q athena.Query) (athena.QueryStatus, error) {
+	ctx, cancel := r.interruptible()
+	defer cancel()
+
+	qs, err := q.Wait(ctx)
+	r.progress.clear()
+
+	if err == context.Canceled {
+		if stopErr := q.Stop(); stopErr != nil {
+			fmt.Fprintln(os.Stderr, "error: unable to stop query:", stopErr)
+		}
+
+		fmt.Fprintf(os.Stderr, "query %s cancelled\n", q.ID())
+	}
+
+	return qs, err
+}
+
+// run runs statement and prints its results.
+func (r *repl) run(statement string) {
+	start := time.Now()
+
+	q, err := r.client.DoQuery(r.database, statement, r.output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return
+	}
+
+	if _, err := r.wait(q); err != nil {
+		if err != context.Canceled {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+
+		return
+	}
+
+	res, err := q.AllResults()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error getting query result:", err)
+		return
+	}
+
+	if len(res.Columns) > 0 {
+		if err := athena.WriteTable(os.Stdout, res); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return
+		}
+	}
+
+	stats, err := q.Statistics()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error getting query statistics:", err)
+		return
+	}
+
+	rows := len(res.WithoutHeader().Rows)
+	summary := fmt.Sprintf("%d rows, %s elapsed, %s scanned, cost $%.4f", rows, time.Since(start).Round(time.Millisecond), humanBytes(stats.DataScannedInBytes), r.client.Cost(stats.DataScannedInBytes))
+	if r.budget != nil {
+		remaining := r.budget.Remaining()
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		summary += fmt.Sprintf(", %s of budget left", humanBytes(remaining))
+	}
+
+	fmt.Fprintf(os.Stderr, "(%s)\n\n", summary)
+}
+
+// describe prints the columns and partition keys of table.
+func (r *repl) describe(table string) {
+	ctx, cancel := r.interruptible()
+	defer cancel()
+
+	td, err := r.client.DescribeTable(ctx, r.database, table, r.output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return
+	}
+
+	desc := athena.Result{Columns: []athena.Column{{Name: "column"}, {Name: "type"}, {Name: "partition"}, {Name: "comment"}}}
+	for _, c := range td.Columns {
+		desc.Rows = append(desc.Rows, athena.Row{c.Name, c.Type, "", c.Comment})
+	}
+
+	for _, c := range td.PartitionKeys {
+		desc.Rows = append(desc.Rows, athena.Row{c.Name, c.Type, "yes", c.Comment})
+	}
+
+	if err := athena.WriteTable(os.Stdout, desc); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+}
+
+// save appends statement to the history file.
+func (r *repl) save(statement string) {
+	if r.history == "" {
+		return
+	}
+
+	f, err := os.OpenFile(r.history, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		r.logger.Log(athena.LevelWarn, "unable to save history", map[string]interface{}{"error": err})
+		r.history = ""
+		return
+	}
+
+	defer f.Close()
+
+	fmt.Fprintf(f, "%s;\n", statement)
+}
+
+// statements returns the statements in the history file, oldest first.
+func (r *repl) statements() ([]string, error) {
+	if r.history == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(r.history)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return athena.SplitStatements(string(b)), nil
+}
+
+// recent returns the statements in the history file, each on one line, for
+// recall with the up arrow.
+func (r *repl) recent() []string {
+	statements, err := r.statements()
+	if err != nil {
+		r.logger.Log(athena.LevelWarn, "unable to read history", map[string]interface{}{"error": err})
+	}
+
+	lines := make([]string, len(statements))
+	for i, statement := range statements {
+		lines[i] = strings.ReplaceAll(statement, "\n", " ") + ";"
+	}
+
+	return lines
+}
+
+// showHistory prints the most recent statements in the history file.
+func (r *repl) showHistory() {
+	const max = 20
+
+	statements, err := r.statements()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return
+	}
+
+	if len(statements) > max {
+		statements = statements[len(statements)-max:]
+	}
+
+	for _, statement := range statements {
+		fmt.Printf("%s;\n", statement)
+	}
+}
+
+// humanBytes formats n bytes for humans.
+func humanBytes(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func runCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, append(awsSettings, "database", "workgroup", "output", "encryption", "kms_key", "poll", "timeout", "format", "price_per_tb", "query_limit")...)
+	qf := addQueryFlags(fs)
+	out := addOutputFlags(fs, cfg)
+	quiet := fs.Bool("quiet", false, "don't report the progress and cost of the query on stderr")
+	dryRun := fs.Bool("dry-run", false, "validate the query and estimate its cost, without running it")
+
+	queryStatement, code, ok := queryArgs(fs, args, qf, cfg)
+	if !ok {
+		return code
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail("unable to create client", err)
+	}
+
+	if *dryRun {
+		return estimate(client, cfg, queryStatement, cfg.duration("timeout"))
+	}
+
+	pr := newProgressReporter()
+	if !*quiet {
+		client = client.WithProgress(pr.report)
+	}
+
+	q, err := client.DoQuery(cfg.get("database"), queryStatement, cfg.get("output"))
+	if err != nil {
+		return fail("failed to create Athena query", err)
+	}
+
+	timeout := cfg.duration("timeout")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	qs, err := q.Wait(ctx)
+	pr.clear()
+
+	if err == context.DeadlineExceeded {
+		fmt.Fprintf(os.Stderr, "deadline reached (%s), query %s is still %s\n", timeout, q.ID(), qs.State)
+		return exitTimeout
+	}
+
+	if err != nil {
+		return fail("query did not succeed", err)
+	}
+
+	if code := out.check(q); code != exitOK {
+		return code
+	}
+
+	r, err := q.AllResults()
+	if err != nil {
+		return fail("error getting query result", err)
+	}
+
+	if !*quiet {
+		stats, err := q.Statistics()
+		if err != nil {
+			return fail("error getting query statistics", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s scanned, cost $%.4f\n", humanBytes(stats.DataScannedInBytes), client.Cost(stats.DataScannedInBytes))
+	}
+
+	return out.write(qs.OutputLocation, r)
+}
+
+func submitCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	cfg := addConfigFlags(fs, append(awsSettings, "database", "workgroup", "output", "encryption", "kms_key")...)
+	qf := addQueryFlags(fs)
+
+	queryStatement, code, ok := queryArgs(fs, args, qf, cfg)
+	if !ok {
+		return code
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail("unable to create client", err)
+	}
+
+	q, err := client.DoQuery(cfg.get("database"), queryStatement, cfg.get("output"))
+	if err != nil {
+		return fail("failed to create Athena query", err)
+	}
+
+	fmt.Println(q.ID())
+
+	return exitOK
+}
+
+// queryArgs parses the arguments of a command taking a query, which may
+// instead be read from a file, and optionally a database and output
+// location overriding the configured ones. The query is rendered with the
+// template variables.
+func queryArgs(fs *flag.FlagSet, args []string, qf *queryFlags, cfg *config) (string, int, bool) {
+	if code, ok := parse(fs, args, 0, 1, 2, 3); !ok {
+		return "", code, false
+	}
+
+	// the query argument is optional only when reading it from a file
+	n := fs.NArg()
+	if n != qf.nargs(0) && n != qf.nargs(2) {
+		fs.Usage()
+		return "", exitUsage, false
+	}
+
+	if err := cfg.load(); err != nil {
+		return "", fail("invalid configuration", err), false
+	}
+
+	// the MFA code is otherwise read from the terminal, which is usually
+	// also stdin
+	if qf.file == "-" && cfg.get("mfa_serial") != "" && cfg.get("mfa_code") == "" {
+		fmt.Fprintln(os.Stderr, "-f - cannot be used with mfa_serial unless mfa_code is set")
+		return "", exitUsage, false
+	}
+
+	if n >= 2 {
+		cfg.set("database", fs.Arg(0), "argument")
+		cfg.set("output", fs.Arg(n-1), "argument")
+	}
+
+	if err := cfg.require("database", "output"); err != nil {
+		return "", fail("invalid configuration", err), false
+	}
+
+	var arg string
+	if qf.file == "" {
+		arg = fs.Arg(n / 2)
+	}
+
+	query, err := qf.query(arg)
+	if err != nil {
+		return "", fail("unable to read query", err), false
+	}
+
+	return query, exitOK, true
+}
+
+// estimate prints the estimate of what query would scan and cost.
+func estimate(client athena.Client, cfg *config, query string, timeout time.Duration) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	e, err := client.Estimate(ctx, cfg.get("database"), query, cfg.get("output"))
+	if err != nil {
+		return fail("invalid query", err)
+	}
+
+	if e.Estimated {
+		fmt.Fprintf(os.Stderr, "estimated %s scanned, cost $%.4f\n", humanBytes(e.EstimatedBytes), e.EstimatedCost)
+	} else {
+		fmt.Fprintln(os.Stderr, "no statistics available to estimate the data scanned")
+	}
+
+	return printJSON(e)
+}
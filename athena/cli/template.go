@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+// varFlag collects -var key=value flags.
+type varFlag map[string]interface{}
+
+func (v varFlag) String() string {
+	return ""
+}
+
+func (v varFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return errors.New("must be of the form key=value")
+	}
+
+	v[s[:i]] = s[i+1:]
+
+	return nil
+}
+
+// queryFlags holds the flags of commands which take a query, either as an
+// argument or from a file, and may render it as a template.
+type queryFlags struct {
+	file     string
+	vars     varFlag
+	varsFile string
+
+	// template renders the query even without variables, e.g. to use
+	// the date functions.
+	template bool
+}
+
+// addQueryFlags adds the query flags to fs.
+func addQueryFlags(fs *flag.FlagSet) *queryFlags {
+	q := queryFlags{vars: varFlag{}}
+
+	fs.StringVar(&q.file, "f", "", "read the query from `FILE`, or stdin if -, rather than an argument")
+	fs.Var(q.vars, "var", "set the template variable `key=value`; may be repeated")
+	fs.StringVar(&q.varsFile, "vars", "", "read template variables from a JSON or YAML `FILE`")
+	fs.BoolVar(&q.template, "template", false, "render the query as a template even without -var or -vars")
+
+	return &q
+}
+
+// nargs returns the number of positional arguments the command needs,
+// given it takes n other than the query.
+func (q *queryFlags) nargs(n int) int {
+	if q.file != "" {
+		return n
+	}
+
+	return n + 1
+}
+
+// query returns the query, read from the file or taken from arg. It is
+// rendered as a template only if variables are given or -template is set,
+// so that queries containing {{ are otherwise run as written.
+func (q *queryFlags) query(arg string) (string, error) {
+	text := arg
+
+	if q.file != "" {
+		var b []byte
+		var err error
+
+		if q.file == "-" {
+			b, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			b, err = ioutil.ReadFile(q.file)
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		text = string(b)
+	}
+
+	if !q.template && q.varsFile == "" && len(q.vars) == 0 {
+		return text, nil
+	}
+
+	vars := map[string]interface{}{}
+
+	if q.varsFile != "" {
+		var err error
+		if vars, err = readVars(q.varsFile); err != nil {
+			return "", err
+		}
+	}
+
+	// variables set by flags override those from the file
+	for k, v := range q.vars {
+		vars[k] = v
+	}
+
+	return athena.RenderQuery(text, vars)
+}
+
+// readVars reads template variables from a JSON object, or if the file
+// has a .yaml or .yml extension, from a YAML mapping; see parseYAML.
+func readVars(file string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		vars, err := parseYAML(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		return vars, nil
+	}
+
+	var vars map[string]interface{}
+	if err := json.Unmarshal(b, &vars); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// ioctl requests getting and setting the attributes of a terminal
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,24 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+// termState is the state of a terminal; terminals are unsupported here.
+type termState struct{}
+
+// editable returns false, as line editing is unsupported here.
+func editable(fd int) bool {
+	return false
+}
+
+// makeRaw fails, as line editing is unsupported here.
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
+
+// restore does nothing, as line editing is unsupported here.
+func restore(fd int, state *termState) error {
+	return nil
+}
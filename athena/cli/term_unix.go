@@ -0,0 +1,71 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// termState is the state of a terminal, to restore once it is no longer in
+// raw mode.
+type termState struct {
+	termios syscall.Termios
+}
+
+// getTermios gets the attributes of the terminal fd.
+func getTermios(fd int) (syscall.Termios, error) {
+	var t syscall.Termios
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return t, errno
+	}
+
+	return t, nil
+}
+
+// setTermios sets the attributes of the terminal fd.
+func setTermios(fd int, t syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// editable returns whether fd is a terminal which can be put in raw mode,
+// to edit lines.
+func editable(fd int) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// makeRaw puts the terminal fd into raw mode, where input is read a byte
+// at a time without being echoed and Ctrl-C is read rather than sending
+// SIGINT, returning its previous state. Output is still processed, so that
+// newlines return the cursor to the start of the line.
+func makeRaw(fd int) (*termState, error) {
+	t, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	old := termState{t}
+
+	t.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IGNCR | syscall.IXON | syscall.ISTRIP
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+
+	if err := setTermios(fd, t); err != nil {
+		return nil, err
+	}
+
+	return &old, nil
+}
+
+// restore returns the terminal fd to state.
+func restore(fd int, state *termState) error {
+	return setTermios(fd, state.termios)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML needed for configuration and template
+// variables: mappings of keys to scalars or further mappings, indented
+// with spaces, e.g.
+//
+//	table: events
+//	since: "2019-11-01"  # comment
+//	limits:
+//	  rows: 100
+//
+// Scalars are returned as strings. Lists, flow collections and multi-line
+// strings are not supported.
+func parseYAML(s string) (map[string]interface{}, error) {
+	type level struct {
+		indent int
+		m      map[string]interface{}
+	}
+
+	root := map[string]interface{}{}
+	stack := []level{{0, root}}
+
+	// pending is the mapping of a key with no value, whose entries are
+	// expected on the following, further indented, lines
+	var pending map[string]interface{}
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || line == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", n)
+		}
+
+		indent := len(line) - len(trimmed)
+
+		if pending != nil && indent > stack[len(stack)-1].indent {
+			stack = append(stack, level{indent, pending})
+		} else {
+			for len(stack) > 1 && indent < stack[len(stack)-1].indent {
+				stack = stack[:len(stack)-1]
+			}
+
+			if indent != stack[len(stack)-1].indent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", n)
+			}
+		}
+
+		pending = nil
+
+		i := strings.Index(trimmed, ":")
+		if i < 1 || (i+1 < len(trimmed) && trimmed[i+1] != ' ') {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+
+		key := strings.TrimSpace(trimmed[:i])
+		value := strings.TrimSpace(trimmed[i+1:])
+		m := stack[len(stack)-1].m
+
+		if value == "" || strings.HasPrefix(value, "#") {
+			pending = map[string]interface{}{}
+			m[key] = pending
+			continue
+		}
+
+		v, err := yamlScalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", n, key, err)
+		}
+
+		m[key] = v
+	}
+
+	return root, scanner.Err()
+}
+
+// yamlScalar parses a plain or quoted scalar, which may be followed by a
+// comment.
+func yamlScalar(value string) (string, error) {
+	switch value[0] {
+	case '"':
+		return strconv.Unquote(quotedPrefix(value, '"'))
+	case '\'':
+		v := quotedPrefix(value, '\'')
+		if len(v) < 2 || v[len(v)-1] != '\'' {
+			return "", fmt.Errorf("unterminated string")
+		}
+
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'"), nil
+	case '[', '{', '|', '>', '&', '*':
+		return "", fmt.Errorf("only plain and quoted scalar values are supported")
+	}
+
+	// a dash starts a sequence entry only if followed by a space, unlike
+	// in e.g. -1 or -prod
+	if value == "-" || strings.HasPrefix(value, "- ") {
+		return "", fmt.Errorf("only plain and quoted scalar values are supported")
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return value, nil
+}
+
+// quotedPrefix returns the quoted string at the start of value, which
+// starts with quote, including the quotes. A doubled single quote is an
+// escaped quote; a double quote may be escaped with a backslash.
+func quotedPrefix(value string, quote byte) string {
+	for i := 1; i < len(value); i++ {
+		switch {
+		case quote == '"' && value[i] == '\\':
+			i++
+		case value[i] == quote && quote == '\'' && i+1 < len(value) && value[i+1] == '\'':
+			i++
+		case value[i] == quote:
+			return value[:i+1]
+		}
+	}
+
+	return value
+}
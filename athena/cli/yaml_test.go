@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	for _, test := range []struct {
+		id       string
+		yaml     string
+		expected map[string]interface{}
+	}{
+		{
+			id:       "plain",
+			yaml:     "table: events\nlimit: 100\n",
+			expected: map[string]interface{}{"table": "events", "limit": "100"},
+		},
+		{
+			id:       "leading dash",
+			yaml:     "poll: -1\noffset: -2.5\nprofile: -prod\nrange: --x # comment\n",
+			expected: map[string]interface{}{"poll": "-1", "offset": "-2.5", "profile": "-prod", "range": "--x"},
+		},
+		{
+			id: "quoting",
+			yaml: strings.Join([]string{
+				`double: "2019-11-01"`,
+				`escaped: "tab\tand \"quote\""`,
+				`hash: "x # not a comment"`,
+				`single: 'it''s'`,
+				`colon: "a: b"`,
+			}, "\n"),
+			expected: map[string]interface{}{
+				"double":  "2019-11-01",
+				"escaped": "tab\tand \"quote\"",
+				"hash":    "x # not a comment",
+				"single":  "it's",
+				"colon":   "a: b",
+			},
+		},
+		{
+			id: "comments",
+			yaml: strings.Join([]string{
+				"---",
+				"# a comment",
+				"",
+				"plain: x # trailing",
+				`quoted: 'y' # trailing`,
+				"url: s3://bucket/a#b",
+				"empty: # no value",
+			}, "\n"),
+			expected: map[string]interface{}{
+				"plain":  "x",
+				"quoted": "y",
+				"url":    "s3://bucket/a#b",
+				"empty":  map[string]interface{}{},
+			},
+		},
+		{
+			id: "nested profiles",
+			yaml: strings.Join([]string{
+				"default_profile: dev",
+				"profiles:",
+				"  dev:",
+				"    database: events_dev",
+				"    output: s3://results-dev/",
+				"  prod:",
+				"    database: events",
+				"",
+				"    poll: 2s",
+				"other: x",
+			}, "\n"),
+			expected: map[string]interface{}{
+				"default_profile": "dev",
+				"profiles": map[string]interface{}{
+					"dev":  map[string]interface{}{"database": "events_dev", "output": "s3://results-dev/"},
+					"prod": map[string]interface{}{"database": "events", "poll": "2s"},
+				},
+				"other": "x",
+			},
+		},
+	} {
+		t.Run(test.id, func(tt *testing.T) {
+			actual, err := parseYAML(test.yaml)
+
+			if err != nil {
+				tt.Errorf("err == %v (want nil)", err)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				tt.Errorf("parseYAML() == %v (want %v)", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, test := range []struct {
+		yaml string
+		err  string
+	}{
+		{"a: b\n  c: d", "line 2: unexpected indentation"},
+		{"a:\n    b: c\n  d: e", "line 3: unexpected indentation"},
+		{"\ta: b", "line 1: tabs are not allowed in indentation"},
+		{"just a value", "line 1: expected key: value"},
+		{"a:b", "line 1: expected key: value"},
+		{`a: "unterminated`, "line 1: a: invalid syntax"},
+		{`a: 'unterminated`, "line 1: a: unterminated string"},
+		{"a: [1, 2]", "line 1: a: only plain and quoted scalar values are supported"},
+		{"a:\n  - b", "line 2: expected key: value"},
+		{"a: - b", "line 1: a: only plain and quoted scalar values are supported"},
+		{"a: -", "line 1: a: only plain and quoted scalar values are supported"},
+	} {
+		_, err := parseYAML(test.yaml)
+
+		if err == nil || err.Error() != test.err {
+			t.Errorf("parseYAML(%q) err == %v (want %s)", test.yaml, err, test.err)
+		}
+	}
+}
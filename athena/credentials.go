@@ -0,0 +1,150 @@
+package athena
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// Errors for invalid credentials
+const (
+	emptyRoleARN      = constError("role ARN must not be an empty string")
+	mfaIncomplete     = constError("MFA serial number and token provider must be specified together")
+	unexpectedAccount = constError("credentials are not for the expected account")
+)
+
+// AssumeRole describes an IAM role to assume, e.g. in another account.
+type AssumeRole struct {
+	// RoleARN is the ARN of the role.
+	RoleARN string
+
+	// ExternalID is the external ID the role's trust policy requires, if any.
+	ExternalID string
+
+	// SessionName identifies the role session in CloudTrail; a name is
+	// generated if empty.
+	SessionName string
+
+	// Duration is how long the role's credentials last before being
+	// refreshed; the STS default of 15 minutes is used if zero.
+	Duration time.Duration
+
+	// MFASerial is the serial number or ARN of the MFA device the role's
+	// trust policy requires, if any, and TokenProvider returns its current
+	// code, e.g. stscreds.StdinTokenProvider.
+	MFASerial     string
+	TokenProvider func() (string, error)
+
+	// Account, if not empty, is the ID of the account the role must be
+	// in; it is checked when creating a client.
+	Account string
+}
+
+// configure applies the role's options to the provider of its credentials.
+func (r AssumeRole) configure(p *stscreds.AssumeRoleProvider) {
+	if r.ExternalID != "" {
+		p.ExternalID = aws.String(r.ExternalID)
+	}
+
+	if r.SessionName != "" {
+		p.RoleSessionName = r.SessionName
+	}
+
+	if r.Duration > 0 {
+		p.Duration = r.Duration
+	}
+
+	if r.MFASerial != "" {
+		p.SerialNumber = aws.String(r.MFASerial)
+		p.TokenProvider = r.TokenProvider
+	}
+}
+
+func (r AssumeRole) validate() error {
+	if r.RoleARN == "" {
+		return emptyRoleARN
+	}
+
+	if (r.MFASerial == "") != (r.TokenProvider == nil) {
+		return mfaIncomplete
+	}
+
+	return nil
+}
+
+// AssumeRoleCredentials returns credentials for role, assumed using the
+// credentials of session. The role is assumed when the credentials are
+// first used, and again as they expire.
+func AssumeRoleCredentials(session *session.Session, role AssumeRole) (*credentials.Credentials, error) {
+	if session == nil {
+		return nil, nilSession
+	}
+
+	return assumeRoleCredentials(sts.New(session), role)
+}
+
+func assumeRoleCredentials(svc stscreds.AssumeRoler, role AssumeRole) (*credentials.Credentials, error) {
+	if err := role.validate(); err != nil {
+		return nil, err
+	}
+
+	return stscreds.NewCredentialsWithClient(svc, role.RoleARN, role.configure), nil
+}
+
+// NewClientWithRole creates a client which queries Athena as role, assumed
+// using the credentials of session. The role is assumed straight away to
+// check it can be, returning the identity it has; this can be given to an
+// Auditor.
+func NewClientWithRole(session *session.Session, role AssumeRole) (Client, Identity, error) {
+	creds, err := AssumeRoleCredentials(session, role)
+	if err != nil {
+		return Client{}, Identity{}, err
+	}
+
+	cfg := &aws.Config{Credentials: creds}
+
+	id, err := verifyIdentity(sts.New(session, cfg), role.Account)
+	if err != nil {
+		return Client{}, Identity{}, err
+	}
+
+	return Client{api: athena.New(session, cfg)}, id, nil
+}
+
+// NewVerifiedClient creates a client as NewClient does, first checking
+// the credentials of session are valid and, if account is not empty, for
+// that account. The identity of the credentials is returned.
+func NewVerifiedClient(session *session.Session, account string) (Client, Identity, error) {
+	if session == nil {
+		return Client{}, Identity{}, nilSession
+	}
+
+	id, err := verifyIdentity(sts.New(session), account)
+	if err != nil {
+		return Client{}, Identity{}, err
+	}
+
+	return Client{api: athena.New(session)}, id, nil
+}
+
+// verifyIdentity returns the identity of the credentials used by api,
+// checking they are for account if it is not empty.
+func verifyIdentity(api stsiface.STSAPI, account string) (Identity, error) {
+	id, err := CallerIdentity(api)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if account != "" && id.Account != account {
+		return Identity{}, fmt.Errorf("%s, not %s: %w", id.Account, account, unexpectedAccount)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,118 @@
+package athena_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// mockAssumeRoler records AssumeRole requests.
+type mockAssumeRoler struct {
+	inputs *[]*sts.AssumeRoleInput
+}
+
+func (m mockAssumeRoler) AssumeRole(in *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	*m.inputs = append(*m.inputs, in)
+
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}
+
+	return &sts.AssumeRoleOutput{Credentials: creds}, nil
+}
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	token := func() (string, error) { return "123456", nil }
+
+	for i, test := range []struct {
+		role     athena.AssumeRole
+		expected *sts.AssumeRoleInput
+		err      error
+	}{
+		{
+			athena.AssumeRole{RoleARN: "arn:aws:iam::123456789012:role/analytics", SessionName: "job"},
+			&sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::123456789012:role/analytics"),
+				RoleSessionName: aws.String("job"),
+				DurationSeconds: aws.Int64(900),
+			},
+			nil,
+		},
+		{
+			athena.AssumeRole{
+				RoleARN:       "arn:aws:iam::123456789012:role/analytics",
+				ExternalID:    "ext",
+				SessionName:   "job",
+				Duration:      time.Hour,
+				MFASerial:     "arn:aws:iam::123456789012:mfa/analyst",
+				TokenProvider: token,
+			},
+			&sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::123456789012:role/analytics"),
+				RoleSessionName: aws.String("job"),
+				DurationSeconds: aws.Int64(3600),
+				ExternalId:      aws.String("ext"),
+				SerialNumber:    aws.String("arn:aws:iam::123456789012:mfa/analyst"),
+				TokenCode:       aws.String("123456"),
+			},
+			nil,
+		},
+		{athena.AssumeRole{}, nil, athena.ErrEmptyRoleARN},
+		{athena.AssumeRole{RoleARN: "arn", MFASerial: "mfa"}, nil, athena.ErrMFAIncomplete},
+		{athena.AssumeRole{RoleARN: "arn", TokenProvider: token}, nil, athena.ErrMFAIncomplete},
+	} {
+		var inputs []*sts.AssumeRoleInput
+
+		creds, err := athena.AssumeRoleCredentialsWithClient(mockAssumeRoler{&inputs}, test.role)
+		if err != test.err {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if test.err != nil {
+			continue
+		}
+
+		v, err := creds.Get()
+		if err != nil {
+			t.Errorf("%d: err == %v (want nil)", i, err)
+		}
+
+		if v.AccessKeyID != "AKIAEXAMPLE" {
+			t.Errorf("%d: AccessKeyID == %v (want AKIAEXAMPLE)", i, v.AccessKeyID)
+		}
+
+		if len(inputs) != 1 || !reflect.DeepEqual(inputs[0], test.expected) {
+			t.Errorf("%d: inputs == %v (want %v)", i, inputs, test.expected)
+		}
+	}
+}
+
+func TestVerifyIdentity(t *testing.T) {
+	out := &sts.GetCallerIdentityOutput{Account: aws.String("123456789012"), Arn: aws.String("arn")}
+
+	for i, test := range []struct {
+		account string
+		err     error
+	}{
+		{"", nil},
+		{"123456789012", nil},
+		{"210987654321", athena.ErrUnexpectedAccount},
+	} {
+		id, err := athena.VerifyIdentity(mockSTS{out: out}, test.account)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if test.err == nil && id.Account != "123456789012" {
+			t.Errorf("%d: Account == %v (want 123456789012)", i, id.Account)
+		}
+	}
+}
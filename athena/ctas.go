@@ -0,0 +1,412 @@
+package athena
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors for invalid CTAS and INSERT INTO statements
+const (
+	bucketingIncomplete = constError("bucketed_by and bucket_count must be specified together")
+	invalidBucketCount  = constError("bucket_count must be positive")
+)
+
+// CTAS describes a CREATE TABLE AS SELECT statement, which creates a table
+// from the results of a query.
+//
+// See https://docs.aws.amazon.com/athena/latest/ug/create-table-as.html
+type CTAS struct {
+	// Table is the name of the table to create, optionally qualified by
+	// database.
+	Table string
+
+	// Query is the SELECT statement whose results populate the table.
+	// Partition columns must be the last columns it selects.
+	Query string
+
+	// Format is the storage format of the table's data; Athena defaults to
+	// Parquet if empty.
+	Format Format
+
+	// ExternalLocation is the S3 URL to write the table's data to; Athena
+	// writes under the workgroup's query result location if empty.
+	ExternalLocation string
+
+	// PartitionedBy are the columns to partition the table by.
+	PartitionedBy []string
+
+	// BucketedBy are the columns to bucket data within each partition by.
+	BucketedBy []string
+
+	// BucketCount is the number of buckets; required with BucketedBy.
+	BucketCount int
+
+	// WriteCompression is the compression of the data files, e.g. SNAPPY.
+	WriteCompression string
+
+	// Cleanup specifies what to remove if the statement fails.
+	Cleanup Cleanup
+}
+
+// ctasFormat returns the name of format in a CTAS statement.
+func ctasFormat(f Format) (string, error) {
+	switch f {
+	case FormatJSON, FormatParquet, FormatORC, FormatAvro:
+		return string(f), nil
+	case FormatCSV:
+		return "TEXTFILE", nil
+	}
+
+	return "", unknownFormat
+}
+
+// Statement returns the CREATE TABLE AS SELECT statement.
+func (c CTAS) Statement() (string, error) {
+	table, err := quoteTable(c.Table)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Query == "" {
+		return "", emptyQuery
+	}
+
+	if (len(c.BucketedBy) == 0) != (c.BucketCount == 0) {
+		return "", bucketingIncomplete
+	}
+
+	if c.BucketCount < 0 {
+		return "", invalidBucketCount
+	}
+
+	var props []string
+
+	if c.Format != "" {
+		f, err := ctasFormat(c.Format)
+		if err != nil {
+			return "", err
+		}
+
+		props = append(props, "format = "+QuoteLiteral(f))
+
+		if c.Format == FormatCSV {
+			props = append(props, "field_delimiter = ','")
+		}
+	}
+
+	if c.ExternalLocation != "" {
+		if err := validS3URL(c.ExternalLocation); err != nil {
+			return "", err
+		}
+
+		props = append(props, "external_location = "+QuoteLiteral(c.ExternalLocation))
+	}
+
+	if len(c.PartitionedBy) > 0 {
+		a, err := array(c.PartitionedBy)
+		if err != nil {
+			return "", err
+		}
+
+		props = append(props, "partitioned_by = "+a)
+	}
+
+	if len(c.BucketedBy) > 0 {
+		a, err := array(c.BucketedBy)
+		if err != nil {
+			return "", err
+		}
+
+		props = append(props, "bucketed_by = "+a, "bucket_count = "+strconv.Itoa(c.BucketCount))
+	}
+
+	if c.WriteCompression != "" {
+		props = append(props, "write_compression = "+QuoteLiteral(c.WriteCompression))
+	}
+
+	var b strings.Builder
+
+	b.WriteString("CREATE TABLE " + table)
+
+	if len(props) > 0 {
+		b.WriteString(" WITH (\n  " + strings.Join(props, ",\n  ") + "\n)")
+	}
+
+	b.WriteString(" AS\n" + c.Query)
+
+	return b.String(), nil
+}
+
+// array returns an ARRAY literal of the column names.
+func array(columns []string) (string, error) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		if c == "" {
+			return "", emptyColumn
+		}
+
+		quoted[i] = QuoteLiteral(c)
+	}
+
+	return "ARRAY[" + strings.Join(quoted, ", ") + "]", nil
+}
+
+// Insert describes an INSERT INTO statement, which appends the results of
+// a query to an existing table.
+type Insert struct {
+	// Table is the name of the table to insert into, optionally qualified
+	// by database.
+	Table string
+
+	// Columns optionally lists the table columns the query's results are
+	// inserted into, in order.
+	Columns []string
+
+	// Query is the SELECT statement whose results are inserted.
+	Query string
+
+	// Cleanup specifies what to remove if the statement fails.
+	Cleanup Cleanup
+}
+
+// Statement returns the INSERT INTO statement.
+func (i Insert) Statement() (string, error) {
+	table, err := quoteTable(i.Table)
+	if err != nil {
+		return "", err
+	}
+
+	if i.Query == "" {
+		return "", emptyQuery
+	}
+
+	var b strings.Builder
+
+	b.WriteString("INSERT INTO " + table)
+
+	if len(i.Columns) > 0 {
+		quoted := make([]string, len(i.Columns))
+		for j, c := range i.Columns {
+			if c == "" {
+				return "", emptyColumn
+			}
+
+			quoted[j] = QuoteIdentifier(c)
+		}
+
+		b.WriteString(" (" + strings.Join(quoted, ", ") + ")")
+	}
+
+	b.WriteString("\n" + i.Query)
+
+	return b.String(), nil
+}
+
+// Cleanup specifies what CreateTableAs and InsertInto remove when their
+// query fails or is cancelled.
+type Cleanup struct {
+	// DropTable drops the table a failed CTAS statement created.
+	DropTable bool
+
+	// RemoveOutput, if not nil, is called with the S3 location of the data
+	// a failed statement wrote: the external location of a CTAS statement
+	// if specified, otherwise the data manifest listing the files written.
+	//
+	// S3 is not in scope of this package, so the caller must provide the
+	// means of removing the data.
+	RemoveOutput func(ctx context.Context, location string) error
+}
+
+// CleanupError is returned when cleaning up after a failed statement also
+// fails. It unwraps to the error of the statement.
+type CleanupError struct {
+	// Err is the error of the statement, usually a *QueryError.
+	Err error
+
+	// CleanupErr is the error encountered cleaning up.
+	CleanupErr error
+}
+
+// Error satisfies the error interface.
+func (e *CleanupError) Error() string {
+	return fmt.Sprintf("%v (cleanup failed: %v)", e.Err, e.CleanupErr)
+}
+
+// Unwrap returns the error of the statement.
+func (e *CleanupError) Unwrap() error {
+	return e.Err
+}
+
+// WriteStatistics describes the outcome of a CTAS or INSERT INTO statement.
+type WriteStatistics struct {
+	// QueryID is the Athena query execution ID of the statement.
+	QueryID string `json:"query_id"`
+
+	// RowsWritten is the number of rows written, as reported in the
+	// statement's result rather than counted from the data manifest; a
+	// result not of one row and column is an ErrUnexpectedResult.
+	RowsWritten int64 `json:"rows_written"`
+
+	Statistics
+}
+
+// CreateTableAs runs the CTAS statement on database, waits for it to
+// complete and returns its statistics. See DoQuery for the meaning of output.
+//
+// If the statement fails, or ctx is done before it completes and it is
+// stopped, the table and data it created are removed as specified by
+// ctas.Cleanup.
+func (c Client) CreateTableAs(ctx context.Context, database string, ctas CTAS, output string) (WriteStatistics, error) {
+	statement, err := ctas.Statement()
+	if err != nil {
+		return WriteStatistics{}, err
+	}
+
+	ws, incomplete, err := c.write(ctx, database, statement, output)
+	if !incomplete {
+		return ws, err
+	}
+
+	ctx = detached{ctx}
+
+	var cleanupErr error
+
+	if ctas.Cleanup.DropTable {
+		// the table name has already been validated by Statement
+		table, _ := quoteDDLTable(ctas.Table)
+		cleanupErr = c.DoDDL(ctx, database, "DROP TABLE IF EXISTS "+table, output)
+	}
+
+	location := ctas.ExternalLocation
+	if location == "" {
+		location = ws.DataManifestLocation
+	}
+
+	if cleanupErr == nil {
+		cleanupErr = ctas.Cleanup.removeOutput(ctx, location)
+	}
+
+	if cleanupErr != nil {
+		return ws, &CleanupError{Err: err, CleanupErr: cleanupErr}
+	}
+
+	return ws, err
+}
+
+// InsertInto runs the INSERT INTO statement on database, waits for it to
+// complete and returns its statistics. See DoQuery for the meaning of output.
+//
+// If the statement fails, or ctx is done before it completes and it is
+// stopped, the data it wrote is removed as specified by insert.Cleanup.
+func (c Client) InsertInto(ctx context.Context, database string, insert Insert, output string) (WriteStatistics, error) {
+	statement, err := insert.Statement()
+	if err != nil {
+		return WriteStatistics{}, err
+	}
+
+	ws, incomplete, err := c.write(ctx, database, statement, output)
+	if !incomplete {
+		return ws, err
+	}
+
+	if cleanupErr := insert.Cleanup.removeOutput(detached{ctx}, ws.DataManifestLocation); cleanupErr != nil {
+		return ws, &CleanupError{Err: err, CleanupErr: cleanupErr}
+	}
+
+	return ws, err
+}
+
+// removeOutput calls RemoveOutput with location, if both are set.
+func (cl Cleanup) removeOutput(ctx context.Context, location string) error {
+	if cl.RemoveOutput == nil || location == "" {
+		return nil
+	}
+
+	return cl.RemoveOutput(ctx, location)
+}
+
+// detached is a context with the values of another, which may be done, but
+// which is never done itself, so that a statement stopped as its context
+// was done can still be cleaned up.
+type detached struct {
+	context.Context
+}
+
+// Deadline returns no deadline.
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, as the context is never done.
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, as the context is never done.
+func (detached) Err() error {
+	return nil
+}
+
+// write runs a CTAS or INSERT INTO statement and gathers its statistics.
+// If ctx is done before the statement completes, it is stopped. incomplete
+// is set if the statement failed or was stopped, having possibly written
+// data, with the statistics so the caller can clean up.
+func (c Client) write(ctx context.Context, database, statement, output string) (ws WriteStatistics, incomplete bool, err error) {
+	ctx, span := c.startQuerySpan(ctx, database)
+	defer func() { endSpan(span, err) }()
+
+	q, err := c.doQuery(ctx, database, statement, output)
+	if err != nil {
+		return WriteStatistics{}, false, err
+	}
+
+	ws = WriteStatistics{QueryID: q.ID()}
+	span.SetAttribute(AttrQueryID, q.id)
+
+	status, waitErr := q.Wait(ctx)
+	if status.State != "" {
+		span.SetAttribute(AttrState, status.State)
+	}
+
+	var qerr *QueryError
+
+	switch {
+	case waitErr == nil, errors.As(waitErr, &qerr):
+	case waitErr == ctx.Err():
+		// left running, the statement would go on writing data
+		if stopErr := q.Stop(); stopErr != nil {
+			return ws, false, fmt.Errorf("%w (unable to stop query %s: %v)", waitErr, q.id, stopErr)
+		}
+	default:
+		return ws, false, waitErr
+	}
+
+	if ws.Statistics, err = q.Statistics(); err != nil {
+		return ws, waitErr != nil, err
+	}
+
+	if waitErr != nil {
+		return ws, true, waitErr
+	}
+
+	r, err := q.allResults(ctx)
+	if err != nil {
+		return ws, false, err
+	}
+
+	// the statement's result is a single row containing the number of rows written
+	rows := r.WithoutHeader().Rows
+	if len(rows) != 1 || len(rows[0]) != 1 {
+		return ws, false, unexpectedResult
+	}
+
+	if ws.RowsWritten, err = strconv.ParseInt(rows[0][0], 10, 64); err != nil {
+		return ws, false, fmt.Errorf("rows written: %w", unexpectedResult)
+	}
+
+	return ws, false, nil
+}
@@ -0,0 +1,307 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestCTASStatement(t *testing.T) {
+	cases := []struct {
+		id       string
+		ctas     athena.CTAS
+		expected string
+		err      error
+	}{
+		{
+			id:       "defaults",
+			ctas:     athena.CTAS{Table: "db.t", Query: "SELECT 1"},
+			expected: "CREATE TABLE \"db\".\"t\" AS\nSELECT 1",
+		},
+		{
+			id: "all options",
+			ctas: athena.CTAS{
+				Table:            "t",
+				Query:            "SELECT id, dt FROM src",
+				Format:           athena.FormatParquet,
+				ExternalLocation: "s3://bucket/t/",
+				PartitionedBy:    []string{"dt"},
+				BucketedBy:       []string{"id"},
+				BucketCount:      8,
+				WriteCompression: "SNAPPY",
+			},
+			expected: "CREATE TABLE \"t\" WITH (\n" +
+				"  format = 'PARQUET',\n" +
+				"  external_location = 's3://bucket/t/',\n" +
+				"  partitioned_by = ARRAY['dt'],\n" +
+				"  bucketed_by = ARRAY['id'],\n" +
+				"  bucket_count = 8,\n" +
+				"  write_compression = 'SNAPPY'\n" +
+				") AS\n" +
+				"SELECT id, dt FROM src",
+		},
+		{
+			id:       "csv",
+			ctas:     athena.CTAS{Table: "t", Query: "SELECT 1", Format: athena.FormatCSV},
+			expected: "CREATE TABLE \"t\" WITH (\n  format = 'TEXTFILE',\n  field_delimiter = ','\n) AS\nSELECT 1",
+		},
+		{
+			id:   "invalid input: empty query",
+			ctas: athena.CTAS{Table: "t"},
+			err:  athena.ErrEmptyQuery,
+		},
+		{
+			id:   "invalid input: bucket count without columns",
+			ctas: athena.CTAS{Table: "t", Query: "SELECT 1", BucketCount: 2},
+			err:  athena.ErrBucketingIncomplete,
+		},
+		{
+			id:   "invalid input: negative bucket count",
+			ctas: athena.CTAS{Table: "t", Query: "SELECT 1", BucketedBy: []string{"id"}, BucketCount: -1},
+			err:  athena.ErrInvalidBucketCount,
+		},
+		{
+			id:   "invalid input: bad location",
+			ctas: athena.CTAS{Table: "t", Query: "SELECT 1", ExternalLocation: "s3://"},
+			err:  athena.ErrS3NoBucket,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			s, err := tc.ctas.Statement()
+
+			if s != tc.expected {
+				tt.Errorf("Statement() == %v (want %v)", s, tc.expected)
+			}
+
+			if err != tc.err {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestInsertStatement(t *testing.T) {
+	s, err := athena.Insert{Table: "t", Columns: []string{"a", "b"}, Query: "SELECT 1, 2"}.Statement()
+
+	expected := "INSERT INTO \"t\" (\"a\", \"b\")\nSELECT 1, 2"
+	if s != expected {
+		t.Errorf("Statement() == %v (want %v)", s, expected)
+	}
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+}
+
+func TestCreateTableAs(t *testing.T) {
+	stats := &aa.QueryExecutionStatistics{
+		DataScannedInBytes:   aws.Int64(1024),
+		DataManifestLocation: aws.String("s3://output/jobid-manifest.csv"),
+	}
+
+	t.Run("happy path", func(tt *testing.T) {
+		mc := mockClient{
+			startQueryExecution:  startQueryExecution{id: "jobid"},
+			getQueryExecution:    getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output/jobid"},
+			queryExecutionDetail: queryExecutionDetail{statistics: stats},
+			getQueryResults:      mockResults([]string{"rows"}, []string{"42"}),
+		}
+
+		c := athena.NewCustomClient(mc)
+
+		actual, err := c.CreateTableAs(context.Background(), "database", athena.CTAS{Table: "t", Query: "SELECT 1"}, "s3://output")
+
+		expected := athena.WriteStatistics{
+			QueryID:     "jobid",
+			RowsWritten: 42,
+			Statistics: athena.Statistics{
+				DataScannedInBytes:   1024,
+				DataManifestLocation: "s3://output/jobid-manifest.csv",
+			},
+		}
+
+		if actual != expected {
+			tt.Errorf("CreateTableAs() == %v (want %v)", actual, expected)
+		}
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+	})
+
+	t.Run("unexpected result", func(tt *testing.T) {
+		mc := mockClient{
+			startQueryExecution:  startQueryExecution{id: "jobid"},
+			getQueryExecution:    getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output/jobid"},
+			queryExecutionDetail: queryExecutionDetail{statistics: stats},
+			getQueryResults:      mockResults([]string{"rows", "other"}, []string{"42", "x"}),
+		}
+
+		_, err := athena.NewCustomClient(mc).CreateTableAs(context.Background(), "database", athena.CTAS{Table: "t", Query: "SELECT 1"}, "s3://output")
+		if err != athena.ErrUnexpectedResult {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrUnexpectedResult)
+		}
+	})
+
+	t.Run("cancellation stops and removes output", func(tt *testing.T) {
+		var stopped, removed []string
+
+		mc := mockClient{
+			startQueryExecution:  startQueryExecution{id: "jobid"},
+			getQueryExecution:    getQueryExecution{state: "RUNNING", outLocation: "s3://output/jobid"},
+			queryExecutionDetail: queryExecutionDetail{statistics: stats},
+			listing:              listing{stopped: &stopped},
+		}
+
+		ctas := athena.CTAS{
+			Table: "t",
+			Query: "SELECT 1",
+			Cleanup: athena.Cleanup{
+				RemoveOutput: func(ctx context.Context, location string) error {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+
+					removed = append(removed, location)
+					return nil
+				},
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		c := athena.NewCustomClient(mc).WithPollInterval(time.Millisecond)
+
+		_, err := c.CreateTableAs(ctx, "database", ctas, "s3://output")
+		if err != context.DeadlineExceeded {
+			tt.Errorf("err == %v (want %v)", err, context.DeadlineExceeded)
+		}
+
+		if !reflect.DeepEqual(stopped, []string{"jobid"}) {
+			tt.Errorf("stopped == %v (want [jobid])", stopped)
+		}
+
+		if !reflect.DeepEqual(removed, []string{"s3://output/jobid-manifest.csv"}) {
+			tt.Errorf("removed == %v (want [s3://output/jobid-manifest.csv])", removed)
+		}
+	})
+
+	t.Run("failure removes output", func(tt *testing.T) {
+		var queries, removed []string
+
+		mc := mockClient{
+			startQueryExecution:  startQueryExecution{id: "jobid", queries: &queries},
+			getQueryExecution:    getQueryExecution{state: "FAILED", outLocation: "s3://output/jobid"},
+			queryExecutionDetail: queryExecutionDetail{reason: "boom", statistics: stats},
+		}
+
+		ctas := athena.CTAS{
+			Table:            "t",
+			Query:            "SELECT 1",
+			ExternalLocation: "s3://bucket/t/",
+			Cleanup: athena.Cleanup{
+				RemoveOutput: func(_ context.Context, location string) error {
+					removed = append(removed, location)
+					return nil
+				},
+			},
+		}
+
+		c := athena.NewCustomClient(mc)
+
+		_, err := c.CreateTableAs(context.Background(), "database", ctas, "s3://output")
+
+		expectedErr := &athena.QueryError{ID: "jobid", State: "FAILED", Reason: "boom"}
+		if !reflect.DeepEqual(err, expectedErr) {
+			tt.Errorf("err == %v (want %v)", err, expectedErr)
+		}
+
+		if !reflect.DeepEqual(removed, []string{"s3://bucket/t/"}) {
+			tt.Errorf("removed == %v (want [s3://bucket/t/])", removed)
+		}
+
+		if len(queries) != 1 {
+			tt.Errorf("len(queries) == %d (want 1)", len(queries))
+		}
+	})
+
+	t.Run("failure drops table", func(tt *testing.T) {
+		var queries []string
+
+		mc := mockClient{
+			startQueryExecution: startQueryExecution{id: "jobid", queries: &queries},
+			getQueryExecution:   getQueryExecution{state: "FAILED", outLocation: "s3://output/jobid"},
+		}
+
+		ctas := athena.CTAS{Table: "db.t", Query: "SELECT 1", Cleanup: athena.Cleanup{DropTable: true}}
+
+		c := athena.NewCustomClient(mc)
+
+		_, err := c.CreateTableAs(context.Background(), "database", ctas, "s3://output")
+
+		// the mock fails every query, including the DROP TABLE
+		var cerr *athena.CleanupError
+		if !errors.As(err, &cerr) {
+			tt.Fatalf("err == %v (want *CleanupError)", err)
+		}
+
+		var qerr *athena.QueryError
+		if !errors.As(err, &qerr) {
+			tt.Errorf("err == %v (want to unwrap to *QueryError)", err)
+		}
+
+		expectedQueries := []string{"CREATE TABLE \"db\".\"t\" AS\nSELECT 1", "DROP TABLE IF EXISTS `db`.`t`"}
+		if !reflect.DeepEqual(queries, expectedQueries) {
+			tt.Errorf("queries == %q (want %q)", queries, expectedQueries)
+		}
+	})
+}
+
+func TestInsertInto(t *testing.T) {
+	var removed []string
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid"},
+		getQueryExecution:   getQueryExecution{state: "CANCELLED", outLocation: "s3://output/jobid"},
+		queryExecutionDetail: queryExecutionDetail{
+			statistics: &aa.QueryExecutionStatistics{DataManifestLocation: aws.String("s3://output/jobid-manifest.csv")},
+		},
+	}
+
+	insert := athena.Insert{
+		Table: "t",
+		Query: "SELECT 1",
+		Cleanup: athena.Cleanup{
+			RemoveOutput: func(_ context.Context, location string) error {
+				removed = append(removed, location)
+				return errors.New("remove failed")
+			},
+		},
+	}
+
+	c := athena.NewCustomClient(mc)
+
+	ws, err := c.InsertInto(context.Background(), "database", insert, "s3://output")
+
+	var cerr *athena.CleanupError
+	if !errors.As(err, &cerr) {
+		t.Errorf("err == %v (want *CleanupError)", err)
+	}
+
+	if ws.QueryID != "jobid" {
+		t.Errorf("QueryID == %v (want jobid)", ws.QueryID)
+	}
+
+	if !reflect.DeepEqual(removed, []string{"s3://output/jobid-manifest.csv"}) {
+		t.Errorf("removed == %v (want [s3://output/jobid-manifest.csv])", removed)
+	}
+}
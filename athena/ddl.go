@@ -0,0 +1,589 @@
+package athena
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors for invalid table definitions
+const (
+	noColumns         = constError("table must have at least one column")
+	emptyColumnType   = constError("column type must not be an empty string")
+	unknownFormat     = constError("unknown table format")
+	notStruct         = constError("value must be a struct or pointer to struct")
+	unsupportedType   = constError("type cannot be mapped to an Athena column type")
+	unknownProjection = constError("unknown partition projection type")
+	projectionNotKey  = constError("partition projection column must be a partition key")
+	emptyEnumValues   = constError("enum partition projection must have at least one value")
+	emptyRange        = constError("partition projection range must not be an empty string")
+)
+
+// Format specifies how the data of a table is stored in S3.
+type Format string
+
+// Formats supported by Athena.
+const (
+	FormatJSON    Format = "JSON"
+	FormatCSV     Format = "CSV"
+	FormatParquet Format = "PARQUET"
+	FormatORC     Format = "ORC"
+	FormatAvro    Format = "AVRO"
+)
+
+// storage returns the ROW FORMAT and STORED AS clauses of format,
+// along with its default SERDEPROPERTIES.
+func (f Format) storage() (serde string, props map[string]string, storedAs string, err error) {
+	switch f {
+	case FormatJSON:
+		return "org.openx.data.jsonserde.JsonSerDe", nil, "TEXTFILE", nil
+	case FormatCSV:
+		props = map[string]string{"separatorChar": ",", "quoteChar": `"`, "escapeChar": `\`}
+		return "org.apache.hadoop.hive.serde2.OpenCSVSerde", props, "TEXTFILE", nil
+	case FormatParquet:
+		return "org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe", nil, "PARQUET", nil
+	case FormatORC:
+		return "org.apache.hadoop.hive.ql.io.orc.OrcSerde", nil, "ORC", nil
+	case FormatAvro:
+		return "org.apache.hadoop.hive.serde2.avro.AvroSerDe", nil, "AVRO", nil
+	}
+
+	return "", nil, "", unknownFormat
+}
+
+// ProjectionType is the type of a projected partition key.
+type ProjectionType string
+
+// Partition projection types supported by Athena.
+const (
+	ProjectionEnum     ProjectionType = "enum"
+	ProjectionInteger  ProjectionType = "integer"
+	ProjectionDate     ProjectionType = "date"
+	ProjectionInjected ProjectionType = "injected"
+)
+
+// Projection configures partition projection for a partition key, so that
+// Athena calculates partitions rather than looking them up in the catalog.
+//
+// See https://docs.aws.amazon.com/athena/latest/ug/partition-projection.html
+type Projection struct {
+	// Column is the name of the partition key.
+	Column string
+
+	// Type is the kind of projection.
+	Type ProjectionType
+
+	// Values are the possible values of an enum projection.
+	Values []string
+
+	// Range is the inclusive lower and upper bound of an integer or date
+	// projection separated by a comma, e.g. "2020-01-01,NOW".
+	Range string
+
+	// Format is the date format of a date projection, e.g. "yyyy-MM-dd".
+	Format string
+
+	// Interval is the step between successive integer or date values.
+	Interval int
+
+	// IntervalUnit is the unit of Interval for a date projection, e.g. DAYS.
+	IntervalUnit string
+
+	// Digits is the minimum number of digits of an integer projection,
+	// which is zero padded to this length.
+	Digits int
+}
+
+// properties returns the table properties of the projection.
+func (p Projection) properties() (map[string]string, error) {
+	prefix := "projection." + p.Column + "."
+	props := map[string]string{prefix + "type": string(p.Type)}
+
+	switch p.Type {
+	case ProjectionEnum:
+		if len(p.Values) == 0 {
+			return nil, emptyEnumValues
+		}
+
+		props[prefix+"values"] = strings.Join(p.Values, ",")
+	case ProjectionInteger, ProjectionDate:
+		if p.Range == "" {
+			return nil, emptyRange
+		}
+
+		props[prefix+"range"] = p.Range
+
+		if p.Interval > 0 {
+			props[prefix+"interval"] = fmt.Sprint(p.Interval)
+		}
+
+		if p.Type == ProjectionInteger && p.Digits > 0 {
+			props[prefix+"digits"] = fmt.Sprint(p.Digits)
+		}
+
+		if p.Type == ProjectionDate {
+			if p.Format != "" {
+				props[prefix+"format"] = p.Format
+			}
+
+			if p.IntervalUnit != "" {
+				props[prefix+"interval.unit"] = p.IntervalUnit
+			}
+		}
+	case ProjectionInjected:
+	default:
+		return nil, unknownProjection
+	}
+
+	return props, nil
+}
+
+// TableDefinition describes an external table to be created in Athena.
+type TableDefinition struct {
+	// Name is the name of the table, optionally qualified by database.
+	Name string
+
+	// Comment is an optional table comment.
+	Comment string
+
+	// Columns are the data columns of the table.
+	Columns []ColumnDescription
+
+	// PartitionKeys are the columns the table is partitioned by.
+	PartitionKeys []ColumnDescription
+
+	// Format specifies the SerDe and storage format of the table's data.
+	Format Format
+
+	// SerDeProperties override or add to the default properties of the
+	// Format's SerDe, e.g. separatorChar for CSV.
+	SerDeProperties map[string]string
+
+	// Location is the S3 URL of the table's data.
+	Location string
+
+	// Properties are additional TBLPROPERTIES, e.g. skip.header.line.count.
+	Properties map[string]string
+
+	// Projections enable partition projection for the given partition keys.
+	Projections []Projection
+
+	// LocationTemplate is the storage.location.template of a projected
+	// table, e.g. s3://bucket/prefix/${dt}/
+	LocationTemplate string
+
+	// IfNotExists avoids an error if the table already exists.
+	IfNotExists bool
+}
+
+// DDL returns the CREATE EXTERNAL TABLE statement for the table.
+func (td TableDefinition) DDL() (string, error) {
+	name, err := quoteDDLTable(td.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(td.Columns) == 0 {
+		return "", noColumns
+	}
+
+	if err := validS3URL(td.Location); err != nil {
+		return "", err
+	}
+
+	serde, serdeProps, storedAs, err := td.Format.storage()
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range td.SerDeProperties {
+		if serdeProps == nil {
+			serdeProps = make(map[string]string, len(td.SerDeProperties))
+		}
+
+		serdeProps[k] = v
+	}
+
+	props, err := td.properties()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	b.WriteString("CREATE EXTERNAL TABLE ")
+	if td.IfNotExists {
+		b.WriteString("IF NOT EXISTS ")
+	}
+	b.WriteString(name)
+
+	if err := writeColumns(&b, td.Columns); err != nil {
+		return "", err
+	}
+
+	if td.Comment != "" {
+		b.WriteString("\nCOMMENT " + quoteDDLLiteral(td.Comment))
+	}
+
+	if len(td.PartitionKeys) > 0 {
+		b.WriteString("\nPARTITIONED BY")
+		if err := writeColumns(&b, td.PartitionKeys); err != nil {
+			return "", err
+		}
+	}
+
+	b.WriteString("\nROW FORMAT SERDE " + quoteDDLLiteral(serde))
+
+	if len(serdeProps) > 0 {
+		b.WriteString("\nWITH SERDEPROPERTIES")
+		writeProperties(&b, serdeProps)
+	}
+
+	b.WriteString("\nSTORED AS " + storedAs)
+	b.WriteString("\nLOCATION " + quoteDDLLiteral(td.Location))
+
+	if len(props) > 0 {
+		b.WriteString("\nTBLPROPERTIES")
+		writeProperties(&b, props)
+	}
+
+	return b.String(), nil
+}
+
+// properties returns the TBLPROPERTIES of the table, including those
+// needed for partition projection.
+func (td TableDefinition) properties() (map[string]string, error) {
+	props := make(map[string]string, len(td.Properties))
+	for k, v := range td.Properties {
+		props[k] = v
+	}
+
+	if len(td.Projections) == 0 {
+		return props, nil
+	}
+
+	keys := make(map[string]bool, len(td.PartitionKeys))
+	for _, pk := range td.PartitionKeys {
+		keys[pk.Name] = true
+	}
+
+	props["projection.enabled"] = "true"
+
+	for _, p := range td.Projections {
+		if !keys[p.Column] {
+			return nil, projectionNotKey
+		}
+
+		pp, err := p.properties()
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range pp {
+			props[k] = v
+		}
+	}
+
+	if td.LocationTemplate != "" {
+		if err := validS3URL(td.LocationTemplate); err != nil {
+			return nil, err
+		}
+
+		props["storage.location.template"] = td.LocationTemplate
+	}
+
+	return props, nil
+}
+
+// writeColumns writes a parenthesised list of column definitions to b.
+func writeColumns(b *strings.Builder, columns []ColumnDescription) error {
+	b.WriteString(" (")
+
+	for i, c := range columns {
+		name, err := quoteDDLIdentifier(c.Name)
+		if err != nil {
+			return err
+		}
+
+		if c.Type == "" {
+			return emptyColumnType
+		}
+
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		b.WriteString("\n  " + name + " " + c.Type)
+
+		if c.Comment != "" {
+			b.WriteString(" COMMENT " + quoteDDLLiteral(c.Comment))
+		}
+	}
+
+	b.WriteString("\n)")
+
+	return nil
+}
+
+// writeProperties writes a parenthesised list of key value pairs to b,
+// sorted by key so that the statement is deterministic.
+func writeProperties(b *strings.Builder, props map[string]string) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	b.WriteString(" (")
+
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		b.WriteString("\n  " + quoteDDLLiteral(k) + "=" + quoteDDLLiteral(props[k]))
+	}
+
+	b.WriteString("\n)")
+}
+
+// ColumnsFromResult returns column definitions matching the columns of
+// a query result, e.g. to create a table over the output of a query.
+//
+// Athena reports result columns with Presto types, which are mapped to
+// their Hive equivalents. Complex types (arrays, maps and rows) cannot be
+// mapped as results do not describe their element types.
+func ColumnsFromResult(columns []Column) ([]ColumnDescription, error) {
+	cds := make([]ColumnDescription, 0, len(columns))
+
+	for _, c := range columns {
+		var t string
+
+		switch c.Type {
+		case "varchar", "char", "json":
+			t = "string"
+		case "integer":
+			t = "int"
+		case "real":
+			t = "float"
+		case "varbinary":
+			t = "binary"
+		case "tinyint", "smallint", "bigint", "double", "boolean", "date", "timestamp":
+			t = c.Type
+		case "decimal":
+			t = fmt.Sprintf("decimal(%d,%d)", c.Precision, c.Scale)
+		default:
+			return nil, fmt.Errorf("column %s: %s: %w", c.Name, c.Type, unsupportedType)
+		}
+
+		cds = append(cds, ColumnDescription{Name: c.Name, Type: t})
+	}
+
+	return cds, nil
+}
+
+// TableFromStruct returns a definition of a table named name whose columns
+// correspond to the exported fields of the struct v.
+//
+// Fields are mapped to columns using the "athena" struct tag, which names
+// the column and may mark it as a partition key:
+//
+//	Year  int    `athena:"year,partition"`
+//	Email string `athena:"email_address" athenatype:"varchar(255)" comment:"primary contact"`
+//	Debug bool   `athena:"-"`
+//
+// Untagged fields are named after the lower cased field name. Column types
+// are derived from Go types (structs, slices and maps map to struct, array
+// and map columns) unless overridden with the "athenatype" tag.
+//
+// The Format and Location of the returned definition must be set before
+// calling DDL.
+func TableFromStruct(name string, v interface{}) (TableDefinition, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return TableDefinition{}, notStruct
+	}
+
+	td := TableDefinition{Name: name}
+	visiting := map[reflect.Type]bool{t: true}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		column, partition, skip := parseTag(f)
+		if skip {
+			continue
+		}
+
+		typ := f.Tag.Get("athenatype")
+		if typ == "" {
+			var err error
+
+			typ, err = hiveType(f.Type, visiting)
+			if err != nil {
+				return TableDefinition{}, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+		}
+
+		cd := ColumnDescription{Name: column, Type: typ, Comment: f.Tag.Get("comment")}
+
+		if partition {
+			td.PartitionKeys = append(td.PartitionKeys, cd)
+		} else {
+			td.Columns = append(td.Columns, cd)
+		}
+	}
+
+	return td, nil
+}
+
+// parseTag returns the column name of a struct field, whether it is
+// a partition key, and whether it should be skipped.
+func parseTag(f reflect.StructField) (name string, partition bool, skip bool) {
+	tag := f.Tag.Get("athena")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+
+	for _, opt := range parts[1:] {
+		if opt == "partition" {
+			partition = true
+		}
+	}
+
+	return name, partition, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// hiveType returns the Hive column type corresponding to the Go type t.
+// visiting holds the struct types whose fields are being mapped; a struct
+// containing itself cannot be mapped, as Hive types are finite.
+func hiveType(t reflect.Type, visiting map[reflect.Type]bool) (string, error) {
+	if t == timeType {
+		return "timestamp", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return hiveType(t.Elem(), visiting)
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8:
+		return "tinyint", nil
+	case reflect.Int16, reflect.Uint8:
+		return "smallint", nil
+	case reflect.Int32, reflect.Uint16:
+		return "int", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "binary", nil
+		}
+
+		e, err := hiveType(t.Elem(), visiting)
+		if err != nil {
+			return "", err
+		}
+
+		return "array<" + e + ">", nil
+	case reflect.Map:
+		k, err := hiveType(t.Key(), visiting)
+		if err != nil {
+			return "", err
+		}
+
+		e, err := hiveType(t.Elem(), visiting)
+		if err != nil {
+			return "", err
+		}
+
+		return "map<" + k + "," + e + ">", nil
+	case reflect.Struct:
+		if visiting[t] {
+			return "", unsupportedType
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		fields := make([]string, 0, t.NumField())
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+
+			if f.PkgPath != "" {
+				continue
+			}
+
+			name, _, skip := parseTag(f)
+			if skip {
+				continue
+			}
+
+			typ := f.Tag.Get("athenatype")
+			if typ == "" {
+				var err error
+
+				typ, err = hiveType(f.Type, visiting)
+				if err != nil {
+					return "", err
+				}
+			}
+
+			fields = append(fields, name+":"+typ)
+		}
+
+		return "struct<" + strings.Join(fields, ",") + ">", nil
+	}
+
+	return "", unsupportedType
+}
+
+// DoDDL runs the DDL statement (e.g. one returned by TableDefinition.DDL)
+// on database and waits for it to complete. See DoQuery for the meaning
+// of output.
+func (c Client) DoDDL(ctx context.Context, database, statement, output string) (err error) {
+	ctx, span := c.startQuerySpan(ctx, database)
+	defer func() { endSpan(span, err) }()
+
+	q, err := c.doQuery(ctx, database, statement, output)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttribute(AttrQueryID, q.id)
+
+	status, err := q.Wait(ctx)
+	if status.State != "" {
+		span.SetAttribute(AttrState, status.State)
+	}
+
+	return err
+}
@@ -0,0 +1,346 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func TestTableDefinitionDDL(t *testing.T) {
+	columns := []athena.ColumnDescription{
+		{Name: "id", Type: "bigint", Comment: "it's the id"},
+		{Name: "name", Type: "string"},
+	}
+
+	cases := []struct {
+		id       string
+		td       athena.TableDefinition
+		expected string
+		err      error
+	}{
+		{
+			id: "parquet",
+			td: athena.TableDefinition{
+				Name:     "db.events",
+				Columns:  columns,
+				Format:   athena.FormatParquet,
+				Location: "s3://bucket/events/",
+			},
+			expected: "CREATE EXTERNAL TABLE `db`.`events` (\n" +
+				"  `id` bigint COMMENT 'it\\'s the id',\n" +
+				"  `name` string\n" +
+				")\n" +
+				"ROW FORMAT SERDE 'org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe'\n" +
+				"STORED AS PARQUET\n" +
+				"LOCATION 's3://bucket/events/'",
+		},
+		{
+			id: "csv with partitions and properties",
+			td: athena.TableDefinition{
+				Name:            "events",
+				Comment:         "events",
+				Columns:         columns,
+				PartitionKeys:   []athena.ColumnDescription{{Name: "dt", Type: "string"}},
+				Format:          athena.FormatCSV,
+				SerDeProperties: map[string]string{"separatorChar": "\t"},
+				Location:        "s3://bucket/events/",
+				Properties:      map[string]string{"skip.header.line.count": "1"},
+				IfNotExists:     true,
+			},
+			expected: "CREATE EXTERNAL TABLE IF NOT EXISTS `events` (\n" +
+				"  `id` bigint COMMENT 'it\\'s the id',\n" +
+				"  `name` string\n" +
+				")\n" +
+				"COMMENT 'events'\n" +
+				"PARTITIONED BY (\n" +
+				"  `dt` string\n" +
+				")\n" +
+				"ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.OpenCSVSerde'\n" +
+				"WITH SERDEPROPERTIES (\n" +
+				"  'escapeChar'='\\\\',\n" +
+				"  'quoteChar'='\"',\n" +
+				"  'separatorChar'='\t'\n" +
+				")\n" +
+				"STORED AS TEXTFILE\n" +
+				"LOCATION 's3://bucket/events/'\n" +
+				"TBLPROPERTIES (\n" +
+				"  'skip.header.line.count'='1'\n" +
+				")",
+		},
+		{
+			id: "json with projection",
+			td: athena.TableDefinition{
+				Name:          "events",
+				Columns:       columns[1:],
+				PartitionKeys: []athena.ColumnDescription{{Name: "dt", Type: "string"}},
+				Format:        athena.FormatJSON,
+				Location:      "s3://bucket/events/",
+				Projections: []athena.Projection{
+					{
+						Column:       "dt",
+						Type:         athena.ProjectionDate,
+						Range:        "2020-01-01,NOW",
+						Format:       "yyyy-MM-dd",
+						Interval:     1,
+						IntervalUnit: "DAYS",
+					},
+				},
+				LocationTemplate: "s3://bucket/events/${dt}/",
+			},
+			expected: "CREATE EXTERNAL TABLE `events` (\n" +
+				"  `name` string\n" +
+				")\n" +
+				"PARTITIONED BY (\n" +
+				"  `dt` string\n" +
+				")\n" +
+				"ROW FORMAT SERDE 'org.openx.data.jsonserde.JsonSerDe'\n" +
+				"STORED AS TEXTFILE\n" +
+				"LOCATION 's3://bucket/events/'\n" +
+				"TBLPROPERTIES (\n" +
+				"  'projection.dt.format'='yyyy-MM-dd',\n" +
+				"  'projection.dt.interval'='1',\n" +
+				"  'projection.dt.interval.unit'='DAYS',\n" +
+				"  'projection.dt.range'='2020-01-01,NOW',\n" +
+				"  'projection.dt.type'='date',\n" +
+				"  'projection.enabled'='true',\n" +
+				"  'storage.location.template'='s3://bucket/events/${dt}/'\n" +
+				")",
+		},
+		{
+			id:  "invalid input: no columns",
+			td:  athena.TableDefinition{Name: "t", Format: athena.FormatORC, Location: "s3://bucket/"},
+			err: athena.ErrNoColumns,
+		},
+		{
+			id:  "invalid input: bad location",
+			td:  athena.TableDefinition{Name: "t", Columns: columns, Format: athena.FormatORC, Location: "http://bucket/"},
+			err: athena.ErrS3BadPrefix,
+		},
+		{
+			id:  "invalid input: unknown format",
+			td:  athena.TableDefinition{Name: "t", Columns: columns, Location: "s3://bucket/"},
+			err: athena.ErrUnknownFormat,
+		},
+		{
+			id: "invalid input: empty column type",
+			td: athena.TableDefinition{
+				Name:     "t",
+				Columns:  []athena.ColumnDescription{{Name: "id"}},
+				Format:   athena.FormatAvro,
+				Location: "s3://bucket/",
+			},
+			err: athena.ErrEmptyColumnType,
+		},
+		{
+			id: "invalid input: projection of non partition key",
+			td: athena.TableDefinition{
+				Name:        "t",
+				Columns:     columns,
+				Format:      athena.FormatORC,
+				Location:    "s3://bucket/",
+				Projections: []athena.Projection{{Column: "id", Type: athena.ProjectionInjected}},
+			},
+			err: athena.ErrProjectionNotKey,
+		},
+		{
+			id: "invalid input: empty enum",
+			td: athena.TableDefinition{
+				Name:          "t",
+				Columns:       columns,
+				PartitionKeys: []athena.ColumnDescription{{Name: "region", Type: "string"}},
+				Format:        athena.FormatORC,
+				Location:      "s3://bucket/",
+				Projections:   []athena.Projection{{Column: "region", Type: athena.ProjectionEnum}},
+			},
+			err: athena.ErrEmptyEnumValues,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			ddl, err := tc.td.DDL()
+
+			if ddl != tc.expected {
+				tt.Errorf("DDL() == %v (want %v)", ddl, tc.expected)
+			}
+
+			if err != tc.err {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestTableFromStruct(t *testing.T) {
+	type address struct {
+		Street string
+		Zip    int32 `athena:"postcode"`
+	}
+
+	type record struct {
+		ID       int64             `athena:"id" comment:"the id"`
+		Name     string            `athena:"name"`
+		Score    float64           `athena:"score"`
+		Price    string            `athena:"price" athenatype:"decimal(10,2)"`
+		Tags     []string          `athena:"tags"`
+		Attrs    map[string]string `athena:"attrs"`
+		Address  *address          `athena:"address"`
+		Seen     time.Time         `athena:"seen"`
+		Raw      []byte            `athena:"raw"`
+		Active   bool
+		Ignored  string `athena:"-"`
+		Year     int    `athena:"year,partition"`
+		internal string
+	}
+
+	expected := athena.TableDefinition{
+		Name: "records",
+		Columns: []athena.ColumnDescription{
+			{Name: "id", Type: "bigint", Comment: "the id"},
+			{Name: "name", Type: "string"},
+			{Name: "score", Type: "double"},
+			{Name: "price", Type: "decimal(10,2)"},
+			{Name: "tags", Type: "array<string>"},
+			{Name: "attrs", Type: "map<string,string>"},
+			{Name: "address", Type: "struct<street:string,postcode:int>"},
+			{Name: "seen", Type: "timestamp"},
+			{Name: "raw", Type: "binary"},
+			{Name: "active", Type: "boolean"},
+		},
+		PartitionKeys: []athena.ColumnDescription{
+			{Name: "year", Type: "bigint"},
+		},
+	}
+
+	t.Run("happy path", func(tt *testing.T) {
+		actual, err := athena.TableFromStruct("records", &record{})
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("TableFromStruct() == %v (want %v)", actual, expected)
+		}
+	})
+
+	t.Run("not a struct", func(tt *testing.T) {
+		_, err := athena.TableFromStruct("records", 1)
+
+		if err != athena.ErrNotStruct {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrNotStruct)
+		}
+	})
+
+	t.Run("unsupported type", func(tt *testing.T) {
+		_, err := athena.TableFromStruct("records", struct{ C chan int }{})
+
+		if !errors.Is(err, athena.ErrUnsupportedType) {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrUnsupportedType)
+		}
+	})
+
+	t.Run("recursive type", func(tt *testing.T) {
+		for _, v := range []interface{}{node{}, tree{}} {
+			_, err := athena.TableFromStruct("records", v)
+
+			if !errors.Is(err, athena.ErrUnsupportedType) {
+				tt.Errorf("%T: err == %v (want %v)", v, err, athena.ErrUnsupportedType)
+			}
+		}
+	})
+
+	t.Run("repeated type", func(tt *testing.T) {
+		type point struct{ X, Y int }
+
+		actual, err := athena.TableFromStruct("records", struct{ From, To point }{})
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		expected := "struct<x:bigint,y:bigint>"
+		if len(actual.Columns) != 2 || actual.Columns[0].Type != expected || actual.Columns[1].Type != expected {
+			tt.Errorf("Columns == %v (want two %s)", actual.Columns, expected)
+		}
+	})
+}
+
+// node contains itself, and tree itself through branch.
+type node struct{ Children []node }
+type tree struct{ Root *branch }
+type branch struct{ Trees map[string]tree }
+
+func TestColumnsFromResult(t *testing.T) {
+	t.Run("happy path", func(tt *testing.T) {
+		columns := []athena.Column{
+			{Name: "a", Type: "varchar"},
+			{Name: "b", Type: "integer"},
+			{Name: "c", Type: "decimal", Precision: 10, Scale: 2},
+			{Name: "d", Type: "timestamp"},
+		}
+
+		expected := []athena.ColumnDescription{
+			{Name: "a", Type: "string"},
+			{Name: "b", Type: "int"},
+			{Name: "c", Type: "decimal(10,2)"},
+			{Name: "d", Type: "timestamp"},
+		}
+
+		actual, err := athena.ColumnsFromResult(columns)
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("ColumnsFromResult() == %v (want %v)", actual, expected)
+		}
+	})
+
+	t.Run("complex type", func(tt *testing.T) {
+		_, err := athena.ColumnsFromResult([]athena.Column{{Name: "a", Type: "array"}})
+
+		if !errors.Is(err, athena.ErrUnsupportedType) {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrUnsupportedType)
+		}
+	})
+}
+
+func TestDoDDL(t *testing.T) {
+	cases := []struct {
+		id    string
+		state string
+		err   error
+	}{
+		{"succeeds", "SUCCEEDED", nil},
+		{"fails", "FAILED", &athena.QueryError{ID: "jobid", State: "FAILED"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			var queries []string
+
+			mc := mockClient{
+				startQueryExecution: startQueryExecution{id: "jobid", queries: &queries},
+				getQueryExecution:   getQueryExecution{state: tc.state, outLocation: "s3://output/jobid.txt"},
+			}
+
+			c := athena.NewCustomClient(mc)
+
+			err := c.DoDDL(context.Background(), "database", "CREATE EXTERNAL TABLE x", "s3://output")
+
+			if !reflect.DeepEqual(err, tc.err) {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+
+			if len(queries) != 1 || queries[0] != "CREATE EXTERNAL TABLE x" {
+				tt.Errorf("queries == %v (want [CREATE EXTERNAL TABLE x])", queries)
+			}
+		})
+	}
+}
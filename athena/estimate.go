@@ -0,0 +1,256 @@
+package athena
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultPricePerTB is the price in US dollars per terabyte scanned used
+// by Client.Cost unless overridden with WithPricePerTB.
+const DefaultPricePerTB = 5.0
+
+// Athena bills the data scanned by a query rounded up to the nearest
+// megabyte, with a minimum of 10 MB.
+const (
+	billingUnit    = 1 << 20
+	billingMinimum = 10 * billingUnit
+	terabyte       = 1 << 40
+)
+
+// Cost returns the cost in US dollars of a query which scanned bytes,
+// applying Athena's billing rules. Queries which scanned nothing, such as
+// DDL statements, cost nothing.
+func Cost(bytes int64, pricePerTB float64) float64 {
+	if bytes <= 0 {
+		return 0
+	}
+
+	billed := (bytes + billingUnit - 1) / billingUnit * billingUnit
+	if billed < billingMinimum {
+		billed = billingMinimum
+	}
+
+	return float64(billed) / terabyte * pricePerTB
+}
+
+// WithPricePerTB returns a copy of the client which computes costs with
+// the price in US dollars per terabyte scanned, e.g. for other regions.
+func (c Client) WithPricePerTB(price float64) Client {
+	return c.withOptions(func(o *options) {
+		o.pricePerTB = price
+	})
+}
+
+// Cost returns the cost in US dollars of a query which scanned bytes, at
+// the client's price; see Cost.
+func (c Client) Cost(bytes int64) float64 {
+	price := c.options().pricePerTB
+	if price <= 0 {
+		price = DefaultPricePerTB
+	}
+
+	return Cost(bytes, price)
+}
+
+// Estimate describes what a query would scan, as planned by Athena.
+type Estimate struct {
+	// Tables are the tables the query reads.
+	Tables []TableScan `json:"tables"`
+
+	// Estimated is false if Athena has no statistics for one or more of the
+	// tables, in which case the bytes and cost are not known.
+	Estimated bool `json:"estimated"`
+
+	// EstimatedBytes is the estimated amount of data the query will scan.
+	EstimatedBytes int64 `json:"estimated_bytes,omitempty"`
+
+	// EstimatedCost is the estimated cost of the query in US dollars.
+	EstimatedCost float64 `json:"estimated_cost,omitempty"`
+}
+
+// TableScan describes the data a query reads from a table.
+type TableScan struct {
+	Catalog string `json:"catalog"`
+	Schema  string `json:"schema"`
+	Table   string `json:"table"`
+
+	// Constraints restrict the values of columns read, such as partition
+	// keys; without any, every partition of the table is read.
+	Constraints []ColumnConstraint `json:"constraints,omitempty"`
+
+	// EstimatedBytes is the estimated amount of data read, if Athena has
+	// statistics for the table, otherwise -1.
+	EstimatedBytes int64 `json:"estimated_bytes"`
+}
+
+// ColumnConstraint restricts the values of a column a query reads.
+type ColumnConstraint struct {
+	Column string `json:"column"`
+	Type   string `json:"type"`
+
+	// Ranges are the ranges of values read, e.g. [2019-01-01, 2019-02-01)
+	// or = 2019-01-01.
+	Ranges []string `json:"ranges"`
+}
+
+// explainFloat is a number in an EXPLAIN plan, where unknown values are
+// given as the string "NaN".
+type explainFloat float64
+
+func (f *explainFloat) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*f = explainFloat(math.NaN())
+		return nil
+	}
+
+	return json.Unmarshal(b, (*float64)(f))
+}
+
+type explainBound struct {
+	Value string `json:"value"`
+	Bound string `json:"bound"`
+}
+
+// explainIO is the output of EXPLAIN (TYPE IO, FORMAT JSON).
+type explainIO struct {
+	InputTableColumnInfos []struct {
+		Table struct {
+			Catalog     string `json:"catalog"`
+			SchemaTable struct {
+				Schema string `json:"schema"`
+				Table  string `json:"table"`
+			} `json:"schemaTable"`
+		} `json:"table"`
+		ColumnConstraints []struct {
+			ColumnName string `json:"columnName"`
+			Type       string `json:"type"`
+			Domain     struct {
+				Ranges []struct {
+					Low  explainBound `json:"low"`
+					High explainBound `json:"high"`
+				} `json:"ranges"`
+			} `json:"domain"`
+		} `json:"columnConstraints"`
+		Estimate struct {
+			OutputSizeInBytes explainFloat `json:"outputSizeInBytes"`
+		} `json:"estimate"`
+	} `json:"inputTableColumnInfos"`
+}
+
+// Explain returns an EXPLAIN statement describing the tables query reads
+// and estimating how much of them it scans.
+func Explain(query string) (string, error) {
+	if query == "" {
+		return "", emptyQuery
+	}
+
+	return "EXPLAIN (TYPE IO, FORMAT JSON)\n" + query, nil
+}
+
+// Estimate validates query by planning it on database without running it,
+// reporting the tables and partitions it reads and, if Athena has
+// statistics for them, the data it would scan and its cost at the client's
+// price. A *QueryError describes why an invalid query could not be planned.
+// See DoQuery for the meaning of output.
+func (c Client) Estimate(ctx context.Context, database, query, output string) (Estimate, error) {
+	statement, err := Explain(query)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	r, err := c.Run(ctx, database, statement, output)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	lines := make([]string, 0, len(r.Rows))
+	for _, row := range r.WithoutHeader().Rows {
+		if len(row) > 0 {
+			lines = append(lines, row[0])
+		}
+	}
+
+	e, err := parseExplain(strings.Join(lines, "\n"))
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	if e.Estimated {
+		e.EstimatedCost = c.Cost(e.EstimatedBytes)
+	}
+
+	return e, nil
+}
+
+// parseExplain parses the output of EXPLAIN (TYPE IO, FORMAT JSON).
+func parseExplain(plan string) (Estimate, error) {
+	var io explainIO
+	if err := json.Unmarshal([]byte(plan), &io); err != nil {
+		return Estimate{}, fmt.Errorf("%w: %v", unexpectedResult, err)
+	}
+
+	e := Estimate{Estimated: true}
+
+	for _, info := range io.InputTableColumnInfos {
+		ts := TableScan{
+			Catalog:        info.Table.Catalog,
+			Schema:         info.Table.SchemaTable.Schema,
+			Table:          info.Table.SchemaTable.Table,
+			EstimatedBytes: -1,
+		}
+
+		for _, cc := range info.ColumnConstraints {
+			constraint := ColumnConstraint{Column: cc.ColumnName, Type: cc.Type}
+			for _, r := range cc.Domain.Ranges {
+				constraint.Ranges = append(constraint.Ranges, valueRange(r.Low, r.High))
+			}
+
+			ts.Constraints = append(ts.Constraints, constraint)
+		}
+
+		if size := float64(info.Estimate.OutputSizeInBytes); !math.IsNaN(size) && !math.IsInf(size, 0) {
+			ts.EstimatedBytes = int64(size)
+			e.EstimatedBytes += ts.EstimatedBytes
+		} else {
+			e.Estimated = false
+		}
+
+		e.Tables = append(e.Tables, ts)
+	}
+
+	if !e.Estimated {
+		e.EstimatedBytes = 0
+	}
+
+	return e, nil
+}
+
+// valueRange formats a range of values in interval notation. Bounds are
+// EXACTLY the value, ABOVE or BELOW it, or unbounded if they have no value.
+func valueRange(low, high explainBound) string {
+	if low.Bound == "EXACTLY" && high.Bound == "EXACTLY" && low.Value == high.Value {
+		return "= " + low.Value
+	}
+
+	open, lowValue := "(", "-inf"
+	if low.Value != "" {
+		lowValue = low.Value
+		if low.Bound == "EXACTLY" {
+			open = "["
+		}
+	}
+
+	close, highValue := ")", "+inf"
+	if high.Value != "" {
+		highValue = high.Value
+		if high.Bound == "EXACTLY" {
+			close = "]"
+		}
+	}
+
+	return open + lowValue + ", " + highValue + close
+}
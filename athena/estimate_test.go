@@ -0,0 +1,152 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func TestCost(t *testing.T) {
+	const mb = 1 << 20
+
+	for _, test := range []struct {
+		bytes    int64
+		price    float64
+		expected float64
+	}{
+		{0, 5, 0},
+		{1, 5, 5 * 10.0 / (1 << 20)},
+		{10 * mb, 5, 5 * 10.0 / (1 << 20)},
+		{10*mb + 1, 5, 5 * 11.0 / (1 << 20)},
+		{1 << 40, 5, 5},
+		{1 << 40, 6.25, 6.25},
+	} {
+		if c := athena.Cost(test.bytes, test.price); math.Abs(c-test.expected) > 1e-12 {
+			t.Errorf("Cost(%d, %v) == %v (want %v)", test.bytes, test.price, c, test.expected)
+		}
+	}
+
+	c := athena.NewCustomClient(mockClient{})
+	if cost := c.Cost(1 << 40); cost != athena.DefaultPricePerTB {
+		t.Errorf("Cost() == %v (want %v)", cost, athena.DefaultPricePerTB)
+	}
+
+	if cost := c.WithPricePerTB(7).Cost(1 << 40); cost != 7 {
+		t.Errorf("Cost() == %v (want 7)", cost)
+	}
+}
+
+const explainPlan = `{
+  "inputTableColumnInfos" : [ {
+    "table" : {
+      "catalog" : "awsdatacatalog",
+      "schemaTable" : { "schema" : "db", "table" : "events" }
+    },
+    "columnConstraints" : [ {
+      "columnName" : "dt",
+      "type" : "varchar",
+      "domain" : {
+        "nullsAllowed" : false,
+        "ranges" : [ {
+          "low" : { "value" : "2019-11-01", "bound" : "EXACTLY" },
+          "high" : { "value" : "2019-11-08", "bound" : "BELOW" }
+        }, {
+          "low" : { "value" : "2019-12-25", "bound" : "EXACTLY" },
+          "high" : { "value" : "2019-12-25", "bound" : "EXACTLY" }
+        }, {
+          "low" : { "value" : "2020-01-01", "bound" : "ABOVE" },
+          "high" : { "bound" : "BELOW" }
+        } ]
+      }
+    } ],
+    "estimate" : { "outputRowCount" : 1000.0, "outputSizeInBytes" : 1099511627776.0 }
+  }, {
+    "table" : {
+      "catalog" : "awsdatacatalog",
+      "schemaTable" : { "schema" : "db", "table" : "users" }
+    },
+    "columnConstraints" : [ ],
+    "estimate" : { "outputRowCount" : "NaN", "outputSizeInBytes" : "NaN" }
+  } ],
+  "estimate" : { "outputRowCount" : "NaN", "outputSizeInBytes" : "NaN" }
+}`
+
+func TestEstimate(t *testing.T) {
+	var queries []string
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid", queries: &queries},
+		getQueryExecution:   getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output/jobid.csv"},
+	}
+
+	events := athena.TableScan{
+		Catalog: "awsdatacatalog",
+		Schema:  "db",
+		Table:   "events",
+		Constraints: []athena.ColumnConstraint{{
+			Column: "dt",
+			Type:   "varchar",
+			Ranges: []string{"[2019-11-01, 2019-11-08)", "= 2019-12-25", "(2020-01-01, +inf)"},
+		}},
+		EstimatedBytes: 1 << 40,
+	}
+
+	users := athena.TableScan{Catalog: "awsdatacatalog", Schema: "db", Table: "users", EstimatedBytes: -1}
+
+	for i, test := range []struct {
+		plan     string
+		expected athena.Estimate
+	}{
+		{explainPlan, athena.Estimate{Tables: []athena.TableScan{events, users}}},
+		{
+			// the plan is returned split across rows
+			strings.Replace(explainPlan, `  }, {
+    "table" : {
+      "catalog" : "awsdatacatalog",
+      "schemaTable" : { "schema" : "db", "table" : "users" }
+    },
+    "columnConstraints" : [ ],
+    "estimate" : { "outputRowCount" : "NaN", "outputSizeInBytes" : "NaN" }
+`, "", 1),
+			athena.Estimate{Tables: []athena.TableScan{events}, Estimated: true, EstimatedBytes: 1 << 40, EstimatedCost: 5},
+		},
+	} {
+		queries = nil
+
+		var rows [][]string
+		for _, line := range strings.Split(test.plan, "\n") {
+			rows = append(rows, []string{line})
+		}
+
+		mc.getQueryResults = mockResults([]string{"Query Plan"}, rows...)
+		c := athena.NewCustomClient(mc)
+
+		e, err := c.Estimate(context.Background(), "db", "SELECT * FROM events", "s3://output")
+		if err != nil {
+			t.Errorf("%d: err == %v (want nil)", i, err)
+		}
+
+		if !reflect.DeepEqual(e, test.expected) {
+			t.Errorf("%d: Estimate() == %+v (want %+v)", i, e, test.expected)
+		}
+
+		expectedQuery := "EXPLAIN (TYPE IO, FORMAT JSON)\nSELECT * FROM events"
+		if len(queries) != 1 || queries[0] != expectedQuery {
+			t.Errorf("%d: queries == %q (want %q)", i, queries, expectedQuery)
+		}
+	}
+
+	mc.getQueryResults = mockResults([]string{"Query Plan"}, []string{"not json"})
+	if _, err := athena.NewCustomClient(mc).Estimate(context.Background(), "db", "SELECT 1", "s3://output"); !errors.Is(err, athena.ErrUnexpectedResult) {
+		t.Errorf("err == %v (want %v)", err, athena.ErrUnexpectedResult)
+	}
+
+	if _, err := athena.NewCustomClient(mc).Estimate(context.Background(), "db", "", "s3://output"); err != athena.ErrEmptyQuery {
+		t.Errorf("err == %v (want %v)", err, athena.ErrEmptyQuery)
+	}
+}
@@ -0,0 +1,117 @@
+package athena
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+const emptyQueryID = constError("query execution ID must not be an empty string")
+
+// Execution describes a query execution in detail.
+type Execution struct {
+	// ID is the Athena query execution ID.
+	ID string `json:"id"`
+
+	// Query is the SQL text of the query.
+	Query string `json:"query"`
+
+	// StatementType is one of DDL, DML or UTILITY.
+	StatementType string `json:"statement_type"`
+
+	// Database is the database the query ran against.
+	Database string `json:"database"`
+
+	// WorkGroup is the workgroup the query ran in.
+	WorkGroup string `json:"workgroup"`
+
+	// State is one of QUEUED, RUNNING, SUCCEEDED, FAILED or CANCELLED.
+	State string `json:"state"`
+
+	// StateChangeReason explains the state, e.g. why the query failed.
+	StateChangeReason string `json:"state_change_reason,omitempty"`
+
+	// OutputLocation is the S3 URL of the query's results.
+	OutputLocation string `json:"output_location"`
+
+	// SubmissionTime is when the query was submitted.
+	SubmissionTime time.Time `json:"submission_time"`
+
+	// CompletionTime is when the query finished, or the zero time.
+	CompletionTime time.Time `json:"completion_time"`
+
+	Statistics
+}
+
+// Done returns true if the query has completed successfully.
+func (e Execution) Done() bool {
+	return e.State == athena.QueryExecutionStateSucceeded
+}
+
+// Finished returns true if the query has finished, successfully or not.
+func (e Execution) Finished() bool {
+	return terminal(e.State)
+}
+
+// execution converts an athena.QueryExecution, any field of which may be nil.
+func execution(qe *athena.QueryExecution) Execution {
+	e := Execution{
+		ID:            aws.StringValue(qe.QueryExecutionId),
+		Query:         aws.StringValue(qe.Query),
+		StatementType: aws.StringValue(qe.StatementType),
+		WorkGroup:     aws.StringValue(qe.WorkGroup),
+		Statistics:    statistics(qe.Statistics),
+	}
+
+	if qe.QueryExecutionContext != nil {
+		e.Database = aws.StringValue(qe.QueryExecutionContext.Database)
+	}
+
+	if qe.Status != nil {
+		e.State = aws.StringValue(qe.Status.State)
+		e.StateChangeReason = aws.StringValue(qe.Status.StateChangeReason)
+		e.SubmissionTime = aws.TimeValue(qe.Status.SubmissionDateTime)
+		e.CompletionTime = aws.TimeValue(qe.Status.CompletionDateTime)
+	}
+
+	if qe.ResultConfiguration != nil {
+		e.OutputLocation = aws.StringValue(qe.ResultConfiguration.OutputLocation)
+	}
+
+	return e
+}
+
+// QueryByID returns the Query with the given execution ID, e.g. one
+// submitted by another process, so that its status and results can be
+// fetched.
+func (c Client) QueryByID(id string) (Query, error) {
+	if id == "" {
+		return Query{}, emptyQueryID
+	}
+
+	return Query{id: id, Client: c}, nil
+}
+
+// Execution returns the details of the query execution.
+func (q Query) Execution() (Execution, error) {
+	qe, err := q.execution()
+
+	if err != nil {
+		return Execution{}, err
+	}
+
+	e := execution(qe.QueryExecution)
+	e.ID = q.id
+
+	return e, nil
+}
+
+// Stop cancels the query. Stopping a query which has already finished
+// has no effect.
+func (q Query) Stop() error {
+	in := &athena.StopQueryExecutionInput{QueryExecutionId: &q.id}
+	_, err := q.api.StopQueryExecution(in)
+
+	return err
+}
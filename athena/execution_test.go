@@ -0,0 +1,72 @@
+package athena_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestQueryByID(t *testing.T) {
+	c := athena.NewCustomClient(mockClient{})
+
+	q, err := c.QueryByID("jobid")
+	if err != nil || !reflect.DeepEqual(q, c.CreateQuery("jobid")) {
+		t.Errorf("QueryByID() == %v, %v (want %v, nil)", q, err, c.CreateQuery("jobid"))
+	}
+
+	_, err = c.QueryByID("")
+	if err != athena.ErrEmptyQueryID {
+		t.Errorf("err == %v (want %v)", err, athena.ErrEmptyQueryID)
+	}
+}
+
+func TestQueryExecution(t *testing.T) {
+	mc := mockClient{
+		getQueryExecution: getQueryExecution{state: "FAILED", outLocation: "s3://output/jobid.csv"},
+		queryExecutionDetail: queryExecutionDetail{
+			reason:     "SYNTAX_ERROR",
+			statistics: &aa.QueryExecutionStatistics{DataScannedInBytes: aws.Int64(5), EngineExecutionTimeInMillis: aws.Int64(1500)},
+		},
+	}
+
+	q := athena.NewCustomClient(mc).CreateQuery("jobid")
+
+	e, err := q.Execution()
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := athena.Execution{
+		ID:                "jobid",
+		State:             "FAILED",
+		StateChangeReason: "SYNTAX_ERROR",
+		OutputLocation:    "s3://output/jobid.csv",
+		Statistics:        athena.Statistics{DataScannedInBytes: 5, EngineExecutionTime: 1500000000},
+	}
+
+	if e != expected {
+		t.Errorf("Execution() == %+v (want %+v)", e, expected)
+	}
+
+	if !e.Finished() || e.Done() {
+		t.Errorf("Finished(), Done() == %t, %t (want true, false)", e.Finished(), e.Done())
+	}
+}
+
+func TestQueryStop(t *testing.T) {
+	var stopped []string
+
+	q := athena.NewCustomClient(mockClient{listing: listing{stopped: &stopped}}).CreateQuery("jobid")
+
+	if err := q.Stop(); err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	if len(stopped) != 1 || stopped[0] != "jobid" {
+		t.Errorf("stopped == %v (want [jobid])", stopped)
+	}
+}
@@ -1,6 +1,8 @@
 package athena
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
 )
 
@@ -13,18 +15,84 @@ const ErrEmptyQuery = emptyQuery
 const ErrInvalidLimit = invalidLimit
 const ErrS3BadPrefix = s3BadPrefix
 const ErrS3NoBucket = s3NoBucket
+const ErrEmptyColumn = emptyColumn
+const ErrInvalidPercent = invalidPercent
+const ErrUnsupportedValue = unsupportedValue
+const ErrEmptyIdentifier = emptyIdentifier
+const ErrBacktickInDDLName = backtickInDDLName
+const ErrUnexpectedResult = unexpectedResult
+const ErrNoColumns = noColumns
+const ErrEmptyColumnType = emptyColumnType
+const ErrUnknownFormat = unknownFormat
+const ErrNotStruct = notStruct
+const ErrUnsupportedType = unsupportedType
+const ErrProjectionNotKey = projectionNotKey
+const ErrEmptyEnumValues = emptyEnumValues
+const ErrNoPartitionValues = noPartitionValues
+const ErrNoPartitionKeys = noPartitionKeys
+const ErrInvalidInterval = invalidInterval
+const ErrInvalidPartition = invalidPartition
+const ErrPartitionKeyMissing = partitionKeyMissing
+const ErrPartitionTooLong = partitionTooLong
+const ErrBucketingIncomplete = bucketingIncomplete
+const ErrInvalidBucketCount = invalidBucketCount
+const ErrDuplicateStatement = duplicateStatement
+const ErrUnknownDependency = unknownDependency
+const ErrDependencyCycle = dependencyCycle
+const ErrEmptyQueryID = emptyQueryID
+const ErrUnknownOutputFormat = unknownOutputFormat
+const ErrUnknownColumn = unknownColumn
+const ErrNotList = notList
+const ErrInvalidEncryption = invalidEncryption
+const ErrInvalidScanLimit = invalidScanLimit
+const ErrEmptyRoleARN = emptyRoleARN
+const ErrMFAIncomplete = mfaIncomplete
+const ErrUnexpectedAccount = unexpectedAccount
+const ErrNoTargets = noTargets
+const ErrEmptyTargetName = emptyTargetName
+const ErrDuplicateTarget = duplicateTarget
+const ErrSourceColumnExists = sourceColumnExists
+const ErrNoOutput = noOutput
+const ErrUnknownLevel = unknownLevel
 
 // NewCustomClient creates and returns a custom Athena client.
 //
 // A mock implementation of the Athena Interface can be provided for testing.
 func NewCustomClient(api athenaiface.AthenaAPI) Client {
-	return Client{api}
+	return Client{api: api}
+}
+
+// AddPartitionStatementsLimit is AddPartitionStatements with a configurable
+// maximum statement length.
+var AddPartitionStatementsLimit = addPartitionStatements
+
+// DropPartitionStatementsLimit is DropPartitionStatements with a configurable
+// maximum statement length.
+var DropPartitionStatementsLimit = dropPartitionStatements
+
+// RenderQueryAt is RenderQuery with a fixed current time.
+func RenderQueryAt(text string, vars map[string]interface{}, now time.Time) (string, error) {
+	return renderQuery(text, vars, func() time.Time { return now })
 }
 
+// AssumeRoleCredentialsWithClient is AssumeRoleCredentials using the
+// given STS client.
+var AssumeRoleCredentialsWithClient = assumeRoleCredentials
+
+// VerifyIdentity exposes the identity check of NewClientWithRole.
+var VerifyIdentity = verifyIdentity
+
 func (c Client) CreateQuery(id string) Query {
 	return Query{id, c}
 }
 
+// WithHeader returns r as if fetched from Athena, with its first row
+// holding the column names.
+func WithHeader(r Result) Result {
+	r.header = true
+	return r
+}
+
 func CreateConstError(msg string) error {
 	return constError(msg)
 }
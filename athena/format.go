@@ -0,0 +1,338 @@
+package athena
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors for invalid output
+const (
+	unknownOutputFormat = constError("unknown output format")
+	unknownColumn       = constError("result does not have the column")
+)
+
+// OutputFormat is a format results can be written in by WriteResult.
+type OutputFormat string
+
+// Output formats
+const (
+	// OutputJSON writes the Result as a single JSON object.
+	OutputJSON OutputFormat = "json"
+
+	// OutputNDJSON writes each row as a JSON object keyed by column name,
+	// one per line.
+	OutputNDJSON OutputFormat = "ndjson"
+
+	// OutputCSV writes comma separated values.
+	OutputCSV OutputFormat = "csv"
+
+	// OutputTSV writes tab separated values, with tabs and line breaks
+	// in values escaped.
+	OutputTSV OutputFormat = "tsv"
+
+	// OutputTable writes aligned columns; see WriteTable.
+	OutputTable OutputFormat = "table"
+
+	// OutputMarkdown writes a GitHub flavoured Markdown table.
+	OutputMarkdown OutputFormat = "markdown"
+)
+
+// OutputFormats are the formats supported by WriteResult.
+var OutputFormats = []OutputFormat{OutputJSON, OutputNDJSON, OutputCSV, OutputTSV, OutputTable, OutputMarkdown}
+
+// Select returns the result with only the named columns, in the order
+// given.
+func (r Result) Select(columns ...string) (Result, error) {
+	index := make([]int, len(columns))
+	selected := Result{Columns: make([]Column, len(columns)), Rows: make([]Row, len(r.Rows)), header: r.header}
+
+	for i, name := range columns {
+		index[i] = -1
+
+		for j, c := range r.Columns {
+			if c.Name == name {
+				index[i] = j
+				break
+			}
+		}
+
+		if index[i] < 0 {
+			return Result{}, fmt.Errorf("%s: %w", name, unknownColumn)
+		}
+
+		selected.Columns[i] = r.Columns[index[i]]
+	}
+
+	for i, row := range r.Rows {
+		selected.Rows[i] = make(Row, len(index))
+		for j, k := range index {
+			if k < len(row) {
+				selected.Rows[i][j] = row[k]
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// WriteResult writes the result to w in format. If header is true the
+// column names are written before the rows, except in NDJSON where each
+// row carries them; Markdown tables always have a header.
+//
+// OutputJSON writes the Result unchanged, including the header row Athena
+// pads onto results, unless header is false. Other formats never write the
+// padded header row as data; see Result.WithoutHeader.
+func WriteResult(w io.Writer, r Result, format OutputFormat, header bool) error {
+	if format == OutputJSON {
+		if !header {
+			r = r.WithoutHeader()
+		}
+
+		return json.NewEncoder(w).Encode(r)
+	}
+
+	r = r.WithoutHeader()
+
+	switch format {
+	case OutputNDJSON:
+		return writeNDJSON(w, r)
+	case OutputCSV:
+		return writeCSV(w, r, header)
+	case OutputTSV:
+		return writeTSV(w, r, header)
+	case OutputTable:
+		if header {
+			return WriteTable(w, r)
+		}
+
+		return writeTableRows(w, r, false)
+	case OutputMarkdown:
+		return writeMarkdown(w, r)
+	}
+
+	return fmt.Errorf("%s: %w", format, unknownOutputFormat)
+}
+
+func columnNames(r Result) []string {
+	names := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		names[i] = c.Name
+	}
+
+	return names
+}
+
+func writeNDJSON(w io.Writer, r Result) error {
+	bw := bufio.NewWriter(w)
+
+	keys := make([][]byte, len(r.Columns))
+	for i, c := range r.Columns {
+		k, err := json.Marshal(c.Name)
+		if err != nil {
+			return err
+		}
+
+		keys[i] = k
+	}
+
+	// objects are written by hand to keep the keys in column order
+	for _, row := range r.Rows {
+		bw.WriteString("{")
+
+		for i, k := range keys {
+			var v string
+			if i < len(row) {
+				v = row[i]
+			}
+
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+
+			if i > 0 {
+				bw.WriteString(",")
+			}
+
+			bw.Write(k)
+			bw.WriteString(":")
+			bw.Write(value)
+		}
+
+		bw.WriteString("}\n")
+	}
+
+	return bw.Flush()
+}
+
+func writeCSV(w io.Writer, r Result, header bool) error {
+	cw := csv.NewWriter(w)
+
+	if header {
+		if err := cw.Write(columnNames(r)); err != nil {
+			return err
+		}
+	}
+
+	if err := cw.WriteAll(rowStrings(r.Rows)); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+func rowStrings(rows []Row) [][]string {
+	s := make([][]string, len(rows))
+	for i, row := range rows {
+		s[i] = row
+	}
+
+	return s
+}
+
+func writeTSV(w io.Writer, r Result, header bool) error {
+	bw := bufio.NewWriter(w)
+
+	write := func(values []string) {
+		for i, v := range values {
+			if i > 0 {
+				bw.WriteString("\t")
+			}
+
+			bw.WriteString(cell(v))
+		}
+
+		bw.WriteString("\n")
+	}
+
+	if header {
+		write(columnNames(r))
+	}
+
+	for _, row := range r.Rows {
+		write(row)
+	}
+
+	return bw.Flush()
+}
+
+func writeMarkdown(w io.Writer, r Result) error {
+	bw := bufio.NewWriter(w)
+	escape := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+	write := func(values []string) {
+		bw.WriteString("|")
+
+		for i := range r.Columns {
+			var v string
+			if i < len(values) {
+				v = values[i]
+			}
+
+			bw.WriteString(" " + escape.Replace(v) + " |")
+		}
+
+		bw.WriteString("\n")
+	}
+
+	write(columnNames(r))
+
+	bw.WriteString("|")
+	for range r.Columns {
+		bw.WriteString(" --- |")
+	}
+	bw.WriteString("\n")
+
+	for _, row := range r.Rows {
+		write(row)
+	}
+
+	return bw.Flush()
+}
+
+// WriteTable writes the result to w as a table of aligned columns headed
+// by the column names, e.g.
+//
+//	 id | name
+//	----+-------
+//	 1  | alice
+//
+// The header row Athena pads onto results is not written twice; see
+// Result.WithoutHeader.
+func WriteTable(w io.Writer, r Result) error {
+	return writeTableRows(w, r.WithoutHeader(), true)
+}
+
+// writeTableRows writes the rows of the result as aligned columns,
+// optionally headed by the column names.
+func writeTableRows(w io.Writer, r Result, header bool) error {
+	widths := make([]int, len(r.Columns))
+	for i, c := range r.Columns {
+		if header {
+			widths[i] = utf8.RuneCountInString(c.Name)
+		}
+	}
+
+	for _, row := range r.Rows {
+		for i := 0; i < len(row) && i < len(widths); i++ {
+			if n := utf8.RuneCountInString(cell(row[i])); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if header {
+		writeTableRow(bw, columnNames(r), widths)
+
+		for i, width := range widths {
+			if i > 0 {
+				bw.WriteString("+")
+			}
+
+			bw.WriteString(strings.Repeat("-", width+2))
+		}
+
+		bw.WriteString("\n")
+	}
+
+	for _, row := range r.Rows {
+		writeTableRow(bw, row, widths)
+	}
+
+	return bw.Flush()
+}
+
+// cell returns the value as displayed in a table, with line breaks and
+// tabs escaped so as not to break alignment.
+func cell(v string) string {
+	return strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(v)
+}
+
+func writeTableRow(w *bufio.Writer, values []string, widths []int) {
+	for i, width := range widths {
+		var v string
+		if i < len(values) {
+			v = cell(values[i])
+		}
+
+		if i > 0 {
+			w.WriteString("|")
+		}
+
+		w.WriteString(" " + v)
+
+		// the last column is not padded, to avoid trailing whitespace
+		if i < len(widths)-1 {
+			w.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(v)+1))
+		}
+	}
+
+	w.WriteString("\n")
+}
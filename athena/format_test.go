@@ -0,0 +1,114 @@
+package athena_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func TestWriteTable(t *testing.T) {
+	r := athena.WithHeader(athena.Result{
+		Columns: []athena.Column{{Name: "id"}, {Name: "name"}},
+		Rows: []athena.Row{
+			{"id", "name"},
+			{"1", "alice"},
+			{"22", "bö\tb"},
+		},
+	})
+
+	expected := "" +
+		" id | name\n" +
+		"----+-------\n" +
+		" 1  | alice\n" +
+		" 22 | bö\\tb\n"
+
+	var b bytes.Buffer
+	if err := athena.WriteTable(&b, r); err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	if b.String() != expected {
+		t.Errorf("WriteTable() == %q (want %q)", b.String(), expected)
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	r := athena.WithHeader(athena.Result{
+		Columns: []athena.Column{{Name: "id"}, {Name: "name"}},
+		Rows: []athena.Row{
+			{"id", "name"},
+			{"1", "a,|b"},
+			{"2", "c\nd"},
+		},
+	})
+
+	for i, test := range []struct {
+		format   athena.OutputFormat
+		header   bool
+		expected string
+		err      error
+	}{
+		{athena.OutputJSON, true, marshal(r) + "\n", nil},
+		{athena.OutputJSON, false, marshal(r.WithoutHeader()) + "\n", nil},
+		{athena.OutputNDJSON, true, `{"id":"1","name":"a,|b"}` + "\n" + `{"id":"2","name":"c\nd"}` + "\n", nil},
+		{athena.OutputCSV, true, "id,name\n1,\"a,|b\"\n2,\"c\nd\"\n", nil},
+		{athena.OutputCSV, false, "1,\"a,|b\"\n2,\"c\nd\"\n", nil},
+		{athena.OutputTSV, true, "id\tname\n1\ta,|b\n2\tc\\nd\n", nil},
+		{athena.OutputTable, false, " 1 | a,|b\n 2 | c\\nd\n", nil},
+		{athena.OutputMarkdown, false, "| id | name |\n| --- | --- |\n| 1 | a,\\|b |\n| 2 | c<br>d |\n", nil},
+		{"xml", true, "", athena.ErrUnknownOutputFormat},
+	} {
+		var b bytes.Buffer
+
+		err := athena.WriteResult(&b, r, test.format, test.header)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if b.String() != test.expected {
+			t.Errorf("%d: WriteResult() == %q (want %q)", i, b.String(), test.expected)
+		}
+	}
+}
+
+func marshal(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestResultSelect(t *testing.T) {
+	r := athena.Result{
+		Columns: []athena.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}, {Name: "age"}},
+		Rows: []athena.Row{
+			{"id", "name", "age"},
+			{"1", "alice", "30"},
+			{"2"},
+		},
+	}
+
+	selected, err := r.Select("name", "id")
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := athena.Result{
+		Columns: []athena.Column{{Name: "name", Type: "varchar"}, {Name: "id", Type: "integer"}},
+		Rows: []athena.Row{
+			{"name", "id"},
+			{"alice", "1"},
+			{"", "2"},
+		},
+	}
+
+	if !reflect.DeepEqual(selected, expected) {
+		t.Errorf("Select() == %v (want %v)", selected, expected)
+	}
+
+	if _, err := r.Select("missing"); !errors.Is(err, athena.ErrUnknownColumn) {
+		t.Errorf("err == %v (want %v)", err, athena.ErrUnknownColumn)
+	}
+}
@@ -24,6 +24,86 @@ func validS3URL(s3url string) error {
 	return nil
 }
 
+// Errors for invalid identifiers
+const (
+	emptyIdentifier   = constError("identifier must not be an empty string")
+	backtickInDDLName = constError("identifier must not contain a backtick")
+)
+
+// QuoteIdentifier quotes name for use as an identifier (e.g. a column name)
+// in a SELECT statement, escaping any embedded double quotes.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteLiteral quotes s for use as a string literal, escaping any embedded
+// single quotes.
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteTable quotes a table name, optionally qualified by database
+// (e.g. db.table), for use in a SELECT statement.
+func quoteTable(table string) (string, error) {
+	if table == "" {
+		return "", emptyTable
+	}
+
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		if p == "" {
+			return "", emptyIdentifier
+		}
+
+		parts[i] = QuoteIdentifier(p)
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// quoteDDLIdentifier quotes name for use as an identifier in DDL statements,
+// which Athena parses with Hive rules: identifiers are quoted with backticks,
+// and backticks cannot be escaped.
+func quoteDDLIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", emptyIdentifier
+	}
+
+	if strings.Contains(name, "`") {
+		return "", backtickInDDLName
+	}
+
+	return "`" + name + "`", nil
+}
+
+// quoteDDLLiteral quotes s for use as a string literal in DDL statements,
+// which Hive escapes with backslashes rather than by doubling quotes.
+func quoteDDLLiteral(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "'", `\'`)
+
+	return "'" + s + "'"
+}
+
+// quoteDDLTable is the DDL equivalent of quoteTable.
+func quoteDDLTable(table string) (string, error) {
+	if table == "" {
+		return "", emptyTable
+	}
+
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		q, err := quoteDDLIdentifier(p)
+		if err != nil {
+			return "", err
+		}
+
+		parts[i] = q
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
 // constError provides a way to specify constant errors: see https://dave.cheney.net/2016/04/07/constant-errors
 //
 // To be used effectively, you create a const package variable of type constError.
@@ -16,3 +16,46 @@ func TestConstError(t *testing.T) {
 		t.Errorf("err.Error() == %v (want %v)", err.Error(), msg)
 	}
 }
+
+func TestQuoteIdentifier(t *testing.T) {
+	cases := []struct {
+		id       string
+		name     string
+		expected string
+	}{
+		{"plain", "column", `"column"`},
+		{"embedded quote", `col"umn`, `"col""umn"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			actual := athena.QuoteIdentifier(tc.name)
+
+			if actual != tc.expected {
+				tt.Errorf("QuoteIdentifier() == %v (want %v)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	cases := []struct {
+		id       string
+		s        string
+		expected string
+	}{
+		{"plain", "value", "'value'"},
+		{"embedded quote", "it's", "'it''s'"},
+		{"injection", "x' OR '1'='1", "'x'' OR ''1''=''1'"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			actual := athena.QuoteLiteral(tc.s)
+
+			if actual != tc.expected {
+				tt.Errorf("QuoteLiteral() == %v (want %v)", actual, tc.expected)
+			}
+		})
+	}
+}
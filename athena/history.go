@@ -0,0 +1,222 @@
+package athena
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// batchGetLimit is the maximum number of IDs accepted by the BatchGet APIs.
+const batchGetLimit = 50
+
+// UnprocessedError is returned by History, along with the executions it
+// could describe, when Athena leaves some of the executions unprocessed.
+type UnprocessedError struct {
+	Executions []UnprocessedExecution
+}
+
+// UnprocessedExecution is a query execution Athena did not describe, with
+// the error it gave.
+type UnprocessedExecution struct {
+	ID           string `json:"id"`
+	ErrorCode    string `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// Error satisfies the error interface.
+func (e *UnprocessedError) Error() string {
+	u := e.Executions[0]
+	msg := fmt.Sprintf("%d query executions were not described, including %s", len(e.Executions), u.ID)
+
+	if u.ErrorCode != "" || u.ErrorMessage != "" {
+		msg += fmt.Sprintf(" (%s: %s)", u.ErrorCode, u.ErrorMessage)
+	}
+
+	return msg
+}
+
+// History returns up to max of the most recent query executions in
+// workgroup, most recent first. The primary workgroup is used if workgroup
+// is empty.
+//
+// If Athena leaves some executions unprocessed, the others are returned
+// along with an *UnprocessedError listing them.
+func (c Client) History(workgroup string, max int) ([]Execution, error) {
+	if max < 0 {
+		return nil, invalidLimit
+	}
+
+	var ids []*string
+
+	in := &athena.ListQueryExecutionsInput{}
+	if workgroup != "" {
+		in.SetWorkGroup(workgroup)
+	}
+
+	for len(ids) < max {
+		out, err := c.api.ListQueryExecutions(in)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, out.QueryExecutionIds...)
+
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
+		}
+
+		in.NextToken = out.NextToken
+	}
+
+	if len(ids) > max {
+		ids = ids[:max]
+	}
+
+	described := make(map[string]Execution, len(ids))
+	var unprocessed []UnprocessedExecution
+
+	for start := 0; start < len(ids); start += batchGetLimit {
+		end := start + batchGetLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		in := &athena.BatchGetQueryExecutionInput{QueryExecutionIds: ids[start:end]}
+		out, err := c.api.BatchGetQueryExecution(in)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, qe := range out.QueryExecutions {
+			described[aws.StringValue(qe.QueryExecutionId)] = execution(qe)
+		}
+
+		for _, u := range out.UnprocessedQueryExecutionIds {
+			unprocessed = append(unprocessed, UnprocessedExecution{
+				ID:           aws.StringValue(u.QueryExecutionId),
+				ErrorCode:    aws.StringValue(u.ErrorCode),
+				ErrorMessage: aws.StringValue(u.ErrorMessage),
+			})
+		}
+	}
+
+	// BatchGetQueryExecution need not keep the order of the IDs listed
+	executions := make([]Execution, 0, len(described))
+
+	for _, id := range ids {
+		if e, ok := described[aws.StringValue(id)]; ok {
+			executions = append(executions, e)
+		}
+	}
+
+	if len(unprocessed) > 0 {
+		return executions, &UnprocessedError{Executions: unprocessed}
+	}
+
+	return executions, nil
+}
+
+// NamedQuery is a query saved in Athena.
+type NamedQuery struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Database    string `json:"database"`
+	Query       string `json:"query"`
+	WorkGroup   string `json:"workgroup"`
+}
+
+// NamedQueries returns the named queries saved in workgroup. The primary
+// workgroup is used if workgroup is empty.
+func (c Client) NamedQueries(workgroup string) ([]NamedQuery, error) {
+	var ids []*string
+
+	in := &athena.ListNamedQueriesInput{}
+	if workgroup != "" {
+		in.SetWorkGroup(workgroup)
+	}
+
+	for {
+		out, err := c.api.ListNamedQueries(in)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, out.NamedQueryIds...)
+
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
+		}
+
+		in.NextToken = out.NextToken
+	}
+
+	queries := make([]NamedQuery, 0, len(ids))
+
+	for start := 0; start < len(ids); start += batchGetLimit {
+		end := start + batchGetLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		in := &athena.BatchGetNamedQueryInput{NamedQueryIds: ids[start:end]}
+		out, err := c.api.BatchGetNamedQuery(in)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nq := range out.NamedQueries {
+			queries = append(queries, NamedQuery{
+				ID:          aws.StringValue(nq.NamedQueryId),
+				Name:        aws.StringValue(nq.Name),
+				Description: aws.StringValue(nq.Description),
+				Database:    aws.StringValue(nq.Database),
+				Query:       aws.StringValue(nq.QueryString),
+				WorkGroup:   aws.StringValue(nq.WorkGroup),
+			})
+		}
+	}
+
+	return queries, nil
+}
+
+// WorkGroup summarises an Athena workgroup.
+type WorkGroup struct {
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	Description  string    `json:"description,omitempty"`
+	CreationTime time.Time `json:"creation_time"`
+}
+
+// WorkGroups returns the workgroups in the account.
+func (c Client) WorkGroups() ([]WorkGroup, error) {
+	var workgroups []WorkGroup
+
+	in := &athena.ListWorkGroupsInput{}
+
+	for {
+		out, err := c.api.ListWorkGroups(in)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, wg := range out.WorkGroups {
+			workgroups = append(workgroups, WorkGroup{
+				Name:         aws.StringValue(wg.Name),
+				State:        aws.StringValue(wg.State),
+				Description:  aws.StringValue(wg.Description),
+				CreationTime: aws.TimeValue(wg.CreationTime),
+			})
+		}
+
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
+		}
+
+		in.NextToken = out.NextToken
+	}
+
+	return workgroups, nil
+}
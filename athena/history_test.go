@@ -0,0 +1,171 @@
+package athena_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestHistory(t *testing.T) {
+	submitted := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	executions := make([]*aa.QueryExecution, 3)
+	for i := range executions {
+		executions[i] = &aa.QueryExecution{
+			QueryExecutionId:      aws.String(string('a' + rune(i))),
+			Query:                 aws.String("SELECT 1"),
+			QueryExecutionContext: &aa.QueryExecutionContext{Database: aws.String("database")},
+			Status: &aa.QueryExecutionStatus{
+				State:              aws.String("SUCCEEDED"),
+				SubmissionDateTime: aws.Time(submitted),
+			},
+			Statistics: &aa.QueryExecutionStatistics{DataScannedInBytes: aws.Int64(int64(i))},
+		}
+	}
+
+	t.Run("paginates to max", func(tt *testing.T) {
+		c := athena.NewCustomClient(mockClient{listing: listing{executions: executions, pageSize: 1}})
+
+		actual, err := c.History("", 2)
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		expected := []athena.Execution{
+			{ID: "a", Query: "SELECT 1", Database: "database", State: "SUCCEEDED", SubmissionTime: submitted},
+			{ID: "b", Query: "SELECT 1", Database: "database", State: "SUCCEEDED", SubmissionTime: submitted, Statistics: athena.Statistics{DataScannedInBytes: 1}},
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("History() == %v (want %v)", actual, expected)
+		}
+	})
+
+	t.Run("reordered and unprocessed", func(tt *testing.T) {
+		c := athena.NewCustomClient(shuffledBatch{mockClient{listing: listing{executions: executions}}})
+
+		actual, err := c.History("", 3)
+
+		var uerr *athena.UnprocessedError
+		if !errors.As(err, &uerr) {
+			tt.Fatalf("err == %v (want an UnprocessedError)", err)
+		}
+
+		expected := []athena.UnprocessedExecution{{ID: "b", ErrorCode: "INTERNAL_ERROR", ErrorMessage: "try again"}}
+		if !reflect.DeepEqual(uerr.Executions, expected) {
+			tt.Errorf("Executions == %v (want %v)", uerr.Executions, expected)
+		}
+
+		if len(actual) != 2 || actual[0].ID != "a" || actual[1].ID != "c" {
+			tt.Errorf("History() == %v (want a then c)", actual)
+		}
+	})
+
+	t.Run("unhappy path", func(tt *testing.T) {
+		errFailure := errors.New("ListQueryExecutions failure")
+		c := athena.NewCustomClient(mockClient{listing: listing{err: errFailure}})
+
+		_, err := c.History("", 10)
+
+		if err != errFailure {
+			tt.Errorf("err == %v (want %v)", err, errFailure)
+		}
+	})
+
+	t.Run("invalid input: negative max", func(tt *testing.T) {
+		c := athena.NewCustomClient(mockClient{})
+
+		_, err := c.History("", -1)
+
+		if err != athena.ErrInvalidLimit {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrInvalidLimit)
+		}
+	})
+}
+
+// shuffledBatch describes query executions in reverse order, leaving b
+// unprocessed.
+type shuffledBatch struct {
+	mockClient
+}
+
+func (sb shuffledBatch) BatchGetQueryExecution(in *aa.BatchGetQueryExecutionInput) (*aa.BatchGetQueryExecutionOutput, error) {
+	out, err := sb.mockClient.BatchGetQueryExecution(in)
+	if err != nil {
+		return out, err
+	}
+
+	shuffled := &aa.BatchGetQueryExecutionOutput{}
+
+	for i := len(out.QueryExecutions) - 1; i >= 0; i-- {
+		qe := out.QueryExecutions[i]
+
+		if *qe.QueryExecutionId == "b" {
+			shuffled.UnprocessedQueryExecutionIds = append(shuffled.UnprocessedQueryExecutionIds, &aa.UnprocessedQueryExecutionId{
+				QueryExecutionId: qe.QueryExecutionId,
+				ErrorCode:        aws.String("INTERNAL_ERROR"),
+				ErrorMessage:     aws.String("try again"),
+			})
+
+			continue
+		}
+
+		shuffled.QueryExecutions = append(shuffled.QueryExecutions, qe)
+	}
+
+	return shuffled, nil
+}
+
+func TestNamedQueries(t *testing.T) {
+	nqs := []*aa.NamedQuery{
+		{NamedQueryId: aws.String("1"), Name: aws.String("one"), Database: aws.String("db"), QueryString: aws.String("SELECT 1")},
+		{NamedQueryId: aws.String("2"), Name: aws.String("two"), Database: aws.String("db"), QueryString: aws.String("SELECT 2")},
+	}
+
+	c := athena.NewCustomClient(mockClient{listing: listing{namedQueries: nqs, pageSize: 1}})
+
+	actual, err := c.NamedQueries("")
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := []athena.NamedQuery{
+		{ID: "1", Name: "one", Database: "db", Query: "SELECT 1"},
+		{ID: "2", Name: "two", Database: "db", Query: "SELECT 2"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("NamedQueries() == %v (want %v)", actual, expected)
+	}
+}
+
+func TestWorkGroups(t *testing.T) {
+	wgs := []*aa.WorkGroupSummary{
+		{Name: aws.String("primary"), State: aws.String("ENABLED")},
+		{Name: aws.String("adhoc"), State: aws.String("DISABLED")},
+	}
+
+	c := athena.NewCustomClient(mockClient{listing: listing{workGroups: wgs, pageSize: 1}})
+
+	actual, err := c.WorkGroups()
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := []athena.WorkGroup{
+		{Name: "primary", State: "ENABLED"},
+		{Name: "adhoc", State: "DISABLED"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("WorkGroups() == %v (want %v)", actual, expected)
+	}
+}
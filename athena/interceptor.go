@@ -0,0 +1,271 @@
+package athena
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+)
+
+// noOutput is returned when an interceptor answers a call itself without
+// setting either its output, a non-nil value of the operation's type, or an
+// error.
+const noOutput = constError("interceptor set neither the output of the call nor an error")
+
+// Call is a call to the Athena API, as seen by interceptors.
+type Call struct {
+	// Operation is the name of the API operation, e.g. StartQueryExecution.
+	Operation string
+
+	// Input is the request, e.g. a *athena.StartQueryExecutionInput, and
+	// Output the response, e.g. a *athena.StartQueryExecutionOutput. Output
+	// and Err are set once the call is invoked. Interceptors may replace
+	// either with another of the same type.
+	Input  interface{}
+	Output interface{}
+	Err    error
+
+	// Start is when the API was last called, and Duration how long it
+	// took. Attempts is the number of times the API has been called, more
+	// than one if an interceptor retried the call.
+	Start    time.Time
+	Duration time.Duration
+	Attempts int
+
+	// opts are the options of the client making the call, if it observes
+	// its calls; see observeCall.
+	opts *options
+}
+
+// Invoker makes a call, setting its Output, Err, Start, Duration and
+// Attempts.
+type Invoker func(call *Call)
+
+// Interceptor intercepts calls to the Athena API. It makes the call by
+// calling invoke, after which the output, error and timing of the call
+// are set, so it may observe or change the call before and after it is
+// made, call invoke more than once to retry it, or not at all to answer
+// the call itself.
+type Interceptor func(call *Call, invoke Invoker)
+
+// WithInterceptors returns a copy of the client whose calls to the Athena
+// API pass through the interceptors, the first outermost, within any the
+// client already has. Every call the client makes is intercepted.
+func (c Client) WithInterceptors(interceptors ...Interceptor) Client {
+	if len(interceptors) == 0 {
+		return c
+	}
+
+	i := &intercepted{AthenaAPI: c.api}
+	if prev, ok := c.api.(*intercepted); ok {
+		i.AthenaAPI = prev.AthenaAPI
+		i.interceptors = append(i.interceptors, prev.interceptors...)
+		i.opts = prev.opts
+	}
+
+	i.interceptors = append(i.interceptors, interceptors...)
+	c.api = i
+
+	return c
+}
+
+// intercepted is an athenaiface.AthenaAPI passing calls through
+// interceptors. It is referenced by pointer so that Client remains
+// comparable.
+type intercepted struct {
+	athenaiface.AthenaAPI
+
+	interceptors []Interceptor
+
+	// opts are the options of the client, once it observes its calls.
+	opts *options
+}
+
+// observe returns a copy of the client whose calls pass through
+// observeCall, after any interceptors it already has, unless they already
+// do.
+func (c Client) observe() Client {
+	if i, ok := c.api.(*intercepted); ok && i.opts != nil {
+		return c
+	}
+
+	c = c.WithInterceptors(observeCall)
+	c.api.(*intercepted).opts = c.opts
+
+	return c
+}
+
+// observeCall measures and logs a call with the metrics and logger of the
+// client making it, as they are when it is made, so that a client given
+// others doesn't measure or log calls twice.
+func observeCall(call *Call, invoke Invoker) {
+	invoke(call)
+
+	if call.opts != nil {
+		measureCall(call, call.opts.metrics)
+		logCall(call, call.opts.logger)
+	}
+}
+
+// call passes a call of operation through the interceptors, calling fn
+// with its input to make it.
+func (i *intercepted) call(operation string, in interface{}, fn func(in interface{}) (interface{}, error)) *Call {
+	invoke := func(call *Call) {
+		call.Attempts++
+		call.Start = time.Now()
+		call.Output, call.Err = fn(call.Input)
+		call.Duration = time.Since(call.Start)
+	}
+
+	for j := len(i.interceptors) - 1; j >= 0; j-- {
+		interceptor, next := i.interceptors[j], invoke
+		invoke = func(call *Call) {
+			interceptor(call, next)
+		}
+	}
+
+	call := &Call{Operation: operation, Input: in, opts: i.opts}
+	invoke(call)
+
+	return call
+}
+
+// StartQueryExecution intercepts a call to StartQueryExecution.
+func (i *intercepted) StartQueryExecution(in *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	call := i.call("StartQueryExecution", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.StartQueryExecution(in.(*athena.StartQueryExecutionInput))
+	})
+
+	out, ok := call.Output.(*athena.StartQueryExecutionOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// GetQueryExecution intercepts a call to GetQueryExecution.
+func (i *intercepted) GetQueryExecution(in *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+	call := i.call("GetQueryExecution", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.GetQueryExecution(in.(*athena.GetQueryExecutionInput))
+	})
+
+	out, ok := call.Output.(*athena.GetQueryExecutionOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// GetQueryResults intercepts a call to GetQueryResults.
+func (i *intercepted) GetQueryResults(in *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+	call := i.call("GetQueryResults", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.GetQueryResults(in.(*athena.GetQueryResultsInput))
+	})
+
+	out, ok := call.Output.(*athena.GetQueryResultsOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// StopQueryExecution intercepts a call to StopQueryExecution.
+func (i *intercepted) StopQueryExecution(in *athena.StopQueryExecutionInput) (*athena.StopQueryExecutionOutput, error) {
+	call := i.call("StopQueryExecution", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.StopQueryExecution(in.(*athena.StopQueryExecutionInput))
+	})
+
+	out, ok := call.Output.(*athena.StopQueryExecutionOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// ListQueryExecutions intercepts a call to ListQueryExecutions.
+func (i *intercepted) ListQueryExecutions(in *athena.ListQueryExecutionsInput) (*athena.ListQueryExecutionsOutput, error) {
+	call := i.call("ListQueryExecutions", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.ListQueryExecutions(in.(*athena.ListQueryExecutionsInput))
+	})
+
+	out, ok := call.Output.(*athena.ListQueryExecutionsOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// BatchGetQueryExecution intercepts a call to BatchGetQueryExecution.
+func (i *intercepted) BatchGetQueryExecution(in *athena.BatchGetQueryExecutionInput) (*athena.BatchGetQueryExecutionOutput, error) {
+	call := i.call("BatchGetQueryExecution", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.BatchGetQueryExecution(in.(*athena.BatchGetQueryExecutionInput))
+	})
+
+	out, ok := call.Output.(*athena.BatchGetQueryExecutionOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// ListNamedQueries intercepts a call to ListNamedQueries.
+func (i *intercepted) ListNamedQueries(in *athena.ListNamedQueriesInput) (*athena.ListNamedQueriesOutput, error) {
+	call := i.call("ListNamedQueries", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.ListNamedQueries(in.(*athena.ListNamedQueriesInput))
+	})
+
+	out, ok := call.Output.(*athena.ListNamedQueriesOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// BatchGetNamedQuery intercepts a call to BatchGetNamedQuery.
+func (i *intercepted) BatchGetNamedQuery(in *athena.BatchGetNamedQueryInput) (*athena.BatchGetNamedQueryOutput, error) {
+	call := i.call("BatchGetNamedQuery", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.BatchGetNamedQuery(in.(*athena.BatchGetNamedQueryInput))
+	})
+
+	out, ok := call.Output.(*athena.BatchGetNamedQueryOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// ListWorkGroups intercepts a call to ListWorkGroups.
+func (i *intercepted) ListWorkGroups(in *athena.ListWorkGroupsInput) (*athena.ListWorkGroupsOutput, error) {
+	call := i.call("ListWorkGroups", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.ListWorkGroups(in.(*athena.ListWorkGroupsInput))
+	})
+
+	out, ok := call.Output.(*athena.ListWorkGroupsOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
+
+// UpdateWorkGroup intercepts a call to UpdateWorkGroup.
+func (i *intercepted) UpdateWorkGroup(in *athena.UpdateWorkGroupInput) (*athena.UpdateWorkGroupOutput, error) {
+	call := i.call("UpdateWorkGroup", in, func(in interface{}) (interface{}, error) {
+		return i.AthenaAPI.UpdateWorkGroup(in.(*athena.UpdateWorkGroupInput))
+	})
+
+	out, ok := call.Output.(*athena.UpdateWorkGroupOutput)
+	if (!ok || out == nil) && call.Err == nil {
+		return nil, noOutput
+	}
+
+	return out, call.Err
+}
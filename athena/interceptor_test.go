@@ -0,0 +1,139 @@
+package athena_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestWithInterceptors(t *testing.T) {
+	var inputs []*aa.StartQueryExecutionInput
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid", inputs: &inputs},
+		getQueryExecution:   getQueryExecution{state: "SUCCEEDED", outLocation: "s3://bucket/jobid.csv"},
+	}
+
+	var events []string
+	trace := func(name string) athena.Interceptor {
+		return func(call *athena.Call, invoke athena.Invoker) {
+			events = append(events, name+">"+call.Operation)
+			invoke(call)
+			events = append(events, "<"+name)
+		}
+	}
+
+	var calls []athena.Call
+	record := func(call *athena.Call, invoke athena.Invoker) {
+		invoke(call)
+		calls = append(calls, *call)
+	}
+
+	workGroup := func(call *athena.Call, invoke athena.Invoker) {
+		if in, ok := call.Input.(*aa.StartQueryExecutionInput); ok {
+			in.SetWorkGroup("intercepted")
+		}
+
+		invoke(call)
+	}
+
+	base := athena.NewCustomClient(mc)
+	c := base.WithInterceptors(trace("a"), record).WithInterceptors(trace("b"), workGroup)
+
+	if _, err := c.Run(context.Background(), "db", "SELECT 1", "s3://bucket/"); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	expected := []string{
+		"a>StartQueryExecution", "b>StartQueryExecution", "<b", "<a",
+		"a>GetQueryExecution", "b>GetQueryExecution", "<b", "<a",
+		"a>GetQueryResults", "b>GetQueryResults", "<b", "<a",
+		"a>GetQueryExecution", "b>GetQueryExecution", "<b", "<a",
+	}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("events == %v (want %v)", events, expected)
+	}
+
+	if len(calls) != 4 {
+		t.Fatalf("len(calls) == %d (want 4)", len(calls))
+	}
+
+	start := calls[0]
+	if out, ok := start.Output.(*aa.StartQueryExecutionOutput); !ok || aws.StringValue(out.QueryExecutionId) != "jobid" {
+		t.Errorf("Output == %v (want jobid)", start.Output)
+	}
+
+	if start.Err != nil || start.Start.IsZero() || start.Duration < 0 {
+		t.Errorf("Call == %+v (want timed, without error)", start)
+	}
+
+	if len(inputs) != 1 || aws.StringValue(inputs[0].WorkGroup) != "intercepted" {
+		t.Errorf("WorkGroup not set by interceptor: %v", inputs)
+	}
+
+	// the receiver is left untouched
+	events = nil
+	if _, err := base.DoQuery("db", "SELECT 1", "s3://bucket/"); err != nil || events != nil {
+		t.Errorf("DoQuery == %v, events %v (want no interception)", err, events)
+	}
+}
+
+func TestInterceptorAnswers(t *testing.T) {
+	errThrottled := awserr.New(aa.ErrCodeTooManyRequestsException, "slow down", nil)
+
+	// fail fails the first call, which is retried
+	failures, attempts := 1, 0
+	fail := func(call *athena.Call, invoke athena.Invoker) {
+		attempts++
+
+		if failures > 0 {
+			failures--
+			call.Err = errThrottled
+			return
+		}
+
+		invoke(call)
+	}
+
+	c := athena.NewCustomClient(mockClient{startQueryExecution: startQueryExecution{id: "jobid"}})
+
+	q, err := c.WithInterceptors(athenatest.Retry(3, 0), fail).DoQuery("db", "SELECT 1", "s3://bucket/")
+	if err != nil || q.ID() != "jobid" || attempts != 2 {
+		t.Errorf("DoQuery == %v, %v after %d attempts (want jobid after 2)", q.ID(), err, attempts)
+	}
+
+	failures = 1
+	if _, err := c.WithInterceptors(fail).DoQuery("db", "SELECT 1", "s3://bucket/"); err != errThrottled {
+		t.Errorf("err == %v (want %v)", err, errThrottled)
+	}
+
+	answer := func(call *athena.Call, invoke athena.Invoker) {
+		call.Output = &aa.StartQueryExecutionOutput{QueryExecutionId: aws.String("answered")}
+	}
+
+	if q, err := c.WithInterceptors(answer).DoQuery("db", "SELECT 1", "s3://bucket/"); err != nil || q.ID() != "answered" {
+		t.Errorf("DoQuery == %v, %v (want answered)", q.ID(), err)
+	}
+
+	drop := func(call *athena.Call, invoke athena.Invoker) {}
+
+	if _, err := c.WithInterceptors(drop).DoQuery("db", "SELECT 1", "s3://bucket/"); err != athena.ErrNoOutput {
+		t.Errorf("err == %v (want %v)", err, athena.ErrNoOutput)
+	}
+
+	// a nil output of the operation's type is no output either
+	answerNil := func(call *athena.Call, invoke athena.Invoker) {
+		call.Output = (*aa.StartQueryExecutionOutput)(nil)
+	}
+
+	if _, err := c.WithInterceptors(answerNil).DoQuery("db", "SELECT 1", "s3://bucket/"); err != athena.ErrNoOutput {
+		t.Errorf("err == %v (want %v)", err, athena.ErrNoOutput)
+	}
+}
@@ -0,0 +1,308 @@
+package athena
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// unknownLevel is returned when parsing an unknown log level.
+const unknownLevel = constError("log level must be debug, info, warn or error")
+
+// Level is the severity of a log message.
+type Level int
+
+// Log levels
+const (
+	// LevelDebug is used for API calls, pages of results and changes of
+	// query state.
+	LevelDebug Level = iota
+
+	// LevelInfo is used for queries starting and finishing.
+	LevelInfo
+
+	// LevelWarn is used for retried and throttled API calls, and queries
+	// stopped for scanning too much.
+	LevelWarn
+
+	// LevelError is used for failed API calls.
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// String returns the name of the level, e.g. info.
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "Level(" + strconv.Itoa(int(l)) + ")"
+	}
+
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the given name, e.g. info.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+
+	return 0, unknownLevel
+}
+
+// Logger receives the log messages of clients configured WithLogger,
+// with fields such as query_id. It may be called concurrently by clients
+// used concurrently.
+type Logger interface {
+	Log(level Level, msg string, fields map[string]interface{})
+}
+
+// LoggerFunc adapts a function to a Logger.
+type LoggerFunc func(level Level, msg string, fields map[string]interface{})
+
+// Log calls f.
+func (f LoggerFunc) Log(level Level, msg string, fields map[string]interface{}) {
+	f(level, msg, fields)
+}
+
+// NewStdLogger returns a logger writing messages of at least level min
+// to l, as the level, message and fields, e.g.
+//
+//	INFO query submitted database=db query_id=abc
+func NewStdLogger(l *log.Logger, min Level) Logger {
+	return LoggerFunc(func(level Level, msg string, fields map[string]interface{}) {
+		if level < min {
+			return
+		}
+
+		var b strings.Builder
+		b.WriteString(strings.ToUpper(level.String()))
+		b.WriteString(" ")
+		b.WriteString(msg)
+
+		for _, k := range sortedKeys(fields) {
+			v := fmt.Sprint(fieldValue(fields[k]))
+			if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+				v = strconv.Quote(v)
+			}
+
+			b.WriteString(" " + k + "=" + v)
+		}
+
+		l.Print(b.String())
+	})
+}
+
+// jsonLogger writes messages as JSON lines.
+type jsonLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min Level
+}
+
+// NewJSONLogger returns a logger writing messages of at least level min
+// to w as JSON, one per line, with their time, level, msg and fields.
+func NewJSONLogger(w io.Writer, min Level) Logger {
+	return &jsonLogger{w: w, min: min}
+}
+
+func (jl *jsonLogger) Log(level Level, msg string, fields map[string]interface{}) {
+	if level < jl.min {
+		return
+	}
+
+	var b bytes.Buffer
+	now, _ := json.Marshal(time.Now())
+	m, _ := json.Marshal(msg)
+	fmt.Fprintf(&b, `{"time":%s,"level":"%s","msg":%s`, now, level, m)
+
+	for _, k := range sortedKeys(fields) {
+		key, _ := json.Marshal(k)
+
+		v, err := json.Marshal(fieldValue(fields[k]))
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(fields[k]))
+		}
+
+		fmt.Fprintf(&b, ",%s:%s", key, v)
+	}
+
+	b.WriteString("}\n")
+
+	jl.mu.Lock()
+	defer jl.mu.Unlock()
+
+	jl.w.Write(b.Bytes())
+}
+
+// fieldValue converts errors and durations to strings, for logging.
+func fieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	}
+
+	return v
+}
+
+// sortedKeys returns the keys of fields in order.
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// WithLogger returns a copy of the client which logs the submission of
+// its queries, the changes of their state, the pages of their results
+// read and its calls to the Athena API with l. Calls are logged by an
+// interceptor added, after any the client already has, the first time it
+// is given a logger, so calls retried by those are logged as retries; see
+// WithInterceptors. l replaces any logger the client had, and if nil
+// nothing is logged.
+//
+// The text of queries is not logged unless configured WithQueryLogging.
+func (c Client) WithLogger(l Logger) Client {
+	c = c.withOptions(func(o *options) {
+		o.logger = l
+	})
+
+	if l == nil {
+		return c
+	}
+
+	return c.observe()
+}
+
+// logCall logs a call to the Athena API with l, if not nil.
+func logCall(call *Call, l Logger) {
+	if l == nil {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"operation": call.Operation,
+		"duration":  call.Duration,
+	}
+
+	if call.Attempts > 1 {
+		fields["attempt"] = call.Attempts
+	}
+
+	if call.Err == nil {
+		if call.Attempts > 1 {
+			l.Log(LevelWarn, "Athena API call retried", fields)
+		} else {
+			l.Log(LevelDebug, "Athena API call", fields)
+		}
+
+		return
+	}
+
+	fields["error"] = call.Err
+
+	var aerr awserr.Error
+	if errors.As(call.Err, &aerr) {
+		fields["error_code"] = aerr.Code()
+	}
+
+	if request.IsErrorThrottle(call.Err) {
+		l.Log(LevelWarn, "Athena API call throttled", fields)
+	} else {
+		l.Log(LevelError, "Athena API call failed", fields)
+	}
+}
+
+// WithQueryLogging returns a copy of the client which includes the text
+// of queries in the messages logged as they are submitted, truncated to
+// maxLength characters if maxLength is positive. See WithLogger.
+func (c Client) WithQueryLogging(maxLength int) Client {
+	return c.withOptions(func(o *options) {
+		o.logQueries = true
+		o.logQueryLength = maxLength
+	})
+}
+
+// log logs a message with the client's logger, if it has one.
+func (c Client) log(level Level, msg string, fields map[string]interface{}) {
+	if l := c.options().logger; l != nil {
+		l.Log(level, msg, fields)
+	}
+}
+
+// logSubmission logs the start of a query.
+func (c Client) logSubmission(id string, in *athena.StartQueryExecutionInput) {
+	o := c.options()
+	if o.logger == nil {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"query_id": id,
+		"database": *in.QueryExecutionContext.Database,
+	}
+
+	if in.WorkGroup != nil {
+		fields["workgroup"] = *in.WorkGroup
+	}
+
+	if o.logQueries {
+		query := *in.QueryString
+		if r := []rune(query); o.logQueryLength > 0 && len(r) > o.logQueryLength {
+			query = string(r[:o.logQueryLength]) + "..."
+		}
+
+		fields["query"] = query
+	}
+
+	o.logger.Log(LevelInfo, "query submitted", fields)
+}
+
+// logStatus logs a change in the state of a query from previous, and its
+// completion.
+func (q Query) logStatus(qe *athena.QueryExecution, previous string) {
+	state := *qe.Status.State
+	if q.options().logger == nil || state == previous {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"query_id":       q.id,
+		"state":          state,
+		"previous_state": previous,
+	}
+
+	if !terminal(state) {
+		q.log(LevelDebug, "query state changed", fields)
+		return
+	}
+
+	s := statistics(qe.Statistics)
+	fields["data_scanned_bytes"] = s.DataScannedInBytes
+	fields["execution_time"] = s.EngineExecutionTime
+
+	if qe.Status.StateChangeReason != nil {
+		fields["reason"] = *qe.Status.StateChangeReason
+	}
+
+	q.log(LevelInfo, "query finished", fields)
+}
@@ -0,0 +1,266 @@
+package athena_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// entry is a message logged to entries.
+type entry struct {
+	level  athena.Level
+	msg    string
+	fields map[string]interface{}
+}
+
+// entries records the messages logged.
+type entries struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+func (e *entries) Log(level athena.Level, msg string, fields map[string]interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.entries = append(e.entries, entry{level, msg, fields})
+}
+
+// find returns the first entry with the message, or an empty entry.
+func (e *entries) find(msg string) entry {
+	for _, en := range e.entries {
+		if en.msg == msg {
+			return en
+		}
+	}
+
+	return entry{}
+}
+
+func TestWithLogger(t *testing.T) {
+	states := []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning}
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid"},
+		getQueryExecution:   getQueryExecution{state: aa.QueryExecutionStateSucceeded, outLocation: "s3://bucket/jobid.csv"},
+		queryExecutionDetail: queryExecutionDetail{
+			states:     &states,
+			statistics: &aa.QueryExecutionStatistics{DataScannedInBytes: aws.Int64(1024)},
+		},
+	}
+
+	logs := &entries{}
+	c := athena.NewCustomClient(throttledOnce{mockClient: mc, throttled: new(bool)})
+	c = c.WithWorkGroup("analysts").WithPollInterval(time.Millisecond).WithInterceptors(athenatest.Retry(2, 0)).WithLogger(logs)
+
+	if _, err := c.Run(context.Background(), "db", "SELECT 1", "s3://bucket/"); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	var msgs []string
+	for _, e := range logs.entries {
+		if e.msg != "Athena API call" {
+			msgs = append(msgs, e.level.String()+" "+e.msg)
+		}
+	}
+
+	expected := []string{
+		"info query submitted",
+		"warn Athena API call throttled",
+		"warn Athena API call retried",
+		"debug query state changed",
+		"debug query state changed",
+		"info query finished",
+		"debug results page read",
+	}
+
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Fatalf("messages == %v (want %v)", msgs, expected)
+	}
+
+	for _, test := range []struct {
+		msg, key string
+		expected interface{}
+	}{
+		{"query submitted", "query_id", "jobid"},
+		{"query submitted", "database", "db"},
+		{"query submitted", "workgroup", "analysts"},
+		{"query submitted", "query", nil},
+		{"Athena API call throttled", "operation", "GetQueryExecution"},
+		{"Athena API call throttled", "error_code", aa.ErrCodeTooManyRequestsException},
+		{"Athena API call retried", "attempt", 2},
+		{"query state changed", "state", aa.QueryExecutionStateQueued},
+		{"query state changed", "previous_state", ""},
+		{"query finished", "state", aa.QueryExecutionStateSucceeded},
+		{"query finished", "data_scanned_bytes", int64(1024)},
+		{"results page read", "page", 0},
+	} {
+		if v := logs.find(test.msg).fields[test.key]; v != test.expected {
+			t.Errorf("%s %s == %v (want %v)", test.msg, test.key, v, test.expected)
+		}
+	}
+
+	mc.startQueryExecution.err = awserr.New(aa.ErrCodeInvalidRequestException, "bad request", nil)
+	logs = &entries{}
+
+	if _, err := athena.NewCustomClient(mc).WithLogger(logs).DoQuery("db", "SELECT 1", "s3://bucket/"); err == nil {
+		t.Fatalf("err == nil (want %v)", mc.startQueryExecution.err)
+	}
+
+	if e := logs.find("Athena API call failed"); e.level != athena.LevelError || e.fields["error_code"] != aa.ErrCodeInvalidRequestException {
+		t.Errorf("failure == %+v (want error with %s)", e, aa.ErrCodeInvalidRequestException)
+	}
+}
+
+func TestWithLoggerReplaced(t *testing.T) {
+	mc := mockClient{startQueryExecution: startQueryExecution{id: "jobid"}}
+	first, second := &entries{}, &entries{}
+
+	a := athena.NewCustomClient(mc).WithLogger(first)
+	b := a.WithLogger(second)
+
+	for _, test := range []struct {
+		c             athena.Client
+		first, second int
+	}{
+		{b, 0, 1},
+		{a, 1, 1},
+		{b.WithLogger(nil), 1, 1},
+	} {
+		if _, err := test.c.DoQuery("db", "SELECT 1", "s3://bucket/"); err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		for _, l := range []struct {
+			logs     *entries
+			expected int
+		}{
+			{first, test.first},
+			{second, test.second},
+		} {
+			n := 0
+			for _, e := range l.logs.entries {
+				if e.msg == "Athena API call" {
+					n++
+				}
+			}
+
+			if n != l.expected {
+				t.Errorf("calls logged == %d (want %d)", n, l.expected)
+			}
+		}
+	}
+}
+
+func TestWithQueryLogging(t *testing.T) {
+	mc := mockClient{startQueryExecution: startQueryExecution{id: "jobid"}}
+
+	for _, test := range []struct {
+		length   int
+		expected string
+	}{
+		{0, "SELECT 'héllo' FROM t"},
+		{10, "SELECT 'hé..."},
+		{21, "SELECT 'héllo' FROM t"},
+	} {
+		logs := &entries{}
+		c := athena.NewCustomClient(mc).WithLogger(logs).WithQueryLogging(test.length)
+
+		if _, err := c.DoQuery("db", "SELECT 'héllo' FROM t", "s3://bucket/"); err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		if query := logs.find("query submitted").fields["query"]; query != test.expected {
+			t.Errorf("query with %d == %v (want %q)", test.length, query, test.expected)
+		}
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var b bytes.Buffer
+	l := athena.NewStdLogger(log.New(&b, "", 0), athena.LevelInfo)
+
+	l.Log(athena.LevelDebug, "hidden", nil)
+	l.Log(athena.LevelInfo, "query submitted", map[string]interface{}{"query_id": "abc", "database": "db"})
+	l.Log(athena.LevelError, "Athena API call failed", map[string]interface{}{
+		"error":    awserr.New("InvalidRequestException", "bad request", nil),
+		"duration": 1500 * time.Millisecond,
+		"empty":    "",
+	})
+
+	expected := "INFO query submitted database=db query_id=abc\n" +
+		`ERROR Athena API call failed duration=1.5s empty="" error="InvalidRequestException: bad request"` + "\n"
+
+	if b.String() != expected {
+		t.Errorf("output == %q (want %q)", b.String(), expected)
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var b bytes.Buffer
+	l := athena.NewJSONLogger(&b, athena.LevelWarn)
+
+	l.Log(athena.LevelInfo, "hidden", nil)
+	l.Log(athena.LevelWarn, "query stopped", map[string]interface{}{
+		"query_id": "abc",
+		"error":    awserr.New("TooManyRequestsException", "slow down", nil),
+		"duration": time.Second,
+		"rows":     2,
+	})
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], `{"time":"`) {
+		t.Fatalf("output == %q (want one line starting with time)", b.String())
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	delete(m, "time")
+
+	expected := map[string]interface{}{
+		"level":    "warn",
+		"msg":      "query stopped",
+		"query_id": "abc",
+		"error":    "TooManyRequestsException: slow down",
+		"duration": "1s",
+		"rows":     2.0,
+	}
+
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("entry == %v (want %v)", m, expected)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		expected athena.Level
+		err      error
+	}{
+		{"debug", athena.LevelDebug, nil},
+		{"INFO", athena.LevelInfo, nil},
+		{"warn", athena.LevelWarn, nil},
+		{"error", athena.LevelError, nil},
+		{"verbose", 0, athena.ErrUnknownLevel},
+	} {
+		level, err := athena.ParseLevel(test.name)
+		if level != test.expected || err != test.err {
+			t.Errorf("ParseLevel(%q) == %v, %v (want %v, %v)", test.name, level, err, test.expected, test.err)
+		}
+	}
+}
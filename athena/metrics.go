@@ -0,0 +1,167 @@
+package athena
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// primaryWorkGroup is the workgroup queries run in unless configured
+// otherwise.
+const primaryWorkGroup = "primary"
+
+// Metrics receives measurements of the queries and API calls of clients
+// configured WithMetrics. Methods may be called concurrently by clients
+// used concurrently, so should return promptly.
+type Metrics interface {
+	// QuerySubmitted is called as a query is started, or fails to start.
+	QuerySubmitted(SubmittedQuery)
+
+	// QueryCompleted is called when Wait sees a query finish.
+	QueryCompleted(CompletedQuery)
+
+	// APICalled is called after each call to the Athena API.
+	APICalled(APICall)
+}
+
+// SubmittedQuery describes a query started, or failing to start.
+type SubmittedQuery struct {
+	WorkGroup string
+	Database  string
+
+	// Err is why the query could not be started, if it couldn't.
+	Err error
+}
+
+// CompletedQuery describes a query which finished.
+type CompletedQuery struct {
+	// ID is the Athena query execution ID.
+	ID string
+
+	WorkGroup string
+	Database  string
+
+	// State is SUCCEEDED, FAILED or CANCELLED.
+	State string
+
+	// QueueTime is how long the query waited to run, the time between its
+	// submission and completion it wasn't executing, and ExecutionTime how
+	// long it took to execute.
+	QueueTime     time.Duration
+	ExecutionTime time.Duration
+
+	DataScannedInBytes int64
+}
+
+// APICall describes a call to the Athena API.
+type APICall struct {
+	// Operation is the name of the API operation, e.g. StartQueryExecution.
+	Operation string
+
+	// Duration is how long the call took, including retries made by the
+	// AWS SDK, and Err the error it returned, if any.
+	Duration time.Duration
+	Err      error
+
+	// Retry is true if the call was a retry by an interceptor, and
+	// Throttled if Athena rejected it for exceeding its rate limits.
+	Retry     bool
+	Throttled bool
+}
+
+// WithMetrics returns a copy of the client which records the submission
+// and completion of its queries, and its calls to the Athena API, with m.
+// Calls are measured by an interceptor added, after any the client already
+// has, the first time it is given metrics, so calls retried by those are
+// measured as retries; see WithInterceptors. m replaces any metrics the
+// client had, and if nil nothing is measured. As completion is recorded by
+// Wait, waiting for a query more than once records its completion more
+// than once.
+func (c Client) WithMetrics(m Metrics) Client {
+	c = c.withOptions(func(o *options) {
+		o.metrics = m
+	})
+
+	if m == nil {
+		return c
+	}
+
+	return c.observe()
+}
+
+// measureCall records a call to the Athena API with m, if not nil.
+func measureCall(call *Call, m Metrics) {
+	if m == nil {
+		return
+	}
+
+	m.APICalled(APICall{
+		Operation: call.Operation,
+		Duration:  call.Duration,
+		Err:       call.Err,
+		Retry:     call.Attempts > 1,
+		Throttled: call.Err != nil && request.IsErrorThrottle(call.Err),
+	})
+}
+
+// measureSubmission records the start of a query, or the failure to start it.
+func (c Client) measureSubmission(in *athena.StartQueryExecutionInput, err error) {
+	m := c.options().metrics
+	if m == nil {
+		return
+	}
+
+	s := SubmittedQuery{
+		WorkGroup: primaryWorkGroup,
+		Database:  *in.QueryExecutionContext.Database,
+		Err:       err,
+	}
+
+	if in.WorkGroup != nil {
+		s.WorkGroup = *in.WorkGroup
+	}
+
+	m.QuerySubmitted(s)
+}
+
+// measureCompletion records the completion of a query; stopped is true if
+// it was stopped by the client, and so is being cancelled.
+func (q Query) measureCompletion(qe *athena.QueryExecution, stopped bool) {
+	m := q.options().metrics
+	if m == nil {
+		return
+	}
+
+	e := execution(qe)
+
+	cq := CompletedQuery{
+		ID:                 q.id,
+		WorkGroup:          e.WorkGroup,
+		Database:           e.Database,
+		State:              e.State,
+		ExecutionTime:      e.EngineExecutionTime,
+		DataScannedInBytes: e.DataScannedInBytes,
+	}
+
+	if cq.WorkGroup == "" {
+		cq.WorkGroup = primaryWorkGroup
+	}
+
+	if stopped && !terminal(cq.State) {
+		cq.State = athena.QueryExecutionStateCancelled
+	}
+
+	completion := e.CompletionTime
+	if completion.IsZero() {
+		completion = time.Now()
+	}
+
+	if !e.SubmissionTime.IsZero() {
+		if queued := completion.Sub(e.SubmissionTime) - cq.ExecutionTime; queued > 0 {
+			cq.QueueTime = queued
+		}
+	}
+
+	m.QueryCompleted(cq)
+}
@@ -0,0 +1,177 @@
+package athena_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/KablamoOSS/exportexample/athena/athenatest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// throttledOnce throttles the first GetQueryExecution call.
+type throttledOnce struct {
+	mockClient
+	throttled *bool
+}
+
+func (tc throttledOnce) GetQueryExecution(in *aa.GetQueryExecutionInput) (*aa.GetQueryExecutionOutput, error) {
+	if !*tc.throttled {
+		*tc.throttled = true
+		return nil, awserr.New(aa.ErrCodeTooManyRequestsException, "slow down", nil)
+	}
+
+	return tc.mockClient.GetQueryExecution(in)
+}
+
+func TestWithMetrics(t *testing.T) {
+	submitted := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+	states := []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning}
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid"},
+		getQueryExecution:   getQueryExecution{state: aa.QueryExecutionStateSucceeded, outLocation: "s3://bucket/jobid.csv"},
+		queryExecutionDetail: queryExecutionDetail{
+			states:     &states,
+			statistics: &aa.QueryExecutionStatistics{DataScannedInBytes: aws.Int64(1024), EngineExecutionTimeInMillis: aws.Int64(3000)},
+			database:   "db",
+			workGroup:  "analysts",
+			submitted:  submitted,
+			completed:  submitted.Add(5 * time.Second),
+		},
+	}
+
+	metrics := athena.NewPrometheusMetrics()
+
+	c := athena.NewCustomClient(throttledOnce{mockClient: mc, throttled: new(bool)})
+	c = c.WithWorkGroup("analysts").WithPollInterval(time.Millisecond).WithInterceptors(athenatest.Retry(2, 0)).WithMetrics(metrics)
+
+	if _, err := c.Run(context.Background(), "db", "SELECT 1", "s3://bucket/"); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		labels   []string
+		expected float64
+	}{
+		{athena.MetricQueriesSubmitted, []string{"analysts", "db"}, 1},
+		{athena.MetricQuerySubmitErrors, []string{"analysts", "db"}, 0},
+		{athena.MetricQueriesCompleted, []string{"analysts", "db", aa.QueryExecutionStateSucceeded}, 1},
+		{athena.MetricDataScanned, []string{"analysts", "db"}, 1024},
+		{athena.MetricAPICalls, []string{"StartQueryExecution"}, 1},
+		{athena.MetricAPICalls, []string{"GetQueryExecution"}, 5},
+		{athena.MetricAPICalls, []string{"GetQueryResults"}, 1},
+		{athena.MetricAPIErrors, []string{"GetQueryExecution"}, 1},
+		{athena.MetricAPIRetries, []string{"GetQueryExecution"}, 1},
+		{athena.MetricAPIThrottles, []string{"GetQueryExecution"}, 1},
+	} {
+		if v := metrics.Counter(test.name, test.labels...); v != test.expected {
+			t.Errorf("Counter(%s, %v) == %v (want %v)", test.name, test.labels, v, test.expected)
+		}
+	}
+
+	if n, sum := metrics.Histogram(athena.MetricQueueSeconds, "analysts"); n != 1 || sum != 2 {
+		t.Errorf("Histogram(%s) == %d, %v (want 1, 2)", athena.MetricQueueSeconds, n, sum)
+	}
+
+	if n, sum := metrics.Histogram(athena.MetricExecutionSeconds, "analysts"); n != 1 || sum != 3 {
+		t.Errorf("Histogram(%s) == %d, %v (want 1, 3)", athena.MetricExecutionSeconds, n, sum)
+	}
+
+	mc.startQueryExecution.err = awserr.New(aa.ErrCodeTooManyRequestsException, "slow down", nil)
+
+	if _, err := athena.NewCustomClient(mc).WithMetrics(metrics).DoQuery("db", "SELECT 1", "s3://bucket/"); err == nil {
+		t.Errorf("err == nil (want %v)", mc.startQueryExecution.err)
+	}
+
+	if v := metrics.Counter(athena.MetricQuerySubmitErrors, "primary", "db"); v != 1 {
+		t.Errorf("Counter(%s) == %v (want 1)", athena.MetricQuerySubmitErrors, v)
+	}
+
+	if v := metrics.Counter(athena.MetricAPIThrottles, "StartQueryExecution"); v != 1 {
+		t.Errorf("Counter(%s) == %v (want 1)", athena.MetricAPIThrottles, v)
+	}
+}
+
+func TestWithMetricsReplaced(t *testing.T) {
+	mc := mockClient{startQueryExecution: startQueryExecution{id: "jobid"}}
+	first, second := athena.NewPrometheusMetrics(), athena.NewPrometheusMetrics()
+
+	a := athena.NewCustomClient(mc).WithMetrics(first)
+	b := a.WithMetrics(second)
+
+	for _, test := range []struct {
+		c             athena.Client
+		first, second float64
+	}{
+		{b, 0, 1},
+		{a, 1, 1},
+		{b.WithMetrics(nil), 1, 1},
+	} {
+		if _, err := test.c.DoQuery("db", "SELECT 1", "s3://bucket/"); err != nil {
+			t.Fatalf("err == %v (want nil)", err)
+		}
+
+		for _, m := range []struct {
+			metrics  *athena.PrometheusMetrics
+			expected float64
+		}{
+			{first, test.first},
+			{second, test.second},
+		} {
+			if v := m.metrics.Counter(athena.MetricAPICalls, "StartQueryExecution"); v != m.expected {
+				t.Errorf("Counter(%s) == %v (want %v)", athena.MetricAPICalls, v, m.expected)
+			}
+		}
+	}
+}
+
+func TestPrometheusMetricsWriteTo(t *testing.T) {
+	metrics := athena.NewPrometheusMetrics()
+	metrics.QuerySubmitted(athena.SubmittedQuery{WorkGroup: "primary", Database: `we"ird`})
+	metrics.QueryCompleted(athena.CompletedQuery{
+		WorkGroup:          "primary",
+		Database:           "db",
+		State:              aa.QueryExecutionStateFailed,
+		QueueTime:          2 * time.Second,
+		ExecutionTime:      90 * time.Second,
+		DataScannedInBytes: 1 << 20,
+	})
+
+	var b bytes.Buffer
+	if _, err := metrics.WriteTo(&b); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	for _, line := range []string{
+		"# TYPE athena_queries_submitted_total counter",
+		`athena_queries_submitted_total{workgroup="primary",database="we\"ird"} 1`,
+		`athena_queries_completed_total{workgroup="primary",database="db",state="FAILED"} 1`,
+		`athena_data_scanned_bytes_total{workgroup="primary",database="db"} 1.048576e+06`,
+		"# TYPE athena_query_execution_seconds histogram",
+		`athena_query_execution_seconds_bucket{workgroup="primary",le="60"} 0`,
+		`athena_query_execution_seconds_bucket{workgroup="primary",le="120"} 1`,
+		`athena_query_execution_seconds_bucket{workgroup="primary",le="+Inf"} 1`,
+		`athena_query_execution_seconds_sum{workgroup="primary"} 90`,
+		`athena_query_execution_seconds_count{workgroup="primary"} 1`,
+		"# TYPE athena_api_calls_total counter",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("output missing %q:\n%s", line, b.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if rec.Body.String() != b.String() || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("ServeHTTP served %q, %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
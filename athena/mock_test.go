@@ -1,6 +1,9 @@
 package athena_test
 
 import (
+	"strconv"
+	"time"
+
 	aa "github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
 )
@@ -8,6 +11,12 @@ import (
 type startQueryExecution struct {
 	id  string
 	err error
+
+	// queries records the submitted query strings, if not nil.
+	queries *[]string
+
+	// inputs records the submitted requests, if not nil.
+	inputs *[]*aa.StartQueryExecutionInput
 }
 
 type getQueryExecution struct {
@@ -16,6 +25,23 @@ type getQueryExecution struct {
 	err         error
 }
 
+// queryExecutionDetail supplements getQueryExecution with optional fields.
+type queryExecutionDetail struct {
+	reason     string
+	statistics *aa.QueryExecutionStatistics
+
+	// states, if not nil, are the states returned by successive calls,
+	// before that of getQueryExecution.
+	states *[]string
+
+	database, workGroup  string
+	submitted, completed time.Time
+
+	// statementType is DML unless set; the results of DDL statements
+	// have no header row.
+	statementType string
+}
+
 type getQueryResults struct {
 	columns []*aa.ColumnInfo
 	rows    []*aa.Row
@@ -23,24 +49,72 @@ type getQueryResults struct {
 	err error
 }
 
+// listing configures the List and BatchGet operations. Lists are returned
+// in pages of pageSize items, or all at once if pageSize is zero.
+type listing struct {
+	executions   []*aa.QueryExecution
+	namedQueries []*aa.NamedQuery
+	workGroups   []*aa.WorkGroupSummary
+	pageSize     int
+
+	// stopped records the IDs of stopped queries, if not nil.
+	stopped *[]string
+
+	// updated records UpdateWorkGroup requests, if not nil.
+	updated *[]*aa.UpdateWorkGroupInput
+
+	err error
+}
+
 type mockClient struct {
 	startQueryExecution
 	getQueryExecution
+	queryExecutionDetail
 	getQueryResults
+	listing
 
 	athenaiface.AthenaAPI
 }
 
 func (mc mockClient) StartQueryExecution(in *aa.StartQueryExecutionInput) (*aa.StartQueryExecutionOutput, error) {
 	id := mc.startQueryExecution.id
+	if mc.startQueryExecution.inputs != nil {
+		*mc.startQueryExecution.inputs = append(*mc.startQueryExecution.inputs, in)
+	}
+
+	if mc.startQueryExecution.queries != nil {
+		*mc.startQueryExecution.queries = append(*mc.startQueryExecution.queries, *in.QueryString)
+	}
+
 	out := (&aa.StartQueryExecutionOutput{}).SetQueryExecutionId(id)
 	return out, mc.startQueryExecution.err
 }
 
 func (mc mockClient) GetQueryExecution(in *aa.GetQueryExecutionInput) (*aa.GetQueryExecutionOutput, error) {
 	s := (&aa.QueryExecutionStatus{}).SetState(mc.getQueryExecution.state)
+	if states := mc.queryExecutionDetail.states; states != nil && len(*states) > 0 {
+		s.SetState((*states)[0])
+		*states = (*states)[1:]
+	}
+
 	rc := (&aa.ResultConfiguration{}).SetOutputLocation(mc.getQueryExecution.outLocation)
-	qe := (&aa.QueryExecution{}).SetStatus(s).SetResultConfiguration(rc)
+	if mc.queryExecutionDetail.reason != "" {
+		s.SetStateChangeReason(mc.queryExecutionDetail.reason)
+	}
+
+	if d := mc.queryExecutionDetail; !d.submitted.IsZero() {
+		s.SetSubmissionDateTime(d.submitted).SetCompletionDateTime(d.completed)
+	}
+
+	qe := (&aa.QueryExecution{}).SetStatus(s).SetResultConfiguration(rc).SetStatistics(mc.queryExecutionDetail.statistics)
+	if d := mc.queryExecutionDetail; d.database != "" {
+		qe.SetQueryExecutionContext((&aa.QueryExecutionContext{}).SetDatabase(d.database)).SetWorkGroup(d.workGroup)
+	}
+
+	if d := mc.queryExecutionDetail; d.statementType != "" {
+		qe.SetStatementType(d.statementType)
+	}
+
 	out := (&aa.GetQueryExecutionOutput{}).SetQueryExecution(qe)
 	return out, mc.getQueryExecution.err
 }
@@ -54,8 +128,97 @@ func (mc mockClient) GetQueryResults(in *aa.GetQueryResultsInput) (*aa.GetQueryR
 		headerRow.Data[i] = &datum
 	}
 	rows := append([]*aa.Row{&headerRow}, mc.getQueryResults.rows...)
+	if mc.queryExecutionDetail.statementType == aa.StatementTypeDdl {
+		rows = rows[1:]
+	}
+
 	rsm := (&aa.ResultSetMetadata{}).SetColumnInfo(mc.getQueryResults.columns)
 	rs := (&aa.ResultSet{}).SetRows(rows).SetResultSetMetadata(rsm)
 	out := (&aa.GetQueryResultsOutput{}).SetResultSet(rs)
 	return out, mc.getQueryResults.err
 }
+
+// page returns the bounds of the page of n items starting at the NextToken,
+// and the NextToken of the following page.
+func (l listing) page(token *string, n int) (int, int, *string) {
+	start := 0
+	if token != nil {
+		start, _ = strconv.Atoi(*token)
+	}
+
+	if l.pageSize == 0 || start+l.pageSize >= n {
+		return start, n, nil
+	}
+
+	next := strconv.Itoa(start + l.pageSize)
+	return start, start + l.pageSize, &next
+}
+
+func (mc mockClient) ListQueryExecutions(in *aa.ListQueryExecutionsInput) (*aa.ListQueryExecutionsOutput, error) {
+	start, end, next := mc.listing.page(in.NextToken, len(mc.listing.executions))
+	out := &aa.ListQueryExecutionsOutput{NextToken: next}
+	for _, qe := range mc.listing.executions[start:end] {
+		out.QueryExecutionIds = append(out.QueryExecutionIds, qe.QueryExecutionId)
+	}
+
+	return out, mc.listing.err
+}
+
+func (mc mockClient) BatchGetQueryExecution(in *aa.BatchGetQueryExecutionInput) (*aa.BatchGetQueryExecutionOutput, error) {
+	out := &aa.BatchGetQueryExecutionOutput{}
+	for _, id := range in.QueryExecutionIds {
+		for _, qe := range mc.listing.executions {
+			if *qe.QueryExecutionId == *id {
+				out.QueryExecutions = append(out.QueryExecutions, qe)
+			}
+		}
+	}
+
+	return out, mc.listing.err
+}
+
+func (mc mockClient) ListNamedQueries(in *aa.ListNamedQueriesInput) (*aa.ListNamedQueriesOutput, error) {
+	start, end, next := mc.listing.page(in.NextToken, len(mc.listing.namedQueries))
+	out := &aa.ListNamedQueriesOutput{NextToken: next}
+	for _, nq := range mc.listing.namedQueries[start:end] {
+		out.NamedQueryIds = append(out.NamedQueryIds, nq.NamedQueryId)
+	}
+
+	return out, mc.listing.err
+}
+
+func (mc mockClient) BatchGetNamedQuery(in *aa.BatchGetNamedQueryInput) (*aa.BatchGetNamedQueryOutput, error) {
+	out := &aa.BatchGetNamedQueryOutput{}
+	for _, id := range in.NamedQueryIds {
+		for _, nq := range mc.listing.namedQueries {
+			if *nq.NamedQueryId == *id {
+				out.NamedQueries = append(out.NamedQueries, nq)
+			}
+		}
+	}
+
+	return out, mc.listing.err
+}
+
+func (mc mockClient) ListWorkGroups(in *aa.ListWorkGroupsInput) (*aa.ListWorkGroupsOutput, error) {
+	start, end, next := mc.listing.page(in.NextToken, len(mc.listing.workGroups))
+	out := &aa.ListWorkGroupsOutput{NextToken: next, WorkGroups: mc.listing.workGroups[start:end]}
+
+	return out, mc.listing.err
+}
+
+func (mc mockClient) UpdateWorkGroup(in *aa.UpdateWorkGroupInput) (*aa.UpdateWorkGroupOutput, error) {
+	if mc.listing.updated != nil {
+		*mc.listing.updated = append(*mc.listing.updated, in)
+	}
+
+	return &aa.UpdateWorkGroupOutput{}, mc.listing.err
+}
+
+func (mc mockClient) StopQueryExecution(in *aa.StopQueryExecutionInput) (*aa.StopQueryExecutionOutput, error) {
+	if mc.listing.stopped != nil {
+		*mc.listing.stopped = append(*mc.listing.stopped, *in.QueryExecutionId)
+	}
+
+	return &aa.StopQueryExecutionOutput{}, mc.listing.err
+}
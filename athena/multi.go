@@ -0,0 +1,285 @@
+package athena
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors for invalid fan-outs
+const (
+	noTargets          = constError("no targets to query")
+	emptyTargetName    = constError("target name must not be an empty string")
+	duplicateTarget    = constError("target name is not unique")
+	sourceColumnExists = constError("result already has a column named as the source column")
+)
+
+// DefaultSourceColumn is the column MultiClient.Run adds to merged rows,
+// naming the target each row came from, unless MultiOptions says otherwise.
+const DefaultSourceColumn = "source"
+
+// Target is one of the clients of a MultiClient, such as a client for a
+// region or, with NewClientWithRole, an account.
+type Target struct {
+	// Name identifies the target in results, e.g. prod-us-east-1.
+	Name string
+
+	Client Client
+
+	// Database and Output are the database queried and where results are
+	// stored for this target; see DoQuery.
+	Database string
+	Output   string
+}
+
+// MultiClient runs the same query against several targets at once, e.g.
+// identical tables in several regions and accounts, and merges their
+// results.
+type MultiClient struct {
+	targets []Target
+}
+
+// NewMultiClient returns a client for the targets, which must have unique,
+// non-empty names.
+func NewMultiClient(targets ...Target) (MultiClient, error) {
+	if len(targets) == 0 {
+		return MultiClient{}, noTargets
+	}
+
+	names := make(map[string]bool, len(targets))
+
+	for _, t := range targets {
+		if t.Name == "" {
+			return MultiClient{}, emptyTargetName
+		}
+
+		if names[t.Name] {
+			return MultiClient{}, fmt.Errorf("%s: %w", t.Name, duplicateTarget)
+		}
+
+		names[t.Name] = true
+	}
+
+	return MultiClient{targets: append([]Target(nil), targets...)}, nil
+}
+
+// Targets returns the targets of the client, in the order given.
+func (m MultiClient) Targets() []Target {
+	return append([]Target(nil), m.targets...)
+}
+
+// MultiOptions configures how MultiClient.Run fans out a query.
+type MultiOptions struct {
+	// RequireAll fails the whole fan-out if any target fails, stopping the
+	// queries still running and not starting those of the targets yet to
+	// be queried; otherwise the results of the targets which succeeded are
+	// merged, and failures are reported per target.
+	RequireAll bool
+
+	// Concurrency is the maximum number of targets queried at once, taken
+	// in the order given to NewMultiClient; values less than 1 query every
+	// target at once.
+	Concurrency int
+
+	// SourceColumn names the column added to merged rows naming their
+	// target; DefaultSourceColumn is used if empty.
+	SourceColumn string
+}
+
+// TargetResult reports the outcome of a query on one target.
+type TargetResult struct {
+	// Name is the name of the target.
+	Name string `json:"name"`
+
+	// Rows is the number of rows the target returned.
+	Rows int `json:"rows"`
+
+	// Duration is how long the target took, including time queued.
+	Duration time.Duration `json:"duration"`
+
+	// Err is the reason the query failed on the target, if it did.
+	Err error `json:"-"`
+}
+
+// MultiError is returned by MultiClient.Run when targets failed and the
+// fan-out was configured to require all of them, or when every target
+// failed.
+type MultiError struct {
+	// Failed names the targets which failed.
+	Failed []string
+
+	// Stopped names the targets whose queries were stopped, or never
+	// started, as another target failed; see MultiOptions.RequireAll.
+	Stopped []string
+}
+
+// Error satisfies the error interface.
+func (e *MultiError) Error() string {
+	return fmt.Sprintf("%d target(s) failed: %s", len(e.Failed), strings.Join(e.Failed, ", "))
+}
+
+// Run runs query on every target concurrently, waiting for each to
+// complete, and merges their results into one.
+//
+// The merged result has a leading source column naming the target of each
+// row, followed by the union of the targets' columns in the order they
+// first appear, taking targets in order. Rows of targets lacking a column
+// have an empty value for it. Where targets disagree on the type of a
+// column, it is given as varchar. Like the results of Run, the merged
+// result starts with a row of column names; rows follow in target order.
+//
+// A result is returned for every target, in the order given. Unless
+// opts.RequireAll is set, the fan-out only fails, with a *MultiError, if
+// every target fails.
+func (m MultiClient) Run(ctx context.Context, query string, opts MultiOptions) (Result, []TargetResult, error) {
+	if query == "" {
+		return Result{}, nil, emptyQuery
+	}
+
+	if len(m.targets) == 0 {
+		return Result{}, nil, noTargets
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 || concurrency > len(m.targets) {
+		concurrency = len(m.targets)
+	}
+
+	// a failure stops the queries of the other targets if all are required
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type completion struct {
+		i      int
+		result Result
+		tr     TargetResult
+	}
+
+	done := make(chan completion, len(m.targets))
+	sem := make(chan struct{}, concurrency)
+
+	// targets take their turn in order
+	for i, t := range m.targets {
+		sem <- struct{}{}
+
+		go func(i int, t Target) {
+			defer func() { <-sem }()
+
+			start := time.Now()
+
+			// targets waiting their turn aren't queried once ctx is done
+			err := ctx.Err()
+
+			var r Result
+			if err == nil {
+				r, err = t.Client.run(ctx, t.Database, query, t.Output, true)
+				r = r.WithoutHeader()
+			}
+
+			// cancelled before the turn passes, so no other query starts
+			if err != nil && opts.RequireAll {
+				cancel()
+			}
+
+			done <- completion{i, r, TargetResult{Name: t.Name, Rows: len(r.Rows), Duration: time.Since(start), Err: err}}
+		}(i, t)
+	}
+
+	results := make([]Result, len(m.targets))
+	trs := make([]TargetResult, len(m.targets))
+
+	for range m.targets {
+		comp := <-done
+		results[comp.i] = comp.result
+		trs[comp.i] = comp.tr
+	}
+
+	var merr MultiError
+	var succeeded []int
+
+	for i, tr := range trs {
+		switch {
+		case tr.Err == nil:
+			succeeded = append(succeeded, i)
+		case errors.Is(tr.Err, context.Canceled) && parent.Err() == nil:
+			merr.Stopped = append(merr.Stopped, tr.Name)
+		default:
+			merr.Failed = append(merr.Failed, tr.Name)
+		}
+	}
+
+	if len(merr.Failed) > 0 && (opts.RequireAll || len(succeeded) == 0) {
+		return Result{}, trs, &merr
+	}
+
+	source := opts.SourceColumn
+	if source == "" {
+		source = DefaultSourceColumn
+	}
+
+	names := make([]string, len(succeeded))
+	rs := make([]Result, len(succeeded))
+	for j, i := range succeeded {
+		names[j], rs[j] = trs[i].Name, results[i]
+	}
+
+	merged, err := mergeResults(source, names, rs)
+	if err != nil {
+		return Result{}, trs, err
+	}
+
+	return merged, trs, nil
+}
+
+// mergeResults merges results without header rows into one with a header
+// row, tagging each row with the name of its result in the source column.
+func mergeResults(source string, names []string, results []Result) (Result, error) {
+	merged := Result{Columns: []Column{{Name: source, Type: "varchar"}}}
+	index := map[string]int{}
+
+	for _, r := range results {
+		for _, c := range r.Columns {
+			if c.Name == source {
+				return Result{}, fmt.Errorf("%s: %w", source, sourceColumnExists)
+			}
+
+			i, ok := index[c.Name]
+			if !ok {
+				index[c.Name] = len(merged.Columns)
+				merged.Columns = append(merged.Columns, c)
+				continue
+			}
+
+			if merged.Columns[i].Type != c.Type {
+				merged.Columns[i].Type = "varchar"
+			}
+		}
+	}
+
+	header := make(Row, len(merged.Columns))
+	for i, c := range merged.Columns {
+		header[i] = c.Name
+	}
+
+	merged.Rows = append(merged.Rows, header)
+
+	for n, r := range results {
+		for _, row := range r.Rows {
+			out := make(Row, len(merged.Columns))
+			out[0] = names[n]
+
+			for i, c := range r.Columns {
+				if i < len(row) {
+					out[index[c.Name]] = row[i]
+				}
+			}
+
+			merged.Rows = append(merged.Rows, out)
+		}
+	}
+
+	return merged, nil
+}
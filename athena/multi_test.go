@@ -0,0 +1,219 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// target returns a target whose query succeeds with a row per element of
+// rows, or fails with err.
+func target(name string, columns []string, types []string, rows [][]string, err error) athena.Target {
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: name},
+		getQueryExecution:   getQueryExecution{state: aa.QueryExecutionStateSucceeded},
+	}
+
+	for i, c := range columns {
+		mc.getQueryResults.columns = append(mc.getQueryResults.columns, &aa.ColumnInfo{Name: aws.String(c), Type: aws.String(types[i])})
+	}
+
+	for _, row := range rows {
+		r := &aa.Row{}
+		for i := range row {
+			r.Data = append(r.Data, &aa.Datum{VarCharValue: aws.String(row[i])})
+		}
+
+		mc.getQueryResults.rows = append(mc.getQueryResults.rows, r)
+	}
+
+	if err != nil {
+		mc.getQueryExecution.state = aa.QueryExecutionStateFailed
+		mc.queryExecutionDetail.reason = err.Error()
+	}
+
+	return athena.Target{Name: name, Client: athena.NewCustomClient(mc), Database: "db", Output: "s3://bucket/"}
+}
+
+func TestNewMultiClient(t *testing.T) {
+	a := target("a", nil, nil, nil, nil)
+
+	for i, test := range []struct {
+		targets []athena.Target
+		err     error
+	}{
+		{[]athena.Target{a}, nil},
+		{nil, athena.ErrNoTargets},
+		{[]athena.Target{{}}, athena.ErrEmptyTargetName},
+		{[]athena.Target{a, a}, athena.ErrDuplicateTarget},
+	} {
+		_, err := athena.NewMultiClient(test.targets...)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+	}
+}
+
+func TestMultiClientRun(t *testing.T) {
+	us := target("us", []string{"id", "n"}, []string{"varchar", "bigint"}, [][]string{{"a", "1"}, {"b", "2"}}, nil)
+	eu := target("eu", []string{"n", "extra"}, []string{"double", "varchar"}, [][]string{{"3", "x"}}, nil)
+	ap := target("ap", []string{"id"}, []string{"varchar"}, nil, errors.New("table not found"))
+
+	merged := athena.Result{
+		Columns: []athena.Column{
+			{Name: "region", Type: "varchar"},
+			{Name: "id", Type: "varchar"},
+			{Name: "n", Type: "varchar"},
+			{Name: "extra", Type: "varchar"},
+		},
+		Rows: []athena.Row{
+			{"region", "id", "n", "extra"},
+			{"us", "a", "1", ""},
+			{"us", "b", "2", ""},
+			{"eu", "", "3", "x"},
+		},
+	}
+
+	for i, test := range []struct {
+		targets  []athena.Target
+		opts     athena.MultiOptions
+		expected athena.Result
+		rows     []int
+		failed   []string
+	}{
+		{[]athena.Target{us, eu}, athena.MultiOptions{SourceColumn: "region"}, merged, []int{2, 1}, nil},
+		{[]athena.Target{us, ap, eu}, athena.MultiOptions{SourceColumn: "region", Concurrency: 1}, merged, []int{2, 0, 1}, nil},
+		{[]athena.Target{us, ap, eu}, athena.MultiOptions{RequireAll: true}, athena.Result{}, nil, []string{"ap"}},
+		{[]athena.Target{ap}, athena.MultiOptions{}, athena.Result{}, nil, []string{"ap"}},
+	} {
+		m, err := athena.NewMultiClient(test.targets...)
+		if err != nil {
+			t.Fatalf("%d: err == %v (want nil)", i, err)
+		}
+
+		r, trs, err := m.Run(context.Background(), "SELECT * FROM t", test.opts)
+
+		var merr *athena.MultiError
+		if test.failed != nil {
+			if !errors.As(err, &merr) || !reflect.DeepEqual(merr.Failed, test.failed) {
+				t.Errorf("%d: err == %v (want failures of %v)", i, err, test.failed)
+			}
+		} else if err != nil {
+			t.Errorf("%d: err == %v (want nil)", i, err)
+		}
+
+		// columns converted from the SDK also record that their details are absent
+		for j := range r.Columns {
+			r.Columns[j] = athena.Column{Name: r.Columns[j].Name, Type: r.Columns[j].Type}
+		}
+
+		if !reflect.DeepEqual(r, test.expected) {
+			t.Errorf("%d: Result == %v (want %v)", i, r, test.expected)
+		}
+
+		if len(trs) != len(test.targets) {
+			t.Fatalf("%d: len(TargetResults) == %v (want %v)", i, len(trs), len(test.targets))
+		}
+
+		for j, tr := range trs {
+			if tr.Name != test.targets[j].Name {
+				t.Errorf("%d: TargetResults[%d].Name == %v (want %v)", i, j, tr.Name, test.targets[j].Name)
+			}
+
+			if test.rows != nil && tr.Rows != test.rows[j] {
+				t.Errorf("%d: TargetResults[%d].Rows == %v (want %v)", i, j, tr.Rows, test.rows[j])
+			}
+
+			// targets may be stopped, without failing, once ap fails
+			if failed := tr.Err != nil && !errors.Is(tr.Err, context.Canceled); failed != (tr.Name == "ap") {
+				t.Errorf("%d: TargetResults[%d].Err == %v", i, j, tr.Err)
+			}
+		}
+	}
+}
+
+func TestMultiClientRunRequireAll(t *testing.T) {
+	var started, stopped []string
+
+	// running never completes, unless stopped
+	running := mockClient{
+		startQueryExecution: startQueryExecution{id: "running", queries: &started},
+		getQueryExecution:   getQueryExecution{state: aa.QueryExecutionStateRunning},
+		listing:             listing{stopped: &stopped},
+	}
+
+	var queued []string
+	waiting := running
+	waiting.startQueryExecution = startQueryExecution{id: "queued", queries: &queued}
+
+	// failing submits its query once running has submitted its own
+	submitted := make(chan struct{})
+	signal := func(call *athena.Call, invoke athena.Invoker) {
+		invoke(call)
+		if call.Operation == "StartQueryExecution" {
+			close(submitted)
+		}
+	}
+	await := func(call *athena.Call, invoke athena.Invoker) {
+		if call.Operation == "StartQueryExecution" {
+			<-submitted
+		}
+		invoke(call)
+	}
+
+	failing := target("failing", nil, nil, nil, errors.New("table not found"))
+	failing.Client = failing.Client.WithInterceptors(await)
+
+	m, err := athena.NewMultiClient(
+		athena.Target{Name: "running", Client: athena.NewCustomClient(running).WithPollInterval(time.Millisecond).WithInterceptors(signal), Database: "db", Output: "s3://bucket/"},
+		failing,
+		athena.Target{Name: "queued", Client: athena.NewCustomClient(waiting).WithPollInterval(time.Millisecond), Database: "db", Output: "s3://bucket/"},
+	)
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	_, trs, err := m.Run(context.Background(), "SELECT * FROM t", athena.MultiOptions{RequireAll: true, Concurrency: 2})
+
+	var merr *athena.MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("err == %v (want a *MultiError)", err)
+	}
+
+	if expected := []string{"failing"}; !reflect.DeepEqual(merr.Failed, expected) {
+		t.Errorf("Failed == %v (want %v)", merr.Failed, expected)
+	}
+
+	if expected := []string{"running", "queued"}; !reflect.DeepEqual(merr.Stopped, expected) {
+		t.Errorf("Stopped == %v (want %v)", merr.Stopped, expected)
+	}
+
+	if len(started) != 1 || !reflect.DeepEqual(stopped, []string{"running"}) {
+		t.Errorf("running: started %v, stopped %v (want started and stopped)", started, stopped)
+	}
+
+	if len(queued) != 0 {
+		t.Errorf("queued: started %v (want never started)", queued)
+	}
+
+	for _, tr := range trs {
+		if tr.Name != "failing" && !errors.Is(tr.Err, context.Canceled) {
+			t.Errorf("%s: err == %v (want %v)", tr.Name, tr.Err, context.Canceled)
+		}
+	}
+}
+
+func TestMultiClientSourceColumnExists(t *testing.T) {
+	m, _ := athena.NewMultiClient(target("us", []string{"source"}, []string{"varchar"}, nil, nil))
+
+	_, _, err := m.Run(context.Background(), "SELECT 1", athena.MultiOptions{})
+	if !errors.Is(err, athena.ErrSourceColumnExists) {
+		t.Errorf("err == %v (want %v)", err, athena.ErrSourceColumnExists)
+	}
+}
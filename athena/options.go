@@ -0,0 +1,154 @@
+package athena
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// invalidEncryption is returned when starting a query on a client with an
+// unknown encryption option, or a KMS option without a key.
+const invalidEncryption = constError("encryption option must be SSE_S3, or SSE_KMS or CSE_KMS with a KMS key")
+
+// defaultPollInterval is how often query status is checked when waiting
+// for a query to complete, unless overridden with WithPollInterval.
+const defaultPollInterval = 1 * time.Second
+
+// options holds optional Client behaviour.
+//
+// It is referenced by pointer from Client so that Client (and Query, which
+// embeds it) remain comparable values.
+type options struct {
+	poll time.Duration
+
+	// progress is called as Wait checks the status of a query.
+	progress func(Progress)
+
+	// pricePerTB is the price used to compute the cost of queries.
+	pricePerTB float64
+
+	// queryLimit is the number of bytes a query may scan before Wait stops
+	// it, and budget the shared budget queries are recorded in.
+	queryLimit int64
+	budget     *Budget
+
+	// auditor records the queries started and waited for.
+	auditor *Auditor
+
+	// metrics measures the queries started and waited for.
+	metrics Metrics
+
+	// tracer traces the queries started, waited for and read.
+	tracer Tracer
+
+	// logger logs the queries started, waited for and read, and the API
+	// calls made; the text of queries is logged if logQueries is set,
+	// truncated to logQueryLength characters if that is positive.
+	logger         Logger
+	logQueries     bool
+	logQueryLength int
+
+	// workGroup and encryption configure the queries the client starts.
+	workGroup  string
+	encryption string
+	kmsKey     string
+}
+
+// options returns a copy of the client's options, or the zero options
+// if none have been set.
+func (c Client) options() options {
+	if c.opts == nil {
+		return options{}
+	}
+
+	return *c.opts
+}
+
+// withOptions returns a copy of the client using the options modified by fn;
+// the receiver is left untouched.
+func (c Client) withOptions(fn func(*options)) Client {
+	o := c.options()
+	fn(&o)
+	c.opts = &o
+
+	// calls observed are observed with the options as they now are
+	if i, ok := c.api.(*intercepted); ok && i.opts != nil {
+		observed := *i
+		observed.opts = c.opts
+		c.api = &observed
+	}
+
+	return c
+}
+
+// WithPollInterval returns a copy of the client which checks query status
+// every d when waiting for a query to complete.
+func (c Client) WithPollInterval(d time.Duration) Client {
+	return c.withOptions(func(o *options) {
+		o.poll = d
+	})
+}
+
+// WithProgress returns a copy of the client which calls fn with the
+// progress of a query each time Wait checks its status, including the
+// final check. fn is called synchronously, so should return promptly.
+func (c Client) WithProgress(fn func(Progress)) Client {
+	return c.withOptions(func(o *options) {
+		o.progress = fn
+	})
+}
+
+// pollInterval returns the configured poll interval, or the default.
+func (c Client) pollInterval() time.Duration {
+	if p := c.options().poll; p > 0 {
+		return p
+	}
+
+	return defaultPollInterval
+}
+
+// WithWorkGroup returns a copy of the client which starts queries in the
+// named workgroup, rather than the primary workgroup.
+func (c Client) WithWorkGroup(name string) Client {
+	return c.withOptions(func(o *options) {
+		o.workGroup = name
+	})
+}
+
+// WithEncryption returns a copy of the client which encrypts the results
+// of the queries it starts. The option is one of SSE_S3, SSE_KMS or
+// CSE_KMS; the KMS options require the ARN or ID of the KMS key.
+func (c Client) WithEncryption(option, kmsKey string) Client {
+	return c.withOptions(func(o *options) {
+		o.encryption = option
+		o.kmsKey = kmsKey
+	})
+}
+
+// configure applies the options to a query about to be started.
+func (o options) configure(in *athena.StartQueryExecutionInput) error {
+	if o.workGroup != "" {
+		in.SetWorkGroup(o.workGroup)
+	}
+
+	switch o.encryption {
+	case "":
+		return nil
+	case athena.EncryptionOptionSseS3:
+	case athena.EncryptionOptionSseKms, athena.EncryptionOptionCseKms:
+		if o.kmsKey == "" {
+			return invalidEncryption
+		}
+	default:
+		return invalidEncryption
+	}
+
+	ec := (&athena.EncryptionConfiguration{}).SetEncryptionOption(o.encryption)
+	if o.kmsKey != "" {
+		ec.SetKmsKey(o.kmsKey)
+	}
+
+	in.ResultConfiguration.SetEncryptionConfiguration(ec)
+
+	return nil
+}
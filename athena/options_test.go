@@ -0,0 +1,69 @@
+package athena_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestQueryOptions(t *testing.T) {
+	for i, test := range []struct {
+		configure  func(athena.Client) athena.Client
+		workGroup  *string
+		encryption *aa.EncryptionConfiguration
+		err        error
+	}{
+		{func(c athena.Client) athena.Client { return c }, nil, nil, nil},
+		{
+			func(c athena.Client) athena.Client { return c.WithWorkGroup("etl") },
+			aws.String("etl"), nil, nil,
+		},
+		{
+			func(c athena.Client) athena.Client { return c.WithEncryption("SSE_S3", "") },
+			nil, (&aa.EncryptionConfiguration{}).SetEncryptionOption("SSE_S3"), nil,
+		},
+		{
+			func(c athena.Client) athena.Client { return c.WithWorkGroup("etl").WithEncryption("SSE_KMS", "key") },
+			aws.String("etl"), (&aa.EncryptionConfiguration{}).SetEncryptionOption("SSE_KMS").SetKmsKey("key"), nil,
+		},
+		{
+			func(c athena.Client) athena.Client { return c.WithEncryption("CSE_KMS", "") },
+			nil, nil, athena.ErrInvalidEncryption,
+		},
+		{
+			func(c athena.Client) athena.Client { return c.WithEncryption("ROT13", "") },
+			nil, nil, athena.ErrInvalidEncryption,
+		},
+	} {
+		var inputs []*aa.StartQueryExecutionInput
+		mc := mockClient{startQueryExecution: startQueryExecution{id: "jobid", inputs: &inputs}}
+
+		_, err := test.configure(athena.NewCustomClient(mc)).DoQuery("database", "query", "s3://output")
+		if err != test.err {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if test.err != nil {
+			if len(inputs) != 0 {
+				t.Errorf("%d: len(inputs) == %d (want 0)", i, len(inputs))
+			}
+
+			continue
+		}
+
+		if len(inputs) != 1 {
+			t.Fatalf("%d: len(inputs) == %d (want 1)", i, len(inputs))
+		}
+
+		if !reflect.DeepEqual(inputs[0].WorkGroup, test.workGroup) {
+			t.Errorf("%d: WorkGroup == %v (want %v)", i, inputs[0].WorkGroup, test.workGroup)
+		}
+
+		if ec := inputs[0].ResultConfiguration.EncryptionConfiguration; !reflect.DeepEqual(ec, test.encryption) {
+			t.Errorf("%d: EncryptionConfiguration == %v (want %v)", i, ec, test.encryption)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package athena
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxQueryLength is the maximum length in bytes of a query string Athena
+// will accept.
+const MaxQueryLength = 262144
+
+// Errors for invalid partitions
+const (
+	noPartitionValues   = constError("partition must have at least one value")
+	noPartitionKeys     = constError("partitioning must have at least one key")
+	invalidInterval     = constError("interval must be positive")
+	invalidPartition    = constError("partition must be of the form key=value[/key=value...]")
+	partitionKeyMissing = constError("partition does not have the key")
+	partitionTooLong    = constError("partition clause exceeds the maximum query length")
+)
+
+// PartitionValue is the value of a single partition key.
+type PartitionValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Partition identifies a partition of a table by the values of its
+// partition keys, in order.
+type Partition struct {
+	Values []PartitionValue `json:"values"`
+
+	// Location is the S3 URL of the partition's data; it is only required
+	// when adding partitions which don't follow the Hive key=value layout.
+	Location string `json:"location,omitempty"`
+}
+
+// String returns the partition in the key=value/key=value form used by
+// SHOW PARTITIONS and the Hive S3 layout.
+func (p Partition) String() string {
+	parts := make([]string, len(p.Values))
+	for i, pv := range p.Values {
+		parts[i] = pv.Key + "=" + pv.Value
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// Get returns the value of key, and whether the partition has the key.
+func (p Partition) Get(key string) (string, bool) {
+	for _, pv := range p.Values {
+		if pv.Key == key {
+			return pv.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Time returns the value of key parsed as a time with layout.
+func (p Partition) Time(key, layout string) (time.Time, error) {
+	v, ok := p.Get(key)
+	if !ok {
+		return time.Time{}, partitionKeyMissing
+	}
+
+	return time.Parse(layout, v)
+}
+
+// Int returns the value of key parsed as an integer.
+func (p Partition) Int(key string) (int64, error) {
+	v, ok := p.Get(key)
+	if !ok {
+		return 0, partitionKeyMissing
+	}
+
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// spec returns the PARTITION (...) clause identifying the partition.
+func (p Partition) spec() (string, error) {
+	if len(p.Values) == 0 {
+		return "", noPartitionValues
+	}
+
+	conds := make([]string, len(p.Values))
+	for i, pv := range p.Values {
+		k, err := quoteDDLIdentifier(pv.Key)
+		if err != nil {
+			return "", err
+		}
+
+		conds[i] = k + " = " + quoteDDLLiteral(pv.Value)
+	}
+
+	return "PARTITION (" + strings.Join(conds, ", ") + ")", nil
+}
+
+// ParsePartition parses a partition in the key=value/key=value form used
+// by SHOW PARTITIONS. Hive escapes special characters in values as %XX.
+func ParsePartition(s string) (Partition, error) {
+	if s == "" {
+		return Partition{}, invalidPartition
+	}
+
+	parts := strings.Split(s, "/")
+	p := Partition{Values: make([]PartitionValue, 0, len(parts))}
+
+	for _, part := range parts {
+		i := strings.Index(part, "=")
+		if i < 1 {
+			return Partition{}, invalidPartition
+		}
+
+		v, err := url.PathUnescape(part[i+1:])
+		if err != nil {
+			return Partition{}, invalidPartition
+		}
+
+		p.Values = append(p.Values, PartitionValue{Key: part[:i], Value: v})
+	}
+
+	return p, nil
+}
+
+// TimeKey is a partition key whose value is derived from a time.
+type TimeKey struct {
+	// Name is the name of the partition key, e.g. dt.
+	Name string
+
+	// Layout is the Go time layout of the key's values, e.g. 2006-01-02.
+	Layout string
+}
+
+// TimePartitioning describes a table partitioned by time, e.g. by date
+// or by date and hour.
+type TimePartitioning struct {
+	// Keys are the partition keys, in order.
+	Keys []TimeKey
+
+	// Interval is the period covered by each partition, e.g. 24 hours.
+	Interval time.Duration
+
+	// LocationTemplate is the S3 URL of each partition's data, in which
+	// ${key} is replaced with the value of each key, e.g.
+	// s3://bucket/events/${dt}/${hour}/
+	//
+	// If empty, partitions use the Hive key=value layout under the table's
+	// location.
+	LocationTemplate string
+}
+
+// Partitions returns the partitions covering the times from start
+// (inclusive) to end (exclusive). Times are converted to UTC and start
+// is truncated to the interval.
+func (tp TimePartitioning) Partitions(start, end time.Time) ([]Partition, error) {
+	if len(tp.Keys) == 0 {
+		return nil, noPartitionKeys
+	}
+
+	if tp.Interval <= 0 {
+		return nil, invalidInterval
+	}
+
+	if tp.LocationTemplate != "" {
+		if err := validS3URL(tp.LocationTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	var partitions []Partition
+
+	for t := start.UTC().Truncate(tp.Interval); t.Before(end); t = t.Add(tp.Interval) {
+		p := Partition{Values: make([]PartitionValue, len(tp.Keys))}
+		replacements := make([]string, 0, 2*len(tp.Keys))
+
+		for i, k := range tp.Keys {
+			v := t.Format(k.Layout)
+			p.Values[i] = PartitionValue{Key: k.Name, Value: v}
+			replacements = append(replacements, "${"+k.Name+"}", v)
+		}
+
+		if tp.LocationTemplate != "" {
+			p.Location = strings.NewReplacer(replacements...).Replace(tp.LocationTemplate)
+		}
+
+		partitions = append(partitions, p)
+	}
+
+	return partitions, nil
+}
+
+// AddPartitionStatements returns the ALTER TABLE ... ADD IF NOT EXISTS
+// statements needed to add partitions to table, with as many partitions
+// in each statement as will fit within MaxQueryLength.
+func AddPartitionStatements(table string, partitions []Partition) ([]string, error) {
+	return addPartitionStatements(table, partitions, MaxQueryLength)
+}
+
+func addPartitionStatements(table string, partitions []Partition, limit int) ([]string, error) {
+	t, err := quoteDDLTable(table)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := make([]string, len(partitions))
+	for i, p := range partitions {
+		spec, err := p.spec()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Location != "" {
+			if err := validS3URL(p.Location); err != nil {
+				return nil, err
+			}
+
+			spec += " LOCATION " + quoteDDLLiteral(p.Location)
+		}
+
+		clauses[i] = spec
+	}
+
+	return batch("ALTER TABLE "+t+" ADD IF NOT EXISTS", "\n  ", clauses, limit)
+}
+
+// DropPartitionStatements returns the ALTER TABLE ... DROP IF EXISTS
+// statements needed to drop partitions from table, with as many partitions
+// in each statement as will fit within MaxQueryLength.
+//
+// Dropping a partition of an external table leaves its data in S3.
+func DropPartitionStatements(table string, partitions []Partition) ([]string, error) {
+	return dropPartitionStatements(table, partitions, MaxQueryLength)
+}
+
+func dropPartitionStatements(table string, partitions []Partition, limit int) ([]string, error) {
+	t, err := quoteDDLTable(table)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := make([]string, len(partitions))
+	for i, p := range partitions {
+		if clauses[i], err = p.spec(); err != nil {
+			return nil, err
+		}
+	}
+
+	return batch("ALTER TABLE "+t+" DROP IF EXISTS", ",\n  ", clauses, limit)
+}
+
+// batch joins clauses onto prefix, starting a new statement whenever the
+// next clause would take the statement over limit bytes.
+func batch(prefix, sep string, clauses []string, limit int) ([]string, error) {
+	var statements []string
+	var b strings.Builder
+
+	lead := strings.TrimLeft(sep, ",")
+
+	for _, c := range clauses {
+		if b.Len() > 0 && b.Len()+len(sep)+len(c) > limit {
+			statements = append(statements, b.String())
+			b.Reset()
+		}
+
+		if b.Len() == 0 {
+			if len(prefix)+len(lead)+len(c) > limit {
+				return nil, partitionTooLong
+			}
+
+			b.WriteString(prefix)
+			b.WriteString(lead)
+		} else {
+			b.WriteString(sep)
+		}
+
+		b.WriteString(c)
+	}
+
+	if b.Len() > 0 {
+		statements = append(statements, b.String())
+	}
+
+	return statements, nil
+}
+
+// Repair returns a MSCK REPAIR TABLE statement, which adds the partitions
+// of table found in S3 under the Hive key=value layout.
+func Repair(table string) (string, error) {
+	t, err := quoteDDLTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	return "MSCK REPAIR TABLE " + t, nil
+}
+
+// ShowPartitions returns a SHOW PARTITIONS statement listing the
+// partitions of table.
+func ShowPartitions(table string) (string, error) {
+	t, err := quoteDDLTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	return "SHOW PARTITIONS " + t, nil
+}
+
+// AddPartitions adds partitions to table in database, running as many
+// statements as needed. See DoQuery for the meaning of output.
+func (c Client) AddPartitions(ctx context.Context, database, table string, partitions []Partition, output string) error {
+	statements, err := AddPartitionStatements(table, partitions)
+	if err != nil {
+		return err
+	}
+
+	return c.doDDLs(ctx, database, statements, output)
+}
+
+// DropPartitions drops partitions from table in database, running as many
+// statements as needed. See DoQuery for the meaning of output.
+func (c Client) DropPartitions(ctx context.Context, database, table string, partitions []Partition, output string) error {
+	statements, err := DropPartitionStatements(table, partitions)
+	if err != nil {
+		return err
+	}
+
+	return c.doDDLs(ctx, database, statements, output)
+}
+
+// doDDLs runs each of statements in turn, stopping at the first error.
+func (c Client) doDDLs(ctx context.Context, database string, statements []string, output string) error {
+	for _, s := range statements {
+		if err := c.DoDDL(ctx, database, s, output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RepairTable adds the partitions of table in database found in S3.
+// See Repair, and DoQuery for the meaning of output.
+func (c Client) RepairTable(ctx context.Context, database, table, output string) error {
+	statement, err := Repair(table)
+	if err != nil {
+		return err
+	}
+
+	return c.DoDDL(ctx, database, statement, output)
+}
+
+// ListPartitions returns the partitions of table in database.
+// See DoQuery for the meaning of output.
+func (c Client) ListPartitions(ctx context.Context, database, table, output string) ([]Partition, error) {
+	statement, err := ShowPartitions(table)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.Run(ctx, database, statement, output)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]Partition, 0, len(r.Rows))
+
+	for _, row := range r.Rows {
+		if len(row) == 0 || !strings.Contains(row[0], "=") {
+			continue
+		}
+
+		p, err := ParsePartition(row[0])
+		if err != nil {
+			return nil, err
+		}
+
+		partitions = append(partitions, p)
+	}
+
+	return partitions, nil
+}
+
+// DropPartitionsBefore drops the partitions of table in database whose key
+// value, parsed as a time, is before cutoff; e.g. to enforce a retention
+// window, pass time.Now().Add(-retention). The dropped partitions are
+// returned. See DoQuery for the meaning of output.
+func (c Client) DropPartitionsBefore(ctx context.Context, database, table string, key TimeKey, cutoff time.Time, output string) ([]Partition, error) {
+	partitions, err := c.ListPartitions(ctx, database, table, output)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []Partition
+
+	for _, p := range partitions {
+		t, err := p.Time(key.Name, key.Layout)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Before(cutoff) {
+			expired = append(expired, p)
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	if err := c.DropPartitions(ctx, database, table, expired, output); err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,281 @@
+package athena_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func partition(kv ...string) athena.Partition {
+	p := athena.Partition{}
+	for i := 0; i < len(kv); i += 2 {
+		p.Values = append(p.Values, athena.PartitionValue{Key: kv[i], Value: kv[i+1]})
+	}
+
+	return p
+}
+
+func TestParsePartition(t *testing.T) {
+	cases := []struct {
+		id       string
+		s        string
+		expected athena.Partition
+		err      error
+	}{
+		{
+			id:       "single key",
+			s:        "dt=2019-10-01",
+			expected: partition("dt", "2019-10-01"),
+		},
+		{
+			id:       "multiple keys",
+			s:        "dt=2019-10-01/hour=05",
+			expected: partition("dt", "2019-10-01", "hour", "05"),
+		},
+		{
+			id:       "escaped value",
+			s:        "path=a%2Fb",
+			expected: partition("path", "a/b"),
+		},
+		{
+			id:  "invalid input: empty",
+			s:   "",
+			err: athena.ErrInvalidPartition,
+		},
+		{
+			id:  "invalid input: missing value",
+			s:   "dt=2019-10-01/hour",
+			err: athena.ErrInvalidPartition,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			actual, err := athena.ParsePartition(tc.s)
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				tt.Errorf("ParsePartition() == %v (want %v)", actual, tc.expected)
+			}
+
+			if err != tc.err {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+
+			if err == nil && actual.String() != tc.s && tc.id != "escaped value" {
+				tt.Errorf("String() == %v (want %v)", actual.String(), tc.s)
+			}
+		})
+	}
+}
+
+func TestPartitionValues(t *testing.T) {
+	p := partition("dt", "2019-10-01", "hour", "05")
+
+	tm, err := p.Time("dt", "2006-01-02")
+	if err != nil || !tm.Equal(time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time() == %v, %v (want 2019-10-01, nil)", tm, err)
+	}
+
+	n, err := p.Int("hour")
+	if err != nil || n != 5 {
+		t.Errorf("Int() == %v, %v (want 5, nil)", n, err)
+	}
+
+	_, err = p.Int("minute")
+	if err != athena.ErrPartitionKeyMissing {
+		t.Errorf("err == %v (want %v)", err, athena.ErrPartitionKeyMissing)
+	}
+}
+
+func TestTimePartitioning(t *testing.T) {
+	start := time.Date(2019, 10, 1, 22, 30, 0, 0, time.UTC)
+	end := time.Date(2019, 10, 2, 1, 0, 0, 0, time.UTC)
+
+	t.Run("hourly", func(tt *testing.T) {
+		tp := athena.TimePartitioning{
+			Keys:             []athena.TimeKey{{"dt", "2006-01-02"}, {"hour", "15"}},
+			Interval:         time.Hour,
+			LocationTemplate: "s3://bucket/events/${dt}/${hour}/",
+		}
+
+		expected := []athena.Partition{
+			partition("dt", "2019-10-01", "hour", "22"),
+			partition("dt", "2019-10-01", "hour", "23"),
+			partition("dt", "2019-10-02", "hour", "00"),
+		}
+		expected[0].Location = "s3://bucket/events/2019-10-01/22/"
+		expected[1].Location = "s3://bucket/events/2019-10-01/23/"
+		expected[2].Location = "s3://bucket/events/2019-10-02/00/"
+
+		actual, err := tp.Partitions(start, end)
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("Partitions() == %v (want %v)", actual, expected)
+		}
+	})
+
+	t.Run("daily", func(tt *testing.T) {
+		tp := athena.TimePartitioning{
+			Keys:     []athena.TimeKey{{"dt", "2006-01-02"}},
+			Interval: 24 * time.Hour,
+		}
+
+		expected := []athena.Partition{
+			partition("dt", "2019-10-01"),
+			partition("dt", "2019-10-02"),
+		}
+
+		actual, err := tp.Partitions(start, end)
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("Partitions() == %v (want %v)", actual, expected)
+		}
+	})
+
+	t.Run("invalid input", func(tt *testing.T) {
+		cases := []struct {
+			tp  athena.TimePartitioning
+			err error
+		}{
+			{athena.TimePartitioning{Interval: time.Hour}, athena.ErrNoPartitionKeys},
+			{athena.TimePartitioning{Keys: []athena.TimeKey{{"dt", "2006"}}}, athena.ErrInvalidInterval},
+			{athena.TimePartitioning{Keys: []athena.TimeKey{{"dt", "2006"}}, Interval: time.Hour, LocationTemplate: "/tmp"}, athena.ErrS3BadPrefix},
+		}
+
+		for _, tc := range cases {
+			_, err := tc.tp.Partitions(start, end)
+
+			if err != tc.err {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+		}
+	})
+}
+
+func TestAddPartitionStatements(t *testing.T) {
+	p1 := partition("dt", "2019-10-01")
+	p2 := partition("dt", "2019-10-02")
+	p2.Location = "s3://bucket/2019-10-02/"
+
+	t.Run("single statement", func(tt *testing.T) {
+		expected := []string{
+			"ALTER TABLE `events` ADD IF NOT EXISTS\n" +
+				"  PARTITION (`dt` = '2019-10-01')\n" +
+				"  PARTITION (`dt` = '2019-10-02') LOCATION 's3://bucket/2019-10-02/'",
+		}
+
+		actual, err := athena.AddPartitionStatements("events", []athena.Partition{p1, p2})
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("AddPartitionStatements() == %q (want %q)", actual, expected)
+		}
+	})
+
+	t.Run("split statements", func(tt *testing.T) {
+		expected := []string{
+			"ALTER TABLE `events` ADD IF NOT EXISTS\n  PARTITION (`dt` = '2019-10-01')",
+			"ALTER TABLE `events` ADD IF NOT EXISTS\n  PARTITION (`dt` = '2019-10-01')",
+		}
+
+		actual, err := athena.AddPartitionStatementsLimit("events", []athena.Partition{p1, p1}, len(expected[0]))
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("AddPartitionStatements() == %q (want %q)", actual, expected)
+		}
+	})
+
+	t.Run("partition too long", func(tt *testing.T) {
+		_, err := athena.AddPartitionStatementsLimit("events", []athena.Partition{p1}, 10)
+
+		if err != athena.ErrPartitionTooLong {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrPartitionTooLong)
+		}
+	})
+
+	t.Run("empty partition", func(tt *testing.T) {
+		_, err := athena.AddPartitionStatements("events", []athena.Partition{{}})
+
+		if err != athena.ErrNoPartitionValues {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrNoPartitionValues)
+		}
+	})
+}
+
+func TestDropPartitionStatements(t *testing.T) {
+	ps := []athena.Partition{partition("dt", "2019-10-01"), partition("dt", "2019-10-02"), partition("dt", "2019-10-03")}
+
+	expected := []string{
+		"ALTER TABLE `events` DROP IF EXISTS\n" +
+			"  PARTITION (`dt` = '2019-10-01'),\n" +
+			"  PARTITION (`dt` = '2019-10-02')",
+		"ALTER TABLE `events` DROP IF EXISTS\n" +
+			"  PARTITION (`dt` = '2019-10-03')",
+	}
+
+	actual, err := athena.DropPartitionStatementsLimit("events", ps, len(expected[0]))
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("DropPartitionStatements() == %q (want %q)", actual, expected)
+	}
+}
+
+func TestDropPartitionsBefore(t *testing.T) {
+	var queries []string
+
+	c := athena.NewCustomClient(mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid", queries: &queries},
+		getQueryExecution:   getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output/jobid.txt"},
+		getQueryResults: mockResults([]string{"partition"},
+			[]string{"dt=2019-09-30"},
+			[]string{"dt=2019-10-01"},
+			[]string{"dt=2019-10-02"},
+		),
+	})
+
+	cutoff := time.Date(2019, 10, 2, 0, 0, 0, 0, time.UTC)
+	key := athena.TimeKey{Name: "dt", Layout: "2006-01-02"}
+
+	dropped, err := c.DropPartitionsBefore(context.Background(), "database", "events", key, cutoff, "s3://output")
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := []athena.Partition{partition("dt", "2019-09-30"), partition("dt", "2019-10-01")}
+	if !reflect.DeepEqual(dropped, expected) {
+		t.Errorf("DropPartitionsBefore() == %v (want %v)", dropped, expected)
+	}
+
+	expectedQueries := []string{
+		"SHOW PARTITIONS `events`",
+		"ALTER TABLE `events` DROP IF EXISTS\n" +
+			"  PARTITION (`dt` = '2019-09-30'),\n" +
+			"  PARTITION (`dt` = '2019-10-01')",
+	}
+	if !reflect.DeepEqual(queries, expectedQueries) {
+		t.Errorf("queries == %q (want %q)", queries, expectedQueries)
+	}
+}
@@ -0,0 +1,266 @@
+package athena
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+const unexpectedResult = constError("query result has an unexpected shape")
+
+// TableDescription is the schema of a table as reported by DESCRIBE.
+type TableDescription struct {
+	// Table is the name of the described table.
+	Table string `json:"table"`
+
+	// Columns are the table's data columns; partition keys are excluded.
+	Columns []ColumnDescription `json:"columns"`
+
+	// PartitionKeys are the columns the table is partitioned by, in order.
+	PartitionKeys []ColumnDescription `json:"partition_keys"`
+}
+
+// ColumnDescription describes a single column of a table.
+type ColumnDescription struct {
+	// The name of the column.
+	Name string `json:"name"`
+
+	// The Hive data type of the column, e.g. bigint or array<string>.
+	Type string `json:"type"`
+
+	// The column comment, if any.
+	Comment string `json:"comment"`
+}
+
+// ValueCount is a distinct value of a column and how often it occurs.
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// NullCount is the number of null values in each column of a table.
+type NullCount struct {
+	// Rows is the total number of rows in the table.
+	Rows int64 `json:"rows"`
+
+	// Columns maps column name to the number of rows where it is null.
+	Columns map[string]int64 `json:"columns"`
+}
+
+// DescribeTable describes the schema of table in database.
+// See DoQuery for the meaning of output.
+//
+// As with the other table preview helpers, the query is stopped if ctx is
+// done before it completes.
+func (c Client) DescribeTable(ctx context.Context, database, table, output string) (TableDescription, error) {
+	query, err := Describe(table)
+	if err != nil {
+		return TableDescription{}, err
+	}
+
+	r, err := c.preview(ctx, database, query, output)
+	if err != nil {
+		return TableDescription{}, err
+	}
+
+	return parseDescription(table, r.Rows), nil
+}
+
+// parseDescription parses DESCRIBE output, where each row is a single
+// tab separated line of name, type and comment. The partition keys follow a
+// "# Partition Information" heading, having already been listed as columns.
+func parseDescription(table string, rows []Row) TableDescription {
+	td := TableDescription{Table: table}
+	partitions := false
+
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+
+		line := strings.TrimSpace(row[0])
+		if strings.HasPrefix(line, "#") {
+			if strings.EqualFold(strings.TrimSpace(line[1:]), "Partition Information") {
+				partitions = true
+			}
+
+			continue
+		}
+
+		fields := strings.Split(row[0], "\t")
+		if len(fields) < 2 || strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+
+		cd := ColumnDescription{
+			Name: strings.TrimSpace(fields[0]),
+			Type: strings.TrimSpace(fields[1]),
+		}
+
+		if len(fields) > 2 {
+			cd.Comment = strings.TrimSpace(fields[2])
+		}
+
+		if partitions {
+			td.PartitionKeys = append(td.PartitionKeys, cd)
+		} else {
+			td.Columns = append(td.Columns, cd)
+		}
+	}
+
+	// partition keys are listed amongst the columns as well as under their heading
+	if len(td.PartitionKeys) > 0 {
+		keys := make(map[string]bool, len(td.PartitionKeys))
+		for _, pk := range td.PartitionKeys {
+			keys[pk.Name] = true
+		}
+
+		columns := td.Columns[:0]
+		for _, cd := range td.Columns {
+			if !keys[cd.Name] {
+				columns = append(columns, cd)
+			}
+		}
+
+		td.Columns = columns
+	}
+
+	return td
+}
+
+// CountRows counts the rows of table in database where each column in where
+// equals the corresponding value, e.g. to count the rows in a partition.
+// See Count for how values are compared, and DoQuery for the meaning of
+// output.
+func (c Client) CountRows(ctx context.Context, database, table string, where map[string]interface{}, output string) (int64, error) {
+	query, err := Count(table, where)
+	if err != nil {
+		return 0, err
+	}
+
+	row, err := c.singleRow(ctx, database, query, output)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(row[0], 10, 64)
+}
+
+// SampleRows returns at most limit rows from a random sample of roughly
+// percent of the rows of table in database, without the header row.
+// See DoQuery for the meaning of output.
+func (c Client) SampleRows(ctx context.Context, database, table string, percent float64, limit int, output string) (Result, error) {
+	query, err := Sample(table, percent, limit)
+	if err != nil {
+		return Result{}, err
+	}
+
+	r, err := c.preview(ctx, database, query, output)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return r.WithoutHeader(), nil
+}
+
+// DistinctValues returns the k most frequent distinct values of column in
+// table in database, most frequent first. Null values are reported as empty
+// strings. See DoQuery for the meaning of output.
+func (c Client) DistinctValues(ctx context.Context, database, table, column string, k int, output string) ([]ValueCount, error) {
+	query, err := Distinct(table, column, k)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.preview(ctx, database, query, output)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := r.WithoutHeader().Rows
+	values := make([]ValueCount, 0, len(rows))
+
+	for _, row := range rows {
+		if len(row) != 2 {
+			return nil, unexpectedResult
+		}
+
+		n, err := strconv.ParseInt(row[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, ValueCount{Value: row[0], Count: n})
+	}
+
+	return values, nil
+}
+
+// CountNulls counts the null values in each of columns of table in database.
+// If no columns are given, every column of the table (including partition
+// keys) is counted. See DoQuery for the meaning of output.
+func (c Client) CountNulls(ctx context.Context, database, table string, columns []string, output string) (NullCount, error) {
+	if len(columns) == 0 {
+		td, err := c.DescribeTable(ctx, database, table, output)
+		if err != nil {
+			return NullCount{}, err
+		}
+
+		for _, cd := range append(td.Columns, td.PartitionKeys...) {
+			columns = append(columns, cd.Name)
+		}
+	}
+
+	query, err := NullCounts(table, columns)
+	if err != nil {
+		return NullCount{}, err
+	}
+
+	row, err := c.singleRow(ctx, database, query, output)
+	if err != nil {
+		return NullCount{}, err
+	}
+
+	if len(row) != len(columns)+1 {
+		return NullCount{}, unexpectedResult
+	}
+
+	nc := NullCount{Columns: make(map[string]int64, len(columns))}
+
+	if nc.Rows, err = strconv.ParseInt(row[0], 10, 64); err != nil {
+		return NullCount{}, err
+	}
+
+	for i, column := range columns {
+		n, err := strconv.ParseInt(row[i+1], 10, 64)
+		if err != nil {
+			return NullCount{}, err
+		}
+
+		nc.Columns[column] = n
+	}
+
+	return nc, nil
+}
+
+// preview runs the query of a table preview helper. Callers have no Query
+// to stop, so it is stopped if ctx is done before it completes.
+func (c Client) preview(ctx context.Context, database, query, output string) (Result, error) {
+	return c.run(ctx, database, query, output, true)
+}
+
+// singleRow runs a query expected to return exactly one non-empty row
+// (after the header row), and returns it.
+func (c Client) singleRow(ctx context.Context, database, query, output string) (Row, error) {
+	r, err := c.preview(ctx, database, query, output)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := r.WithoutHeader().Rows
+	if len(rows) != 1 || len(rows[0]) == 0 {
+		return nil, unexpectedResult
+	}
+
+	return rows[0], nil
+}
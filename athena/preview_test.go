@@ -0,0 +1,323 @@
+package athena_test
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestPreviewQueries(t *testing.T) {
+	cases := []struct {
+		id       string
+		query    func() (string, error)
+		expected string
+		err      error
+	}{
+		{
+			id:       "describe",
+			query:    func() (string, error) { return athena.Describe("db.table") },
+			expected: "DESCRIBE `db`.`table`",
+		},
+		{
+			id:    "describe: backtick",
+			query: func() (string, error) { return athena.Describe("ta`ble") },
+			err:   athena.ErrBacktickInDDLName,
+		},
+		{
+			id:       "count",
+			query:    func() (string, error) { return athena.Count("table", nil) },
+			expected: `SELECT COUNT(*) AS "$count" FROM "table"`,
+		},
+		{
+			id: "count: partition",
+			query: func() (string, error) {
+				return athena.Count("db.table", map[string]interface{}{"year": "2019", "month": "1'0"})
+			},
+			expected: `SELECT COUNT(*) AS "$count" FROM "db"."table" WHERE "month" = '1''0' AND "year" = '2019'`,
+		},
+		{
+			id: "count: typed values",
+			query: func() (string, error) {
+				return athena.Count("table", map[string]interface{}{
+					"year":   2019,
+					"ratio":  0.5,
+					"active": true,
+					"dt":     time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC),
+					"ts":     time.Date(2019, 10, 1, 12, 30, 0, 0, time.UTC),
+				})
+			},
+			expected: `SELECT COUNT(*) AS "$count" FROM "table" WHERE "active" = TRUE AND "dt" = DATE '2019-10-01' AND "ratio" = 0.5 AND "ts" = TIMESTAMP '2019-10-01 12:30:00.000' AND "year" = 2019`,
+		},
+		{
+			id:    "count: unsupported value",
+			query: func() (string, error) { return athena.Count("table", map[string]interface{}{"year": []int{2019}}) },
+			err:   athena.ErrUnsupportedValue,
+		},
+		{
+			id:       "count: float32",
+			query:    func() (string, error) { return athena.Count("table", map[string]interface{}{"x": float32(0.1)}) },
+			expected: `SELECT COUNT(*) AS "$count" FROM "table" WHERE "x" = 0.1`,
+		},
+		{
+			id:    "count: NaN",
+			query: func() (string, error) { return athena.Count("table", map[string]interface{}{"x": math.NaN()}) },
+			err:   athena.ErrUnsupportedValue,
+		},
+		{
+			id:    "count: infinity",
+			query: func() (string, error) { return athena.Count("table", map[string]interface{}{"x": math.Inf(-1)}) },
+			err:   athena.ErrUnsupportedValue,
+		},
+		{
+			id:    "count: empty table",
+			query: func() (string, error) { return athena.Count("", nil) },
+			err:   athena.ErrEmptyTable,
+		},
+		{
+			id:    "count: empty database",
+			query: func() (string, error) { return athena.Count(".table", nil) },
+			err:   athena.ErrEmptyIdentifier,
+		},
+		{
+			id:    "count: empty column",
+			query: func() (string, error) { return athena.Count("table", map[string]interface{}{"": "x"}) },
+			err:   athena.ErrEmptyColumn,
+		},
+		{
+			id:       "sample",
+			query:    func() (string, error) { return athena.Sample("table", 2.5, 10) },
+			expected: `SELECT * FROM "table" TABLESAMPLE BERNOULLI (2.5) LIMIT 10`,
+		},
+		{
+			id:    "sample: zero percent",
+			query: func() (string, error) { return athena.Sample("table", 0, 10) },
+			err:   athena.ErrInvalidPercent,
+		},
+		{
+			id:    "sample: over 100 percent",
+			query: func() (string, error) { return athena.Sample("table", 100.1, 10) },
+			err:   athena.ErrInvalidPercent,
+		},
+		{
+			id:    "sample: negative limit",
+			query: func() (string, error) { return athena.Sample("table", 1, -1) },
+			err:   athena.ErrInvalidLimit,
+		},
+		{
+			id:       "distinct",
+			query:    func() (string, error) { return athena.Distinct("table", "col", 5) },
+			expected: `SELECT "col", COUNT(*) AS "$count" FROM "table" GROUP BY "col" ORDER BY 2 DESC, 1 LIMIT 5`,
+		},
+		{
+			id:       "distinct: column named count",
+			query:    func() (string, error) { return athena.Distinct("table", "count", 5) },
+			expected: `SELECT "count", COUNT(*) AS "$count" FROM "table" GROUP BY "count" ORDER BY 2 DESC, 1 LIMIT 5`,
+		},
+		{
+			id:    "distinct: empty column",
+			query: func() (string, error) { return athena.Distinct("table", "", 5) },
+			err:   athena.ErrEmptyColumn,
+		},
+		{
+			id:       "null counts",
+			query:    func() (string, error) { return athena.NullCounts("table", []string{"a", "b"}) },
+			expected: `SELECT COUNT(*) AS "$count", COUNT(*) - COUNT("a") AS "a", COUNT(*) - COUNT("b") AS "b" FROM "table"`,
+		},
+		{
+			id:    "null counts: empty column",
+			query: func() (string, error) { return athena.NullCounts("table", []string{""}) },
+			err:   athena.ErrEmptyColumn,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			q, err := tc.query()
+
+			if q != tc.expected {
+				tt.Errorf("Query == %v (want %v)", q, tc.expected)
+			}
+
+			if err != tc.err {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+		})
+	}
+}
+
+// mockResults returns query results with the given column names and rows.
+func mockResults(columns []string, data ...[]string) getQueryResults {
+	ci := make([]*aa.ColumnInfo, len(columns))
+	for i := range columns {
+		ci[i] = &aa.ColumnInfo{Name: aws.String(columns[i])}
+	}
+
+	rows := make([]*aa.Row, len(data))
+	for i, d := range data {
+		r := aa.Row{Data: make([]*aa.Datum, len(d))}
+		for j := range d {
+			r.Data[j] = &aa.Datum{VarCharValue: aws.String(d[j])}
+		}
+
+		rows[i] = &r
+	}
+
+	return getQueryResults{columns: ci, rows: rows}
+}
+
+// previewClient returns a client whose queries succeed with the given
+// column names and rows.
+func previewClient(columns []string, data ...[]string) athena.Client {
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid"},
+		getQueryExecution:   getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output/jobid.csv"},
+		getQueryResults:     mockResults(columns, data...),
+	}
+
+	return athena.NewCustomClient(mc)
+}
+
+func TestDescribeTable(t *testing.T) {
+	c := previewClient([]string{"col_name"},
+		[]string{"id                  \tbigint              \tthe id              "},
+		[]string{"name                \tstring              \t                    "},
+		[]string{"dt                  \tstring              \t                    "},
+		[]string{"                    \t                    \t                    "},
+		[]string{"# Partition Information\t \t "},
+		[]string{"# col_name            \tdata_type           \tcomment             "},
+		[]string{"                    \t                    \t                    "},
+		[]string{"dt                  \tstring              \t                    "},
+	)
+
+	expected := athena.TableDescription{
+		Table: "table",
+		Columns: []athena.ColumnDescription{
+			{Name: "id", Type: "bigint", Comment: "the id"},
+			{Name: "name", Type: "string"},
+		},
+		PartitionKeys: []athena.ColumnDescription{
+			{Name: "dt", Type: "string"},
+		},
+	}
+
+	actual, err := c.DescribeTable(context.Background(), "database", "table", "s3://output")
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("DescribeTable() == %v (want %v)", actual, expected)
+	}
+}
+
+func TestDescribeTableCancelled(t *testing.T) {
+	var stopped []string
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid"},
+		getQueryExecution:   getQueryExecution{state: "RUNNING"},
+		listing:             listing{stopped: &stopped},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := athena.NewCustomClient(mc).DescribeTable(ctx, "database", "table", "s3://output")
+	if err != context.Canceled {
+		t.Errorf("err == %v (want %v)", err, context.Canceled)
+	}
+
+	if !reflect.DeepEqual(stopped, []string{"jobid"}) {
+		t.Errorf("stopped == %v (want [jobid])", stopped)
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	t.Run("happy path", func(tt *testing.T) {
+		c := previewClient([]string{"count"}, []string{"42"})
+
+		actual, err := c.CountRows(context.Background(), "database", "table", nil, "s3://output")
+
+		if actual != 42 {
+			tt.Errorf("CountRows() == %d (want 42)", actual)
+		}
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+	})
+
+	t.Run("no rows", func(tt *testing.T) {
+		c := previewClient([]string{"count"})
+
+		_, err := c.CountRows(context.Background(), "database", "table", nil, "s3://output")
+
+		if err != athena.ErrUnexpectedResult {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrUnexpectedResult)
+		}
+	})
+}
+
+func TestSampleRows(t *testing.T) {
+	c := previewClient([]string{"a", "b"}, []string{"1", "2"}, []string{"3", "4"})
+
+	actual, err := c.SampleRows(context.Background(), "database", "table", 10, 2, "s3://output")
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := []athena.Row{{"1", "2"}, {"3", "4"}}
+	if !reflect.DeepEqual(actual.Rows, expected) {
+		t.Errorf("SampleRows().Rows == %v (want %v)", actual.Rows, expected)
+	}
+}
+
+func TestDistinctValues(t *testing.T) {
+	c := previewClient([]string{"col", "count"}, []string{"x", "10"}, []string{"y", "3"})
+
+	actual, err := c.DistinctValues(context.Background(), "database", "table", "col", 2, "s3://output")
+
+	if err != nil {
+		t.Errorf("err == %v (want nil)", err)
+	}
+
+	expected := []athena.ValueCount{{Value: "x", Count: 10}, {Value: "y", Count: 3}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("DistinctValues() == %v (want %v)", actual, expected)
+	}
+}
+
+func TestCountNulls(t *testing.T) {
+	t.Run("happy path", func(tt *testing.T) {
+		c := previewClient([]string{"count", "a", "b"}, []string{"10", "0", "4"})
+
+		actual, err := c.CountNulls(context.Background(), "database", "table", []string{"a", "b"}, "s3://output")
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		expected := athena.NullCount{Rows: 10, Columns: map[string]int64{"a": 0, "b": 4}}
+		if !reflect.DeepEqual(actual, expected) {
+			tt.Errorf("CountNulls() == %v (want %v)", actual, expected)
+		}
+	})
+
+	t.Run("column mismatch", func(tt *testing.T) {
+		c := previewClient([]string{"count", "a"}, []string{"10", "0"})
+
+		_, err := c.CountNulls(context.Background(), "database", "table", []string{"a", "b"}, "s3://output")
+
+		if err != athena.ErrUnexpectedResult {
+			tt.Errorf("err == %v (want %v)", err, athena.ErrUnexpectedResult)
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package athena
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric names of PrometheusMetrics, with their labels.
+const (
+	// MetricQueriesSubmitted counts queries started, by workgroup and
+	// database, and MetricQuerySubmitErrors those failing to start.
+	MetricQueriesSubmitted  = "athena_queries_submitted_total"
+	MetricQuerySubmitErrors = "athena_query_submit_errors_total"
+
+	// MetricQueriesCompleted counts queries finished, by workgroup,
+	// database and final state.
+	MetricQueriesCompleted = "athena_queries_completed_total"
+
+	// MetricQueueSeconds and MetricExecutionSeconds are histograms of the
+	// queue and execution times of queries, by workgroup.
+	MetricQueueSeconds     = "athena_query_queue_seconds"
+	MetricExecutionSeconds = "athena_query_execution_seconds"
+
+	// MetricDataScanned counts the bytes scanned by queries, by workgroup
+	// and database.
+	MetricDataScanned = "athena_data_scanned_bytes_total"
+
+	// MetricAPICalls counts calls to the Athena API, and MetricAPIErrors,
+	// MetricAPIRetries and MetricAPIThrottles those failing, retried and
+	// throttled, by operation.
+	MetricAPICalls     = "athena_api_calls_total"
+	MetricAPIErrors    = "athena_api_errors_total"
+	MetricAPIRetries   = "athena_api_retries_total"
+	MetricAPIThrottles = "athena_api_throttles_total"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the
+// latency histograms of PrometheusMetrics.
+var DefaultBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800}
+
+// PrometheusMetrics is Metrics kept in memory and exposed in Prometheus'
+// text format, e.g. by serving it over HTTP:
+//
+//	metrics := athena.NewPrometheusMetrics()
+//	client = client.WithMetrics(metrics)
+//	http.Handle("/metrics", metrics)
+//
+// Each is independent of any other, with no global registry, and its
+// values may be read with Counter and Histogram. It is safe for
+// concurrent use.
+type PrometheusMetrics struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// family is a metric and its series, one per combination of label values.
+type family struct {
+	name, help, kind string
+	labels           []string
+
+	// buckets are the upper bounds of the buckets of a histogram.
+	buckets []float64
+
+	series map[string]*series
+}
+
+// series is the value of a counter, or the observations of a histogram,
+// with the given label values.
+type series struct {
+	values []string
+
+	value float64
+
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewPrometheusMetrics returns metrics with all values zero.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{}
+
+	m.register(MetricQueriesSubmitted, "Queries started.", "counter", nil, "workgroup", "database")
+	m.register(MetricQuerySubmitErrors, "Queries which failed to start.", "counter", nil, "workgroup", "database")
+	m.register(MetricQueriesCompleted, "Queries finished, by final state.", "counter", nil, "workgroup", "database", "state")
+	m.register(MetricQueueSeconds, "Time queries waited to run.", "histogram", DefaultBuckets, "workgroup")
+	m.register(MetricExecutionSeconds, "Time queries took to execute.", "histogram", DefaultBuckets, "workgroup")
+	m.register(MetricDataScanned, "Bytes scanned by queries.", "counter", nil, "workgroup", "database")
+	m.register(MetricAPICalls, "Calls to the Athena API.", "counter", nil, "operation")
+	m.register(MetricAPIErrors, "Calls to the Athena API which failed.", "counter", nil, "operation")
+	m.register(MetricAPIRetries, "Calls to the Athena API which were retries.", "counter", nil, "operation")
+	m.register(MetricAPIThrottles, "Calls to the Athena API which were throttled.", "counter", nil, "operation")
+
+	return m
+}
+
+// register adds a metric family.
+func (m *PrometheusMetrics) register(name, help, kind string, buckets []float64, labels ...string) {
+	m.families = append(m.families, &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*series{},
+	})
+}
+
+// family returns the family named name, or nil.
+func (m *PrometheusMetrics) family(name string) *family {
+	for _, f := range m.families {
+		if f.name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// get returns the series with the label values, creating it if needed;
+// the metrics must be locked.
+func (f *family) get(values ...string) *series {
+	key := strings.Join(values, "\xff")
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: values}
+		if f.buckets != nil {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// count adds v to the counter named name with the label values.
+func (m *PrometheusMetrics) count(name string, v float64, values ...string) {
+	m.family(name).get(values...).value += v
+}
+
+// observe records v in the histogram named name with the label values.
+func (m *PrometheusMetrics) observe(name string, v float64, values ...string) {
+	f := m.family(name)
+	s := f.get(values...)
+
+	for i, le := range f.buckets {
+		if v <= le {
+			s.counts[i]++
+		}
+	}
+
+	s.count++
+	s.sum += v
+}
+
+// QuerySubmitted satisfies Metrics.
+func (m *PrometheusMetrics) QuerySubmitted(q SubmittedQuery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if q.Err != nil {
+		m.count(MetricQuerySubmitErrors, 1, q.WorkGroup, q.Database)
+		return
+	}
+
+	m.count(MetricQueriesSubmitted, 1, q.WorkGroup, q.Database)
+}
+
+// QueryCompleted satisfies Metrics.
+func (m *PrometheusMetrics) QueryCompleted(q CompletedQuery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count(MetricQueriesCompleted, 1, q.WorkGroup, q.Database, q.State)
+	m.count(MetricDataScanned, float64(q.DataScannedInBytes), q.WorkGroup, q.Database)
+	m.observe(MetricQueueSeconds, q.QueueTime.Seconds(), q.WorkGroup)
+	m.observe(MetricExecutionSeconds, q.ExecutionTime.Seconds(), q.WorkGroup)
+}
+
+// APICalled satisfies Metrics.
+func (m *PrometheusMetrics) APICalled(c APICall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count(MetricAPICalls, 1, c.Operation)
+
+	if c.Err != nil {
+		m.count(MetricAPIErrors, 1, c.Operation)
+	}
+
+	if c.Retry {
+		m.count(MetricAPIRetries, 1, c.Operation)
+	}
+
+	if c.Throttled {
+		m.count(MetricAPIThrottles, 1, c.Operation)
+	}
+}
+
+// Counter returns the value of the counter named name with the label
+// values, given in the order of the metric's labels; see the Metric
+// constants.
+func (m *PrometheusMetrics) Counter(name string, values ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := m.family(name)
+	if f == nil || f.kind != "counter" {
+		return 0
+	}
+
+	if s, ok := f.series[strings.Join(values, "\xff")]; ok {
+		return s.value
+	}
+
+	return 0
+}
+
+// Histogram returns the number and sum of the observations of the
+// histogram named name with the label values.
+func (m *PrometheusMetrics) Histogram(name string, values ...string) (uint64, float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := m.family(name)
+	if f == nil || f.kind != "histogram" {
+		return 0, 0
+	}
+
+	if s, ok := f.series[strings.Join(values, "\xff")]; ok {
+		return s.count, s.sum
+	}
+
+	return 0, 0
+}
+
+// WriteTo writes the metrics in Prometheus' text format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	b := bufio.NewWriter(cw)
+
+	for _, f := range m.families {
+		fmt.Fprintf(b, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			s := f.series[k]
+
+			if f.kind == "counter" {
+				fmt.Fprintf(b, "%s%s %s\n", f.name, labels(f.labels, s.values), formatValue(s.value))
+				continue
+			}
+
+			names := append(append([]string{}, f.labels...), "le")
+			for i, le := range f.buckets {
+				values := append(append([]string{}, s.values...), formatValue(le))
+				fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, labels(names, values), s.counts[i])
+			}
+
+			values := append(append([]string{}, s.values...), "+Inf")
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, labels(names, values), s.count)
+			fmt.Fprintf(b, "%s_sum%s %s\n", f.name, labels(f.labels, s.values), formatValue(s.sum))
+			fmt.Fprintf(b, "%s_count%s %d\n", f.name, labels(f.labels, s.values), s.count)
+		}
+	}
+
+	err := b.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves the metrics in Prometheus' text format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// labels formats label names and values as {name="value",...}.
+func labels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatValue formats a sample value.
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,398 @@
+package athena
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// Errors for invalid scripts
+const (
+	duplicateStatement = constError("statement name is not unique")
+	unknownDependency  = constError("statement depends on an unknown statement")
+	dependencyCycle    = constError("statement dependencies form a cycle")
+)
+
+// SplitStatements splits a SQL script into its individual statements,
+// which are separated by semicolons.
+//
+// Semicolons within quoted strings and identifiers, and within -- and /* */
+// comments, do not separate statements. Comments preceding a statement are
+// kept with it; statements consisting only of comments are dropped.
+func SplitStatements(sql string) []string {
+	var statements []string
+
+	start := 0
+	code := false
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// a doubled quote is an escaped quote, not the end of the string
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c {
+						j++
+						continue
+					}
+
+					break
+				}
+			}
+
+			i = j
+			code = true
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if j := strings.IndexByte(sql[i:], '\n'); j < 0 {
+				i = len(sql)
+			} else {
+				i += j
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if j := strings.Index(sql[i+2:], "*/"); j < 0 {
+				i = len(sql)
+			} else {
+				i += j + 3
+			}
+		case c == ';':
+			if code {
+				statements = append(statements, strings.TrimSpace(sql[start:i]))
+			}
+
+			start = i + 1
+			code = false
+		case !unicode.IsSpace(rune(c)):
+			code = true
+		}
+	}
+
+	if code {
+		statements = append(statements, strings.TrimSpace(sql[start:]))
+	}
+
+	return statements
+}
+
+// Statement is a single statement of a script.
+type Statement struct {
+	// Name identifies the statement in results and dependencies.
+	Name string `json:"name"`
+
+	// SQL is the text of the statement.
+	SQL string `json:"sql"`
+
+	// DependsOn names the statements which must succeed before this
+	// statement is run.
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// ParseScript splits a SQL script into statements (see SplitStatements),
+// reading their names and dependencies from directives in the comments
+// preceding each statement:
+//
+//	-- name: daily_totals
+//	-- depends: load_events, load_users
+//	CREATE TABLE daily_totals AS SELECT ...;
+//
+// Statements without a name directive are named by their position in the
+// script, starting at 1.
+func ParseScript(sql string) []Statement {
+	texts := SplitStatements(sql)
+	statements := make([]Statement, len(texts))
+
+	for i, text := range texts {
+		s := Statement{Name: fmt.Sprint(i + 1), SQL: text}
+
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if !strings.HasPrefix(line, "--") {
+				break
+			}
+
+			directive := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+			lower := strings.ToLower(directive)
+
+			switch {
+			case strings.HasPrefix(lower, "name:"):
+				s.Name = strings.TrimSpace(directive[len("name:"):])
+			case strings.HasPrefix(lower, "depends:"):
+				for _, d := range strings.Split(directive[len("depends:"):], ",") {
+					if d = strings.TrimSpace(d); d != "" {
+						s.DependsOn = append(s.DependsOn, d)
+					}
+				}
+			}
+		}
+
+		statements[i] = s
+	}
+
+	return statements
+}
+
+// ScriptOptions configures how RunScript executes statements.
+type ScriptOptions struct {
+	// ContinueOnError keeps running statements which don't depend on a
+	// failed statement; otherwise no further statements are started once
+	// one fails.
+	ContinueOnError bool
+
+	// Concurrency is the maximum number of statements run at once; values
+	// less than 2 run statements one at a time, in order.
+	Concurrency int
+}
+
+// StatementResult reports the outcome of a statement run by RunScript.
+type StatementResult struct {
+	// Name is the name of the statement.
+	Name string `json:"name"`
+
+	// QueryID is the Athena query execution ID, if the statement was started.
+	QueryID string `json:"query_id,omitempty"`
+
+	// State is the final state of the query, or SKIPPED if the statement
+	// was not run.
+	State string `json:"state"`
+
+	// Duration is how long the statement took, including time queued.
+	Duration time.Duration `json:"duration"`
+
+	// DataScannedInBytes is the amount of data the statement scanned.
+	DataScannedInBytes int64 `json:"data_scanned_in_bytes"`
+
+	// Err is the reason the statement failed, if it did.
+	Err error `json:"-"`
+}
+
+// StateSkipped is the StatementResult state of statements RunScript did
+// not run, because an earlier statement or a dependency failed.
+const StateSkipped = "SKIPPED"
+
+// ScriptError is returned by RunScript when one or more statements did
+// not succeed.
+type ScriptError struct {
+	// Failed names the statements which failed.
+	Failed []string
+
+	// Skipped names the statements which were not run.
+	Skipped []string
+}
+
+// Error satisfies the error interface.
+func (e *ScriptError) Error() string {
+	msg := fmt.Sprintf("%d statement(s) failed: %s", len(e.Failed), strings.Join(e.Failed, ", "))
+	if len(e.Skipped) > 0 {
+		msg += fmt.Sprintf("; %d skipped", len(e.Skipped))
+	}
+
+	return msg
+}
+
+// RunScript runs statements on database, waiting for each to complete.
+// A statement is started once all of its dependencies have succeeded,
+// in the order given. See DoQuery for the meaning of output.
+//
+// A result is returned for every statement, in the order given; a
+// *ScriptError is returned if any statement did not succeed.
+func (c Client) RunScript(ctx context.Context, database string, statements []Statement, output string, opts ScriptOptions) ([]StatementResult, error) {
+	index, err := checkDependencies(statements)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]StatementResult, len(statements))
+	started := make([]bool, len(statements))
+	finished := make([]bool, len(statements))
+
+	type completion struct {
+		i      int
+		result StatementResult
+	}
+
+	done := make(chan completion)
+	running := 0
+	stopped := false
+
+	for {
+		for i, s := range statements {
+			if started[i] || stopped || running >= concurrency {
+				continue
+			}
+
+			ready, blocked := true, false
+			for _, d := range s.DependsOn {
+				j := index[d]
+				if !finished[j] {
+					ready = false
+				} else if results[j].State != athena.QueryExecutionStateSucceeded {
+					blocked = true
+				}
+			}
+
+			if blocked {
+				started[i], finished[i] = true, true
+				results[i] = StatementResult{Name: s.Name, State: StateSkipped}
+				continue
+			}
+
+			if !ready {
+				continue
+			}
+
+			started[i] = true
+			running++
+
+			go func(i int, s Statement) {
+				done <- completion{i, c.runStatement(ctx, database, s, output)}
+			}(i, s)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		comp := <-done
+		running--
+		results[comp.i] = comp.result
+		finished[comp.i] = true
+
+		if comp.result.Err != nil && !opts.ContinueOnError {
+			stopped = true
+		}
+	}
+
+	var serr ScriptError
+
+	for i, s := range statements {
+		if !started[i] {
+			results[i] = StatementResult{Name: s.Name, State: StateSkipped}
+		}
+
+		if results[i].Err != nil {
+			serr.Failed = append(serr.Failed, s.Name)
+		} else if results[i].State == StateSkipped {
+			serr.Skipped = append(serr.Skipped, s.Name)
+		}
+	}
+
+	if len(serr.Failed) > 0 {
+		return results, &serr
+	}
+
+	return results, nil
+}
+
+// runStatement runs a single statement, reporting its outcome.
+func (c Client) runStatement(ctx context.Context, database string, s Statement, output string) StatementResult {
+	sr := StatementResult{Name: s.Name}
+	start := time.Now()
+
+	ctx, span := c.startQuerySpan(ctx, database)
+	defer func() { endSpan(span, sr.Err) }()
+
+	q, err := c.doQuery(ctx, database, s.SQL, output)
+	if err != nil {
+		sr.State = athena.QueryExecutionStateFailed
+		sr.Err = err
+		sr.Duration = time.Since(start)
+		return sr
+	}
+
+	sr.QueryID = q.ID()
+	span.SetAttribute(AttrQueryID, q.id)
+
+	status, err := q.Wait(ctx)
+	sr.State = status.State
+	sr.Err = err
+	if status.State != "" {
+		span.SetAttribute(AttrState, status.State)
+	}
+
+	if sr.State == "" {
+		sr.State = athena.QueryExecutionStateFailed
+	}
+
+	if stats, err := q.Statistics(); err == nil {
+		sr.DataScannedInBytes = stats.DataScannedInBytes
+	}
+
+	sr.Duration = time.Since(start)
+
+	return sr
+}
+
+// checkDependencies ensures statement names are unique and their
+// dependencies exist and are acyclic, returning the index of each name.
+func checkDependencies(statements []Statement) (map[string]int, error) {
+	index := make(map[string]int, len(statements))
+
+	for i, s := range statements {
+		if _, ok := index[s.Name]; ok {
+			return nil, fmt.Errorf("%s: %w", s.Name, duplicateStatement)
+		}
+
+		index[s.Name] = i
+	}
+
+	for _, s := range statements {
+		for _, d := range s.DependsOn {
+			if _, ok := index[d]; !ok {
+				return nil, fmt.Errorf("%s: %s: %w", s.Name, d, unknownDependency)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	marks := make([]int, len(statements))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case visiting:
+			return fmt.Errorf("%s: %w", statements[i].Name, dependencyCycle)
+		case visited:
+			return nil
+		}
+
+		marks[i] = visiting
+
+		for _, d := range statements[i].DependsOn {
+			if err := visit(index[d]); err != nil {
+				return err
+			}
+		}
+
+		marks[i] = visited
+
+		return nil
+	}
+
+	for i := range statements {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
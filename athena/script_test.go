@@ -0,0 +1,182 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		id       string
+		sql      string
+		expected []string
+	}{
+		{
+			id:       "single statement without semicolon",
+			sql:      "SELECT 1",
+			expected: []string{"SELECT 1"},
+		},
+		{
+			id:       "multiple statements",
+			sql:      "SELECT 1;\nSELECT 2;\n",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			id:       "semicolons in strings and identifiers",
+			sql:      `SELECT 'a;b', "c;d", ` + "`e;f`" + `, 'it''s;'; SELECT 2`,
+			expected: []string{`SELECT 'a;b', "c;d", ` + "`e;f`" + `, 'it''s;'`, "SELECT 2"},
+		},
+		{
+			id:       "semicolons in comments",
+			sql:      "-- first; statement\nSELECT 1 /* ; */;\nSELECT 2",
+			expected: []string{"-- first; statement\nSELECT 1 /* ; */", "SELECT 2"},
+		},
+		{
+			id:       "empty and comment only statements",
+			sql:      ";;\n-- nothing here;\n/* or here */;SELECT 1;\n-- trailing comment",
+			expected: []string{"SELECT 1"},
+		},
+		{
+			id:       "unterminated comment",
+			sql:      "SELECT 1; /* ; SELECT 2",
+			expected: []string{"SELECT 1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			actual := athena.SplitStatements(tc.sql)
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				tt.Errorf("SplitStatements() == %q (want %q)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	sql := `
+-- name: load
+CREATE TABLE a AS SELECT 1;
+
+-- name: report
+-- Depends: load, other
+SELECT * FROM a;
+
+SELECT 2 -- name: ignored
+`
+
+	expected := []athena.Statement{
+		{Name: "load", SQL: "-- name: load\nCREATE TABLE a AS SELECT 1"},
+		{Name: "report", SQL: "-- name: report\n-- Depends: load, other\nSELECT * FROM a", DependsOn: []string{"load", "other"}},
+		{Name: "3", SQL: "SELECT 2 -- name: ignored"},
+	}
+
+	actual := athena.ParseScript(sql)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ParseScript() == %v (want %v)", actual, expected)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	statements := []athena.Statement{
+		{Name: "a", SQL: "SELECT 1"},
+		{Name: "b", SQL: "SELECT 2", DependsOn: []string{"a"}},
+		{Name: "c", SQL: "SELECT 3"},
+	}
+
+	stats := &aa.QueryExecutionStatistics{DataScannedInBytes: aws.Int64(10)}
+
+	t.Run("succeeds", func(tt *testing.T) {
+		var queries []string
+
+		c := athena.NewCustomClient(mockClient{
+			startQueryExecution:  startQueryExecution{id: "jobid", queries: &queries},
+			getQueryExecution:    getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output/jobid.csv"},
+			queryExecutionDetail: queryExecutionDetail{statistics: stats},
+		})
+
+		results, err := c.RunScript(context.Background(), "database", statements, "s3://output", athena.ScriptOptions{})
+
+		if err != nil {
+			tt.Errorf("err == %v (want nil)", err)
+		}
+
+		if !reflect.DeepEqual(queries, []string{"SELECT 1", "SELECT 2", "SELECT 3"}) {
+			tt.Errorf("queries == %v (want statements in order)", queries)
+		}
+
+		for i, r := range results {
+			if r.Name != statements[i].Name || r.State != "SUCCEEDED" || r.QueryID != "jobid" || r.DataScannedInBytes != 10 {
+				tt.Errorf("results[%d] == %+v (want %s SUCCEEDED)", i, r, statements[i].Name)
+			}
+		}
+	})
+
+	t.Run("stops on error", func(tt *testing.T) {
+		c := athena.NewCustomClient(mockClient{
+			startQueryExecution: startQueryExecution{id: "jobid"},
+			getQueryExecution:   getQueryExecution{state: "FAILED", outLocation: "s3://output/jobid.csv"},
+		})
+
+		results, err := c.RunScript(context.Background(), "database", statements, "s3://output", athena.ScriptOptions{})
+
+		expectedErr := &athena.ScriptError{Failed: []string{"a"}, Skipped: []string{"b", "c"}}
+		if !reflect.DeepEqual(err, expectedErr) {
+			tt.Errorf("err == %v (want %v)", err, expectedErr)
+		}
+
+		states := []string{results[0].State, results[1].State, results[2].State}
+		if !reflect.DeepEqual(states, []string{"FAILED", athena.StateSkipped, athena.StateSkipped}) {
+			tt.Errorf("states == %v (want [FAILED SKIPPED SKIPPED])", states)
+		}
+	})
+
+	t.Run("continues on error", func(tt *testing.T) {
+		c := athena.NewCustomClient(mockClient{
+			startQueryExecution: startQueryExecution{id: "jobid"},
+			getQueryExecution:   getQueryExecution{state: "FAILED", outLocation: "s3://output/jobid.csv"},
+		})
+
+		opts := athena.ScriptOptions{ContinueOnError: true, Concurrency: 2}
+		results, err := c.RunScript(context.Background(), "database", statements, "s3://output", opts)
+
+		expectedErr := &athena.ScriptError{Failed: []string{"a", "c"}, Skipped: []string{"b"}}
+		if !reflect.DeepEqual(err, expectedErr) {
+			tt.Errorf("err == %v (want %v)", err, expectedErr)
+		}
+
+		var qerr *athena.QueryError
+		if !errors.As(results[0].Err, &qerr) {
+			tt.Errorf("results[0].Err == %v (want *QueryError)", results[0].Err)
+		}
+	})
+
+	t.Run("invalid dependencies", func(tt *testing.T) {
+		cases := []struct {
+			statements []athena.Statement
+			err        error
+		}{
+			{[]athena.Statement{{Name: "a"}, {Name: "a"}}, athena.ErrDuplicateStatement},
+			{[]athena.Statement{{Name: "a", DependsOn: []string{"b"}}}, athena.ErrUnknownDependency},
+			{[]athena.Statement{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}}, athena.ErrDependencyCycle},
+		}
+
+		c := athena.NewCustomClient(mockClient{})
+
+		for _, tc := range cases {
+			_, err := c.RunScript(context.Background(), "database", tc.statements, "s3://output", athena.ScriptOptions{})
+
+			if !errors.Is(err, tc.err) {
+				tt.Errorf("err == %v (want %v)", err, tc.err)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package athena
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// notList is returned by the literals template function when given a value
+// which is not a slice or array.
+const notList = constError("value is not a list")
+
+// templateDate is a date in a query template, which is written as
+// 2006-01-02 unless formatted with the date function.
+type templateDate struct {
+	time.Time
+}
+
+// String returns the date in the form 2006-01-02.
+func (d templateDate) String() string {
+	return d.Format("2006-01-02")
+}
+
+// TemplateFuncs returns the functions available to query templates
+// rendered by RenderQuery:
+//
+//	now                 the current time, in UTC
+//	today               the current date, in UTC
+//	daysAgo N           the date N days before today
+//	date LAYOUT T       T formatted with the Go time layout LAYOUT
+//	literal V           V quoted as a string literal; see QuoteLiteral
+//	literals LIST       the items of LIST quoted as literals and separated by commas
+//	ident NAME          NAME quoted as an identifier; see QuoteIdentifier
+//
+// e.g. WHERE dt >= {{ daysAgo 7 | date "2006-01-02" | literal }}
+func TemplateFuncs() template.FuncMap {
+	return templateFuncs(time.Now)
+}
+
+func templateFuncs(now func() time.Time) template.FuncMap {
+	today := func() templateDate {
+		return templateDate{now().UTC().Truncate(24 * time.Hour)}
+	}
+
+	return template.FuncMap{
+		"now":   func() time.Time { return now().UTC() },
+		"today": today,
+		"daysAgo": func(n int) templateDate {
+			return templateDate{today().AddDate(0, 0, -n)}
+		},
+		"date": func(layout string, t interface{ Format(string) string }) string {
+			return t.Format(layout)
+		},
+		"literal": func(v interface{}) string {
+			return QuoteLiteral(fmt.Sprint(v))
+		},
+		"literals": func(list interface{}) (string, error) {
+			v := reflect.ValueOf(list)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return "", fmt.Errorf("%T: %w", list, notList)
+			}
+
+			quoted := make([]string, v.Len())
+			for i := range quoted {
+				quoted[i] = QuoteLiteral(fmt.Sprint(v.Index(i).Interface()))
+			}
+
+			return strings.Join(quoted, ", "), nil
+		},
+		"ident": func(name string) (string, error) {
+			if name == "" {
+				return "", emptyIdentifier
+			}
+
+			return QuoteIdentifier(name), nil
+		},
+	}
+}
+
+// RenderQuery renders text as a text/template with the functions of
+// TemplateFuncs, substituting vars, e.g. {{ .table | ident }}. It is an
+// error for the template to refer to a variable not in vars.
+//
+// Variables are substituted as is; use the literal and ident functions to
+// quote them safely.
+func RenderQuery(text string, vars map[string]interface{}) (string, error) {
+	return renderQuery(text, vars, time.Now)
+}
+
+func renderQuery(text string, vars map[string]interface{}, now func() time.Time) (string, error) {
+	t, err := template.New("query").Funcs(templateFuncs(now)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
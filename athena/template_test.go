@@ -0,0 +1,52 @@
+package athena_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+)
+
+func TestRenderQuery(t *testing.T) {
+	now := time.Date(2019, 11, 5, 13, 45, 0, 0, time.FixedZone("AEDT", 11*60*60))
+	vars := map[string]interface{}{
+		"table":  `odd"name`,
+		"name":   "o'brien",
+		"limit":  10,
+		"states": []interface{}{"NSW", "it's"},
+	}
+
+	for i, test := range []struct {
+		text     string
+		expected string
+		err      error
+	}{
+		{"SELECT * FROM {{ .table | ident }} LIMIT {{ .limit }}", `SELECT * FROM "odd""name" LIMIT 10`, nil},
+		{"WHERE name = {{ .name | literal }}", `WHERE name = 'o''brien'`, nil},
+		{"WHERE state IN ({{ literals .states }})", `WHERE state IN ('NSW', 'it''s')`, nil},
+		{"WHERE dt = '{{ today }}'", `WHERE dt = '2019-11-05'`, nil},
+		{`WHERE dt >= {{ daysAgo 7 | date "20060102" | literal }}`, `WHERE dt >= '20191029'`, nil},
+		{`WHERE ts < {{ now | date "2006-01-02 15:04" | literal }}`, `WHERE ts < '2019-11-05 02:45'`, nil},
+		{"{{ .missing }}", "", nil},
+		{"{{ literals .name }}", "", athena.ErrNotList},
+		{`{{ ident "" }}`, "", athena.ErrEmptyIdentifier},
+		{"{{ .table", "", nil},
+	} {
+		r, err := athena.RenderQueryAt(test.text, vars, now)
+
+		if test.expected == "" && err == nil {
+			t.Errorf("%d: err == nil (want error)", i)
+		} else if test.expected != "" && err != nil {
+			t.Errorf("%d: err == %v (want nil)", i, err)
+		}
+
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("%d: err == %v (want %v)", i, err, test.err)
+		}
+
+		if r != test.expected {
+			t.Errorf("%d: RenderQuery() == %q (want %q)", i, r, test.expected)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package athena
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// Span names
+const (
+	// SpanQuery spans a logical query run by Run, or by the other methods
+	// taking a context which start a query and wait for it: submitting it,
+	// waiting for it and reading its results.
+	SpanQuery = "athena.query"
+
+	// SpanSubmit spans starting a query, SpanPoll each check of its status,
+	// and SpanResults the fetching of each page of its results.
+	SpanSubmit  = "athena.submit"
+	SpanPoll    = "athena.poll"
+	SpanResults = "athena.results"
+)
+
+// Span attribute keys
+const (
+	AttrQueryID   = "athena.query_id"
+	AttrDatabase  = "athena.database"
+	AttrWorkGroup = "athena.workgroup"
+
+	// AttrState is the state of a query, and AttrPreviousState its state
+	// before an EventStateChange.
+	AttrState         = "athena.state"
+	AttrPreviousState = "athena.previous_state"
+
+	AttrDataScanned = "athena.data_scanned_bytes"
+
+	// AttrErrorCode is the code of an AWS error, or the error code Athena
+	// gave as the reason a query failed, e.g. SYNTAX_ERROR.
+	AttrErrorCode = "athena.error_code"
+
+	// AttrPage is the index of a page of results, from zero, and AttrRows
+	// the number of rows in it.
+	AttrPage = "athena.page"
+	AttrRows = "athena.rows"
+)
+
+// EventStateChange is added to the span of the poll which saw the state
+// of a query change, including the first.
+const EventStateChange = "athena.state_change"
+
+// Tracer starts spans, such as those of OpenTelemetry, for the queries
+// of clients configured WithTracer.
+type Tracer interface {
+	// Start starts a span named name, the child of the span in ctx if
+	// there is one, returning it and a context containing it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer. Attribute values are strings,
+// ints or int64s.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	AddEvent(name string, attributes map[string]interface{})
+
+	// RecordError records that the operation spanned failed with err.
+	RecordError(err error)
+
+	End()
+}
+
+// WithTracer returns a copy of the client which traces its queries with
+// t, in spans named by the Span constants.
+//
+// Only the methods taking a context start a query span; spans of the
+// others are children of the span in the context they are given, if any,
+// or are without a parent.
+func (c Client) WithTracer(t Tracer) Client {
+	return c.withOptions(func(o *options) {
+		o.tracer = t
+	})
+}
+
+// noopSpan is the span of a client without a tracer.
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{})        {}
+func (noopSpan) AddEvent(string, map[string]interface{}) {}
+func (noopSpan) RecordError(error)                       {}
+func (noopSpan) End()                                    {}
+
+// startSpan starts a span with the client's tracer, if it has one.
+func (c Client) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	t := c.options().tracer
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+
+	return t.Start(ctx, name)
+}
+
+// startQuerySpan starts the span of a logical query on database.
+func (c Client) startQuerySpan(ctx context.Context, database string) (context.Context, Span) {
+	ctx, span := c.startSpan(ctx, SpanQuery)
+	span.SetAttribute(AttrDatabase, database)
+
+	return ctx, span
+}
+
+// endSpan ends a span, recording err and its code if not nil.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+
+		var aerr awserr.Error
+		var qerr *QueryError
+
+		if errors.As(err, &aerr) {
+			span.SetAttribute(AttrErrorCode, aerr.Code())
+		} else if errors.As(err, &qerr) {
+			span.SetAttribute(AttrState, qerr.State)
+			if code := reasonCode(qerr.Reason); code != "" {
+				span.SetAttribute(AttrErrorCode, code)
+			}
+		}
+	}
+
+	span.End()
+}
+
+// reasonCode returns the error code prefixing the reason Athena gave for
+// a query failing, e.g. SYNTAX_ERROR in "SYNTAX_ERROR: line 1:8: ...",
+// or "".
+func reasonCode(reason string) string {
+	i := strings.IndexByte(reason, ':')
+	if i <= 0 {
+		return ""
+	}
+
+	for _, r := range reason[:i] {
+		if (r < 'A' || r > 'Z') && r != '_' {
+			return ""
+		}
+	}
+
+	return reason[:i]
+}
+
+// traceStatus annotates the span of a poll with the status of a query,
+// and its change from previous.
+func traceStatus(span Span, qe *athena.QueryExecution, previous string) {
+	state := *qe.Status.State
+
+	span.SetAttribute(AttrState, state)
+	span.SetAttribute(AttrDataScanned, statistics(qe.Statistics).DataScannedInBytes)
+
+	if state != previous {
+		span.AddEvent(EventStateChange, map[string]interface{}{
+			AttrPreviousState: previous,
+			AttrState:         state,
+		})
+	}
+
+	if qe.Status.StateChangeReason != nil {
+		if code := reasonCode(*qe.Status.StateChangeReason); code != "" {
+			span.SetAttribute(AttrErrorCode, code)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+// span is a span recorded by tracer.
+type span struct {
+	name       string
+	parent     *span
+	attributes map[string]interface{}
+	events     []map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *span) AddEvent(name string, attributes map[string]interface{}) {
+	s.events = append(s.events, attributes)
+}
+
+func (s *span) RecordError(err error) {
+	s.err = err
+}
+
+func (s *span) End() {
+	s.ended = true
+}
+
+type spanKey struct{}
+
+// tracer records the spans started.
+type tracer struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, athena.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, _ := ctx.Value(spanKey{}).(*span)
+	s := &span{name: name, parent: parent, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, s)
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// names returns the names of the spans, prefixed by those of their parents.
+func (t *tracer) names() []string {
+	var names []string
+
+	for _, s := range t.spans {
+		name := s.name
+		for p := s.parent; p != nil; p = p.parent {
+			name = p.name + "/" + name
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func TestWithTracer(t *testing.T) {
+	states := []string{aa.QueryExecutionStateQueued, aa.QueryExecutionStateRunning}
+
+	mc := mockClient{
+		startQueryExecution: startQueryExecution{id: "jobid"},
+		getQueryExecution:   getQueryExecution{state: aa.QueryExecutionStateSucceeded, outLocation: "s3://bucket/jobid.csv"},
+		queryExecutionDetail: queryExecutionDetail{
+			states:     &states,
+			statistics: &aa.QueryExecutionStatistics{DataScannedInBytes: aws.Int64(1024)},
+		},
+		getQueryResults: getQueryResults{
+			columns: []*aa.ColumnInfo{{Name: aws.String("n"), Type: aws.String("bigint")}},
+			rows:    []*aa.Row{{Data: []*aa.Datum{{VarCharValue: aws.String("1")}}}},
+		},
+	}
+
+	tr := &tracer{}
+	ctx, root := tr.Start(context.Background(), "request")
+
+	c := athena.NewCustomClient(mc).WithWorkGroup("analysts").WithPollInterval(time.Millisecond).WithTracer(tr)
+	if _, err := c.Run(ctx, "db", "SELECT 1", "s3://bucket/"); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	root.End()
+
+	expected := []string{
+		"request",
+		"request/athena.query",
+		"request/athena.query/athena.submit",
+		"request/athena.query/athena.poll",
+		"request/athena.query/athena.poll",
+		"request/athena.query/athena.poll",
+		"request/athena.query/athena.results",
+	}
+
+	if names := tr.names(); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("spans == %v (want %v)", names, expected)
+	}
+
+	for _, s := range tr.spans {
+		if !s.ended || s.err != nil {
+			t.Errorf("span %s ended %v with %v (want ended without error)", s.name, s.ended, s.err)
+		}
+	}
+
+	query, submit, poll, results := tr.spans[1], tr.spans[2], tr.spans[3], tr.spans[6]
+
+	for _, test := range []struct {
+		span     *span
+		key      string
+		expected interface{}
+	}{
+		{query, athena.AttrDatabase, "db"},
+		{query, athena.AttrQueryID, "jobid"},
+		{query, athena.AttrState, aa.QueryExecutionStateSucceeded},
+		{submit, athena.AttrWorkGroup, "analysts"},
+		{submit, athena.AttrQueryID, "jobid"},
+		{poll, athena.AttrState, aa.QueryExecutionStateQueued},
+		{poll, athena.AttrDataScanned, int64(1024)},
+		{results, athena.AttrPage, 0},
+		{results, athena.AttrRows, 2},
+	} {
+		if v := test.span.attributes[test.key]; v != test.expected {
+			t.Errorf("%s %s == %v (want %v)", test.span.name, test.key, v, test.expected)
+		}
+	}
+
+	var transitions []string
+	for _, s := range tr.spans[3:6] {
+		for _, e := range s.events {
+			transitions = append(transitions, e[athena.AttrPreviousState].(string)+">"+e[athena.AttrState].(string))
+		}
+	}
+
+	expected = []string{">QUEUED", "QUEUED>RUNNING", "RUNNING>SUCCEEDED"}
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Errorf("transitions == %v (want %v)", transitions, expected)
+	}
+}
+
+func TestTracerErrors(t *testing.T) {
+	mc := mockClient{
+		startQueryExecution:  startQueryExecution{id: "jobid"},
+		getQueryExecution:    getQueryExecution{state: aa.QueryExecutionStateFailed, outLocation: "s3://bucket/jobid.csv"},
+		queryExecutionDetail: queryExecutionDetail{reason: "SYNTAX_ERROR: line 1:8: Column 'x' cannot be resolved"},
+	}
+
+	tr := &tracer{}
+	c := athena.NewCustomClient(mc).WithTracer(tr)
+
+	err := c.DoDDL(context.Background(), "db", "SELECT x", "s3://bucket/")
+
+	var qerr *athena.QueryError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("err == %v (want a QueryError)", err)
+	}
+
+	query := tr.spans[0]
+	if query.err != err || query.attributes[athena.AttrErrorCode] != "SYNTAX_ERROR" || query.attributes[athena.AttrState] != aa.QueryExecutionStateFailed {
+		t.Errorf("query span == %+v (want FAILED with SYNTAX_ERROR)", query)
+	}
+
+	if poll := tr.spans[2]; poll.attributes[athena.AttrErrorCode] != "SYNTAX_ERROR" {
+		t.Errorf("poll span == %+v (want SYNTAX_ERROR)", poll)
+	}
+
+	mc.startQueryExecution.err = awserr.New(aa.ErrCodeInvalidRequestException, "bad request", nil)
+	tr = &tracer{}
+
+	// without a context, the submission has no parent
+	if _, err := athena.NewCustomClient(mc).WithTracer(tr).DoQuery("db", "SELECT 1", "s3://bucket/"); err == nil {
+		t.Fatalf("err == nil (want %v)", mc.startQueryExecution.err)
+	}
+
+	submit := tr.spans[0]
+	if submit.name != athena.SpanSubmit || submit.parent != nil || submit.attributes[athena.AttrErrorCode] != aa.ErrCodeInvalidRequestException {
+		t.Errorf("submit span == %+v (want %s)", submit, aa.ErrCodeInvalidRequestException)
+	}
+}
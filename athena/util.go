@@ -2,10 +2,24 @@ package athena
 
 import (
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const invalidLimit = constError("limit must be non-negative")
 const emptyTable = constError("table must not be an empty string")
+const emptyColumn = constError("column must not be an empty string")
+const invalidPercent = constError("percent must be greater than 0 and at most 100")
+const unsupportedValue = constError("value must be a string, number, bool or time.Time")
+
+// countAlias names the count of rows output by Count, Distinct and
+// NullCounts; Athena column names cannot contain $, so it cannot clash
+// with a column of the table.
+const countAlias = `"$count"`
 
 // NRows returns a query string for selecting at most N rows (where 0 <= N <= limit)
 // from table.
@@ -20,3 +34,174 @@ func NRows(table string, limit int) (string, error) {
 
 	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", table, limit), nil
 }
+
+// Describe returns a query string describing the columns, partition keys
+// and comments of table.
+func Describe(table string) (string, error) {
+	t, err := quoteDDLTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	return "DESCRIBE " + t, nil
+}
+
+// Count returns a query string counting the rows in table where each column
+// in where equals the corresponding value, e.g. the rows of a partition.
+//
+// Strings are compared as string literals, so that a partition key of type
+// string matches; numbers and bools as literals of their type; and times
+// as DATE literals if they are midnight, or TIMESTAMP literals otherwise.
+func Count(table string, where map[string]interface{}) (string, error) {
+	t, err := quoteTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	w, err := whereEqual(where)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("SELECT COUNT(*) AS %s FROM %s%s", countAlias, t, w), nil
+}
+
+// Sample returns a query string selecting at most limit rows from a random
+// sample of roughly percent of the rows in table.
+func Sample(table string, percent float64, limit int) (string, error) {
+	t, err := quoteTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	if percent <= 0 || percent > 100 {
+		return "", invalidPercent
+	}
+
+	if limit < 0 {
+		return "", invalidLimit
+	}
+
+	p := strconv.FormatFloat(percent, 'f', -1, 64)
+
+	return fmt.Sprintf("SELECT * FROM %s TABLESAMPLE BERNOULLI (%s) LIMIT %d", t, p, limit), nil
+}
+
+// Distinct returns a query string selecting the k most frequent distinct
+// values of column in table, along with the number of times each occurs.
+func Distinct(table, column string, k int) (string, error) {
+	t, err := quoteTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	if column == "" {
+		return "", emptyColumn
+	}
+
+	if k < 0 {
+		return "", invalidLimit
+	}
+
+	c := QuoteIdentifier(column)
+
+	return fmt.Sprintf("SELECT %s, COUNT(*) AS %s FROM %s GROUP BY %s ORDER BY 2 DESC, 1 LIMIT %d", c, countAlias, t, c, k), nil
+}
+
+// NullCounts returns a query string counting the rows in table, followed by
+// the number of null values in each of columns.
+func NullCounts(table string, columns []string) (string, error) {
+	t, err := quoteTable(table)
+	if err != nil {
+		return "", err
+	}
+
+	exprs := make([]string, 0, len(columns)+1)
+	exprs = append(exprs, "COUNT(*) AS "+countAlias)
+
+	for _, column := range columns {
+		if column == "" {
+			return "", emptyColumn
+		}
+
+		c := QuoteIdentifier(column)
+		exprs = append(exprs, fmt.Sprintf("COUNT(*) - COUNT(%s) AS %s", c, c))
+	}
+
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), t), nil
+}
+
+// whereEqual returns a WHERE clause (with a leading space) requiring each
+// column to equal its value, or an empty string if there are no columns.
+//
+// Columns are sorted so that the clause is deterministic.
+func whereEqual(where map[string]interface{}) (string, error) {
+	if len(where) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(where))
+	for k := range where {
+		if k == "" {
+			return "", emptyColumn
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	conds := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v, err := literal(where[k])
+		if err != nil {
+			return "", err
+		}
+
+		conds = append(conds, QuoteIdentifier(k)+" = "+v)
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), nil
+}
+
+// literal returns v as an SQL literal of its type; see Count.
+func literal(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return QuoteLiteral(v), nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return "DATE '" + v.Format("2006-01-02") + "'", nil
+		}
+
+		return "TIMESTAMP '" + v.Format("2006-01-02 15:04:05.000") + "'", nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+
+		// Athena has no literals for these
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", unsupportedValue
+		}
+
+		// formatted with the precision of the type, so that float32(0.1) is 0.1
+		bits := 64
+		if rv.Kind() == reflect.Float32 {
+			bits = 32
+		}
+
+		return strconv.FormatFloat(f, 'f', -1, bits), nil
+	}
+
+	return "", unsupportedValue
+}
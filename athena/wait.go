@@ -0,0 +1,228 @@
+package athena
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// QueryError is returned when a query finished without succeeding,
+// i.e. it was FAILED or CANCELLED.
+type QueryError struct {
+	// ID is the Athena query execution ID.
+	ID string
+
+	// State is the final state of the query.
+	State string
+
+	// Reason is Athena's explanation of the state change, if any.
+	Reason string
+}
+
+// Error satisfies the error interface.
+func (e *QueryError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("query %s %s", e.ID, e.State)
+	}
+
+	return fmt.Sprintf("query %s %s: %s", e.ID, e.State, e.Reason)
+}
+
+// terminal returns true if state is one Athena will not transition out of.
+func terminal(state string) bool {
+	switch state {
+	case athena.QueryExecutionStateSucceeded,
+		athena.QueryExecutionStateFailed,
+		athena.QueryExecutionStateCancelled:
+		return true
+	}
+
+	return false
+}
+
+// Progress describes a query being waited for. It is reported to the
+// function given to WithProgress each time Wait checks the query's status.
+type Progress struct {
+	// ID is the Athena query execution ID.
+	ID string
+
+	// State is the current state of the query.
+	State string
+
+	// StateChanged is true if the state differs from the previous check,
+	// and on the first check.
+	StateChanged bool
+
+	// Elapsed is the time since the query was submitted.
+	Elapsed time.Duration
+
+	// QueueTime is how long the query was, or has been, QUEUED, and
+	// RunTime how long it has been running since. Both are measured
+	// between checks, so are accurate to the poll interval.
+	QueueTime time.Duration
+	RunTime   time.Duration
+
+	// DataScannedInBytes is the amount of data scanned so far.
+	DataScannedInBytes int64
+}
+
+// progress tracks the progress of a query across checks of its status.
+type progress struct {
+	Progress
+
+	start   time.Time
+	dequeue time.Time
+}
+
+// update records the status of the query at now.
+func (p *progress) update(qe *athena.QueryExecution, now time.Time) {
+	if p.start.IsZero() {
+		p.start = now
+		if qe.Status.SubmissionDateTime != nil && qe.Status.SubmissionDateTime.Before(now) {
+			p.start = *qe.Status.SubmissionDateTime
+		}
+	}
+
+	state := *qe.Status.State
+
+	p.StateChanged = state != p.State
+	p.State = state
+	p.Elapsed = now.Sub(p.start)
+
+	if state == athena.QueryExecutionStateQueued {
+		p.QueueTime = p.Elapsed
+	} else if p.dequeue.IsZero() {
+		p.dequeue = now
+		p.QueueTime = now.Sub(p.start)
+	}
+
+	if !p.dequeue.IsZero() {
+		p.RunTime = now.Sub(p.dequeue)
+	}
+
+	p.DataScannedInBytes = statistics(qe.Statistics).DataScannedInBytes
+}
+
+// Wait polls the query status until the query completes or ctx is done.
+// If the client was configured WithProgress, progress is reported on
+// each check. The data the query scanned is recorded in the client's
+// budget once it finishes; see WithBudget.
+//
+// A *QueryError is returned if the query FAILED or was CANCELLED; ctx.Err()
+// is returned if ctx is done first. The query is not stopped in either case,
+// unless it exceeds the client's query limit, when it is stopped and a
+// *ScanLimitError returned; see WithQueryLimit.
+func (q Query) Wait(ctx context.Context) (QueryStatus, error) {
+	ticker := time.NewTicker(q.pollInterval())
+	defer ticker.Stop()
+
+	report := q.options().progress
+	p := progress{Progress: Progress{ID: q.id}}
+	previous := ""
+
+	for {
+		qe, err := q.poll(ctx, previous)
+
+		if err != nil {
+			return QueryStatus{}, err
+		}
+
+		status := queryStatus(qe)
+		previous = status.State
+
+		if report != nil {
+			p.update(qe.QueryExecution, time.Now())
+			report(p.Progress)
+		}
+
+		if err := q.account(qe.QueryExecution); err != nil {
+			q.auditCompletion(qe.QueryExecution, err)
+			q.measureCompletion(qe.QueryExecution, true)
+			q.log(LevelWarn, "query stopped", map[string]interface{}{
+				"query_id": q.id,
+				"error":    err,
+			})
+			return status, err
+		}
+
+		if terminal(status.State) {
+			q.auditCompletion(qe.QueryExecution, nil)
+			q.measureCompletion(qe.QueryExecution, false)
+
+			if status.Done() {
+				return status, nil
+			}
+
+			qerr := &QueryError{ID: q.id, State: status.State}
+			if qe.QueryExecution.Status.StateChangeReason != nil {
+				qerr.Reason = *qe.QueryExecution.Status.StateChangeReason
+			}
+
+			return status, qerr
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll checks the status of the query, tracing the check as a child of
+// the span in ctx; previous is the state seen by the previous check.
+func (q Query) poll(ctx context.Context, previous string) (*athena.GetQueryExecutionOutput, error) {
+	_, span := q.startSpan(ctx, SpanPoll)
+	span.SetAttribute(AttrQueryID, q.id)
+
+	qe, err := q.execution()
+	if err == nil {
+		traceStatus(span, qe.QueryExecution, previous)
+		q.logStatus(qe.QueryExecution, previous)
+	}
+
+	endSpan(span, err)
+
+	return qe, err
+}
+
+// Run starts query on database, waits for it to complete, and returns
+// every row of its result. See DoQuery for the meaning of output.
+func (c Client) Run(ctx context.Context, database, query, output string) (Result, error) {
+	return c.run(ctx, database, query, output, false)
+}
+
+// run is Run, stopping the query if ctx is done before it completes if
+// stop is set.
+func (c Client) run(ctx context.Context, database, query, output string, stop bool) (r Result, err error) {
+	ctx, span := c.startQuerySpan(ctx, database)
+	defer func() { endSpan(span, err) }()
+
+	q, err := c.doQuery(ctx, database, query, output)
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	span.SetAttribute(AttrQueryID, q.id)
+
+	status, err := q.Wait(ctx)
+	if status.State != "" {
+		span.SetAttribute(AttrState, status.State)
+	}
+
+	if err != nil && stop && err == ctx.Err() {
+		// the query would otherwise run on, and be billed, unwatched
+		if serr := q.Stop(); serr != nil {
+			err = fmt.Errorf("%w (unable to stop query %s: %v)", err, q.id, serr)
+		}
+	}
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return q.allResults(ctx)
+}
@@ -0,0 +1,227 @@
+package athena_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KablamoOSS/exportexample/athena"
+	"github.com/aws/aws-sdk-go/aws"
+	aa "github.com/aws/aws-sdk-go/service/athena"
+)
+
+func TestQueryWait(t *testing.T) {
+	var errFailure = errors.New("GetQueryExecution failure")
+
+	cases := []struct {
+		id          string
+		cfg         getQueryExecution
+		expected    athena.QueryStatus
+		expectedErr error
+	}{
+		{
+			id:          "unhappy path",
+			cfg:         getQueryExecution{err: errFailure},
+			expected:    athena.QueryStatus{},
+			expectedErr: errFailure,
+		},
+		{
+			id:          "query succeeds",
+			cfg:         getQueryExecution{state: "SUCCEEDED", outLocation: "s3://finished/file.csv"},
+			expected:    athena.QueryStatus{State: "SUCCEEDED", OutputLocation: "s3://finished/file.csv"},
+			expectedErr: nil,
+		},
+		{
+			id:          "query fails",
+			cfg:         getQueryExecution{state: "FAILED", outLocation: "s3://finished/file.csv"},
+			expected:    athena.QueryStatus{State: "FAILED", OutputLocation: "s3://finished/file.csv"},
+			expectedErr: &athena.QueryError{ID: "jobid", State: "FAILED"},
+		},
+		{
+			id:          "query cancelled",
+			cfg:         getQueryExecution{state: "CANCELLED", outLocation: "s3://finished/file.csv"},
+			expected:    athena.QueryStatus{State: "CANCELLED", OutputLocation: "s3://finished/file.csv"},
+			expectedErr: &athena.QueryError{ID: "jobid", State: "CANCELLED"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			c := athena.NewCustomClient(mockClient{getQueryExecution: tc.cfg})
+			q := c.CreateQuery("jobid")
+
+			actual, err := q.Wait(context.Background())
+
+			if actual != tc.expected {
+				tt.Errorf("Wait() == %v (want %v)", actual, tc.expected)
+			}
+
+			var qerr *athena.QueryError
+			if errors.As(tc.expectedErr, &qerr) {
+				var actualErr *athena.QueryError
+				if !errors.As(err, &actualErr) || *actualErr != *qerr {
+					tt.Errorf("err == %v (want %v)", err, tc.expectedErr)
+				}
+			} else if err != tc.expectedErr {
+				tt.Errorf("err == %v (want %v)", err, tc.expectedErr)
+			}
+		})
+	}
+
+	t.Run("context done", func(tt *testing.T) {
+		cfg := getQueryExecution{state: "RUNNING", outLocation: "s3://output"}
+		c := athena.NewCustomClient(mockClient{getQueryExecution: cfg}).WithPollInterval(time.Millisecond)
+		q := c.CreateQuery("jobid")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		actual, err := q.Wait(ctx)
+
+		if actual.State != "RUNNING" {
+			tt.Errorf("Wait().State == %v (want RUNNING)", actual.State)
+		}
+
+		if err != context.DeadlineExceeded {
+			tt.Errorf("err == %v (want %v)", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestQueryWaitProgress(t *testing.T) {
+	states := []string{"QUEUED", "QUEUED", "RUNNING", "RUNNING"}
+	mc := mockClient{
+		getQueryExecution:    getQueryExecution{state: "SUCCEEDED", outLocation: "s3://output"},
+		queryExecutionDetail: queryExecutionDetail{states: &states, statistics: (&aa.QueryExecutionStatistics{}).SetDataScannedInBytes(42)},
+	}
+
+	var reported []athena.Progress
+	c := athena.NewCustomClient(mc).WithPollInterval(time.Millisecond).WithProgress(func(p athena.Progress) {
+		reported = append(reported, p)
+	})
+
+	if _, err := c.CreateQuery("jobid").Wait(context.Background()); err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	expected := []struct {
+		state   string
+		changed bool
+	}{
+		{"QUEUED", true},
+		{"QUEUED", false},
+		{"RUNNING", true},
+		{"RUNNING", false},
+		{"SUCCEEDED", true},
+	}
+
+	if len(reported) != len(expected) {
+		t.Fatalf("len(reported) == %d (want %d)", len(reported), len(expected))
+	}
+
+	for i, p := range reported {
+		if p.ID != "jobid" || p.State != expected[i].state || p.StateChanged != expected[i].changed {
+			t.Errorf("%d: Progress == %+v (want %s, changed %v)", i, p, expected[i].state, expected[i].changed)
+		}
+
+		if p.DataScannedInBytes != 42 {
+			t.Errorf("%d: DataScannedInBytes == %d (want 42)", i, p.DataScannedInBytes)
+		}
+
+		if p.QueueTime+p.RunTime != p.Elapsed {
+			t.Errorf("%d: QueueTime + RunTime == %v (want %v)", i, p.QueueTime+p.RunTime, p.Elapsed)
+		}
+	}
+
+	if last := reported[len(reported)-1]; last.QueueTime != reported[2].QueueTime || last.RunTime <= 0 {
+		t.Errorf("final Progress == %+v (want queue time %v and run time)", last, reported[2].QueueTime)
+	}
+}
+
+func TestResultWithoutHeader(t *testing.T) {
+	columns := []athena.Column{{Name: "first"}, {Name: "second"}}
+
+	cases := []struct {
+		id       string
+		rows     []athena.Row
+		header   bool
+		expected int
+	}{
+		{"empty", nil, false, 0},
+		{"header only", []athena.Row{{"first", "second"}}, true, 0},
+		{"header and data", []athena.Row{{"first", "second"}, {"a", "b"}}, true, 1},
+		{"no header", []athena.Row{{"a", "b"}, {"c", "d"}}, false, 2},
+		{"data like header", []athena.Row{{"first", "second"}}, false, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			r := athena.Result{Columns: columns, Rows: tc.rows}
+			if tc.header {
+				r = athena.WithHeader(r)
+			}
+
+			r = r.WithoutHeader()
+			if len(r.Rows) != tc.expected {
+				tt.Errorf("len(WithoutHeader().Rows) == %d (want %d)", len(r.Rows), tc.expected)
+			}
+
+			if r = r.WithoutHeader(); len(r.Rows) != tc.expected {
+				tt.Errorf("len(WithoutHeader().WithoutHeader().Rows) == %d (want %d)", len(r.Rows), tc.expected)
+			}
+		})
+	}
+}
+
+func TestAllResultsHeader(t *testing.T) {
+	column := []*aa.ColumnInfo{{Name: aws.String("a"), Type: aws.String("varchar")}}
+	row := []*aa.Row{{Data: []*aa.Datum{{VarCharValue: aws.String("a")}}}}
+
+	cases := []struct {
+		id            string
+		statementType string
+		rows          []*aa.Row
+		expected      []athena.Row
+	}{
+		{"select", aa.StatementTypeDml, row, []athena.Row{{"a"}}},
+		{"select nothing", aa.StatementTypeDml, nil, nil},
+		{"ddl", aa.StatementTypeDdl, row, []athena.Row{{"a"}}},
+		{"ddl nothing", aa.StatementTypeDdl, nil, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(tt *testing.T) {
+			c := athena.NewCustomClient(mockClient{
+				getQueryExecution:    getQueryExecution{state: "SUCCEEDED", outLocation: "s3://bucket/prefix"},
+				queryExecutionDetail: queryExecutionDetail{statementType: tc.statementType},
+				getQueryResults:      getQueryResults{columns: column, rows: tc.rows},
+			})
+
+			r, err := c.CreateQuery("jobid").AllResults()
+			if err != nil {
+				tt.Fatalf("err == %v (want nil)", err)
+			}
+
+			if actual := r.WithoutHeader().Rows; len(actual) != len(tc.expected) || len(actual) > 0 && !reflect.DeepEqual(actual, tc.expected) {
+				tt.Errorf("WithoutHeader().Rows == %v (want %v)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestQueryColumns(t *testing.T) {
+	c := athena.NewCustomClient(mockClient{
+		getQueryResults: getQueryResults{columns: []*aa.ColumnInfo{{Name: aws.String("a"), Type: aws.String("bigint")}}},
+	})
+
+	columns, err := c.CreateQuery("jobid").Columns()
+	if err != nil {
+		t.Fatalf("err == %v (want nil)", err)
+	}
+
+	if expected := []athena.Column{{Name: "a", Type: "bigint"}}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Columns() == %v (want %v)", columns, expected)
+	}
+}